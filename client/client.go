@@ -0,0 +1,365 @@
+// Package client implements a client for the line-based scheme protocol spoken by the simulator.
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout is the dial timeout used when Config.DialTimeout is not set.
+	DefaultDialTimeout = 5 * time.Second
+	// DefaultRequestTimeout is the request timeout used when Config.RequestTimeout is not set.
+	DefaultRequestTimeout = 30 * time.Second
+	// DefaultMaxConnections is the connection limit used when Config.MaxConnections is not set.
+	DefaultMaxConnections = 10
+)
+
+var (
+	// ErrClosed is returned when a request is sent with a closed client.
+	ErrClosed = errors.New("client closed")
+	// ErrConnectionClosed is returned when the scheme closes the connection without a complete response.
+	ErrConnectionClosed = errors.New("connection closed without response")
+)
+
+// Config defines the configuration of a Client.
+type Config struct {
+	// Address is the host and port of the scheme.
+	Address string
+	// TLSConfig enables TLS if set. Client certificates for mutual TLS are set in it as well.
+	TLSConfig *tls.Config
+	// DialTimeout is the maximum time to establish a connection.
+	DialTimeout time.Duration
+	// RequestTimeout is the maximum time to send a request and receive its response.
+	RequestTimeout time.Duration
+	// MaxConnections is the maximum number of open connections, which is the number of requests sent concurrently.
+	MaxConnections int
+	// ReasonCodes parses responses and notifications with a reason code, which the scheme sends if it is configured to.
+	ReasonCodes bool
+	// OnNotification is called with the notifications the scheme sends on the connections of the client
+	// when asynchronous settlement is enabled. They are read before the response of the next request on the connection,
+	// so it must not block. Notifications are discarded if it isn't set.
+	OnNotification func(Notification)
+}
+
+// Client sends payments to the scheme. It is safe for concurrent use.
+// Each connection carries one request at a time, and connections are reused for later requests.
+// A connection is closed if its request fails, so a failed request never affects another one.
+// Idle connections closed by the scheme, after its idle timeout or when it is drained, aren't reused,
+// and a request which can't be written to a reused connection is sent once more on a new connection.
+type Client struct {
+	cfg    Config
+	slots  chan struct{}
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// conn is a connection with its buffered reader.
+// While the connection is idle, watched receives the result of waiting for it to become readable.
+type conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	watched chan error
+}
+
+// sendError is returned when the request can't be written to the connection, so the scheme didn't receive it.
+type sendError struct {
+	err error
+}
+
+func (e sendError) Error() string {
+	return "can't send request: " + e.err.Error()
+}
+
+func (e sendError) Unwrap() error {
+	return e.err
+}
+
+// New creates a new Client instance. Connections are established when requests are sent.
+func New(cfg Config) *Client {
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = DefaultDialTimeout
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+	if cfg.MaxConnections <= 0 {
+		cfg.MaxConnections = DefaultMaxConnections
+	}
+
+	return &Client{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxConnections),
+	}
+}
+
+// Send sends the payment and returns the response of the scheme.
+// Rejected payments are returned as a response, errors are only returned if no response is received.
+func (c *Client) Send(ctx context.Context, payment Payment) (Response, error) {
+	return c.Do(ctx, payment.Request())
+}
+
+// Status enquires the outcome of the payment with the ID. The response has the status and reason the payment was answered with,
+// or StatusPending or StatusUnknown if the payment is still being processed or its outcome isn't known.
+func (c *Client) Status(ctx context.Context, paymentID string) (Response, error) {
+	return c.Do(ctx, "STATUS|"+paymentID)
+}
+
+// Recall recalls the accepted payment with the ID for the reason. The response is accepted if the recall is accepted.
+func (c *Client) Recall(ctx context.Context, paymentID, reason string) (Response, error) {
+	return c.Do(ctx, "RECALL|"+paymentID+"|"+reason)
+}
+
+// Do sends the raw request line, without the trailing newline, and returns the parsed response.
+// It blocks until a connection is available, the request timeout is reached or the context is cancelled.
+func (c *Client) Do(ctx context.Context, request string) (Response, error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+	defer func() {
+		<-c.slots
+	}()
+
+	cn, reused, err := c.get(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+
+	line, err := c.roundTrip(ctx, cn, request)
+	if errors.As(err, &sendError{}) && reused && ctx.Err() == nil {
+		// The scheme closed the connection before it was reused, so the request is sent on a new connection.
+		cn.Close() //nolint:errcheck
+
+		cn, err = c.dial(ctx)
+		if err != nil {
+			return Response{}, err
+		}
+
+		line, err = c.roundTrip(ctx, cn, request)
+	}
+	if err != nil {
+		cn.Close() //nolint:errcheck
+		if ctx.Err() != nil {
+			return Response{}, ctx.Err()
+		}
+
+		return Response{}, err
+	}
+
+	parse := ParseResponse
+	if c.cfg.ReasonCodes {
+		parse = ParseCodedResponse
+	}
+
+	response, err := parse(line)
+	if err != nil {
+		cn.Close() //nolint:errcheck
+		return Response{}, err
+	}
+
+	c.put(cn)
+
+	return response, nil
+}
+
+// Close closes idle connections. Requests sent afterwards fail with ErrClosed,
+// and connections of requests in progress are closed when they complete.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	var errs []error
+	for _, cn := range c.idle {
+		errs = append(errs, cn.Close())
+	}
+	c.idle = nil
+
+	return errors.Join(errs...)
+}
+
+// roundTrip writes the request to the connection and reads the response line.
+// Notifications received before the response are passed to Config.OnNotification.
+// The context cancels the request by expiring the deadline of the connection.
+func (c *Client) roundTrip(ctx context.Context, cn *conn, request string) (string, error) {
+	deadline := time.Now().Add(c.cfg.RequestTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	if err := cn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		cn.SetDeadline(time.Unix(1, 0)) //nolint:errcheck
+	})
+	defer stop()
+
+	if _, err := io.WriteString(cn, request+"\n"); err != nil {
+		return "", sendError{err: err}
+	}
+
+	var line string
+	for {
+		var err error
+		line, err = cn.reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return "", ErrConnectionClosed
+		}
+		if err != nil {
+			return "", fmt.Errorf("can't receive response: %w", err)
+		}
+
+		if !strings.HasPrefix(line, notificationPrefix) {
+			break
+		}
+
+		if err := c.notify(line); err != nil {
+			return "", err
+		}
+	}
+
+	if !stop() {
+		return "", ctx.Err()
+	}
+
+	return line, cn.SetDeadline(time.Time{})
+}
+
+// notify parses the notification line and passes it to Config.OnNotification.
+func (c *Client) notify(line string) error {
+	if c.cfg.OnNotification == nil {
+		return nil
+	}
+
+	parse := ParseNotification
+	if c.cfg.ReasonCodes {
+		parse = ParseCodedNotification
+	}
+
+	notification, err := parse(line)
+	if err != nil {
+		return err
+	}
+
+	c.cfg.OnNotification(notification)
+
+	return nil
+}
+
+// get returns an idle connection which is still open and true, or establishes a new connection if none is idle.
+func (c *Client) get(ctx context.Context) (*conn, bool, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, false, ErrClosed
+		}
+
+		n := len(c.idle)
+		if n == 0 {
+			c.mu.Unlock()
+			break
+		}
+
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+
+		if cn.wake() {
+			return cn, true, nil
+		}
+		cn.Close() //nolint:errcheck
+	}
+
+	cn, err := c.dial(ctx)
+
+	return cn, false, err
+}
+
+// dial establishes a new connection.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: c.cfg.DialTimeout}
+
+	var (
+		nc  net.Conn
+		err error
+	)
+	if c.cfg.TLSConfig != nil {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: c.cfg.TLSConfig}).DialContext(ctx, "tcp", c.cfg.Address)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", c.cfg.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't connect: %w", err)
+	}
+
+	return &conn{Conn: nc, reader: bufio.NewReader(nc)}, nil
+}
+
+// put returns the connection to the idle connections, or closes it if the client is closed.
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		cn.Close() //nolint:errcheck
+		return
+	}
+
+	c.idle = append(c.idle, cn)
+	c.watch(cn)
+}
+
+// watch waits in the background until the idle connection is readable, and discards it if the scheme closed it.
+// Notifications received while the connection is idle are left in the reader.
+func (c *Client) watch(cn *conn) {
+	cn.watched = make(chan error, 1)
+
+	go func() {
+		_, err := cn.reader.Peek(1)
+		if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			c.discard(cn)
+		}
+
+		cn.watched <- err
+	}()
+}
+
+// discard closes the idle connection and removes it from the idle connections.
+func (c *Client) discard(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, idle := range c.idle {
+		if idle == cn {
+			c.idle = append(c.idle[:i], c.idle[i+1:]...)
+			break
+		}
+	}
+
+	cn.Close() //nolint:errcheck
+}
+
+// wake stops watching the idle connection, and reports whether it is still open.
+func (cn *conn) wake() bool {
+	cn.SetReadDeadline(time.Unix(1, 0)) //nolint:errcheck
+
+	err := <-cn.watched
+
+	return err == nil || errors.Is(err, os.ErrDeadlineExceeded)
+}
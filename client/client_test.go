@@ -0,0 +1,398 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func Test_Client(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(request string) (response string, ok bool)
+		cfg    Config
+		run    func(*testing.T, *Client, *testServer)
+	}{
+		{
+			name: "Legacy payment",
+			handle: func(request string) (string, bool) {
+				return "RESPONSE|ACCEPTED|" + request + "\n", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				response, err := c.Send(context.Background(), Payment{Amount: 1})
+				require.NoError(t, err)
+				assert.Equal(t, Response{Status: StatusAccepted, Reason: "PAYMENT|1"}, response)
+				assert.True(t, response.Accepted())
+			},
+		},
+		{
+			name: "Extended payment",
+			handle: func(string) (string, bool) {
+				return "RESPONSE|abc-1|REJECTED|Insufficient funds\n", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				response, err := c.Send(context.Background(), Payment{ID: "abc-1", Amount: 1, Currency: "GBP"})
+				require.NoError(t, err)
+				assert.Equal(t, Response{PaymentID: "abc-1", Status: StatusRejected, Reason: "Insufficient funds"}, response)
+				assert.False(t, response.Accepted())
+			},
+		},
+		{
+			name: "Status enquiry",
+			handle: func(request string) (string, bool) {
+				return "RESPONSE|abc-1|PENDING|" + request + "\n", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				response, err := c.Status(context.Background(), "abc-1")
+				require.NoError(t, err)
+				assert.Equal(t, Response{PaymentID: "abc-1", Status: StatusPending, Reason: "STATUS|abc-1"}, response)
+			},
+		},
+		{
+			name: "Recall",
+			handle: func(request string) (string, bool) {
+				return "RESPONSE|abc-1|ACCEPTED|" + request + "\n", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				response, err := c.Recall(context.Background(), "abc-1", "Fraud")
+				require.NoError(t, err)
+				assert.Equal(t, Response{PaymentID: "abc-1", Status: StatusAccepted, Reason: "RECALL|abc-1|Fraud"}, response)
+			},
+		},
+		{
+			name: "Connection is reused",
+			handle: func(string) (string, bool) {
+				return "RESPONSE|ACCEPTED|Transaction processed\n", true
+			},
+			run: func(t *testing.T, c *Client, server *testServer) {
+				for range 3 {
+					_, err := c.Send(context.Background(), Payment{Amount: 1})
+					require.NoError(t, err)
+				}
+
+				assert.EqualValues(t, 1, server.connections.Load())
+			},
+		},
+		{
+			name: "Idle connection closed by the scheme",
+			handle: func(string) (string, bool) {
+				return "RESPONSE|ACCEPTED|Transaction processed\n", true
+			},
+			run: func(t *testing.T, c *Client, server *testServer) {
+				_, err := c.Send(context.Background(), Payment{Amount: 1})
+				require.NoError(t, err)
+
+				server.closeConns()
+				require.Eventually(t, func() bool {
+					c.mu.Lock()
+					defer c.mu.Unlock()
+					return len(c.idle) == 0
+				}, time.Second, time.Millisecond)
+
+				_, err = c.Send(context.Background(), Payment{Amount: 2})
+				require.NoError(t, err)
+
+				assert.EqualValues(t, 2, server.connections.Load())
+			},
+		},
+		{
+			name: "Closed connection",
+			handle: func(request string) (string, bool) {
+				if request == "PAYMENT|1" {
+					return "", false
+				}
+				return "RESPONSE|ACCEPTED|Transaction processed\n", true
+			},
+			run: func(t *testing.T, c *Client, server *testServer) {
+				_, err := c.Send(context.Background(), Payment{Amount: 1})
+				assert.ErrorIs(t, err, ErrConnectionClosed)
+
+				_, err = c.Send(context.Background(), Payment{Amount: 2})
+				assert.NoError(t, err)
+
+				assert.EqualValues(t, 2, server.connections.Load())
+			},
+		},
+		{
+			name: "Truncated response",
+			handle: func(string) (string, bool) {
+				return "RESPONSE|ACC", false
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				_, err := c.Send(context.Background(), Payment{Amount: 1})
+				assert.ErrorIs(t, err, ErrConnectionClosed)
+			},
+		},
+		{
+			name: "Malformed response",
+			handle: func(string) (string, bool) {
+				return "HELLO\n", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				_, err := c.Send(context.Background(), Payment{Amount: 1})
+				assert.ErrorIs(t, err, ErrMalformedResponse)
+			},
+		},
+		{
+			name: "Request timeout",
+			handle: func(string) (string, bool) {
+				return "", true
+			},
+			cfg: Config{RequestTimeout: 50 * time.Millisecond},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				_, err := c.Send(context.Background(), Payment{Amount: 1})
+				assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+			},
+		},
+		{
+			name: "Cancelled request",
+			handle: func(string) (string, bool) {
+				return "", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				ctx, cncl := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cncl)
+
+				_, err := c.Send(ctx, Payment{Amount: 1})
+				assert.ErrorIs(t, err, context.Canceled)
+			},
+		},
+		{
+			name: "Closed client",
+			handle: func(string) (string, bool) {
+				return "RESPONSE|ACCEPTED|Transaction processed\n", true
+			},
+			run: func(t *testing.T, c *Client, _ *testServer) {
+				require.NoError(t, c.Close())
+
+				_, err := c.Send(context.Background(), Payment{Amount: 1})
+				assert.ErrorIs(t, err, ErrClosed)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			server := newTestServer(t, test.handle)
+			defer server.close()
+
+			cfg := test.cfg
+			cfg.Address = server.addr()
+
+			c := New(cfg)
+			defer c.Close() //nolint:errcheck
+
+			test.run(t, c, server)
+		})
+	}
+}
+
+func Test_Client_Notifications(t *testing.T) {
+	tests := []struct {
+		name     string
+		handle   func(request string) (response string, ok bool)
+		cfg      Config
+		expected []Notification
+	}{
+		{
+			name: "Notifications before responses",
+			handle: func(request string) (string, bool) {
+				if request == "PAYMENT|abc-1|1|GBP" {
+					return "RESPONSE|abc-1|PENDING|Pending\n", true
+				}
+				return "NOTIFY|abc-1|SETTLED|Transaction processed\nRESPONSE|abc-2|PENDING|Pending\n", true
+			},
+			expected: []Notification{{PaymentID: "abc-1", Status: StatusSettled, Reason: "Transaction processed"}},
+		},
+		{
+			name: "Coded notifications",
+			handle: func(request string) (string, bool) {
+				if request == "PAYMENT|abc-1|1|GBP" {
+					return "RESPONSE|abc-1|PENDING|PENDING|Pending\n", true
+				}
+				return "NOTIFY|abc-1|REJECTED|INSUFFICIENT_FUNDS|Insufficient funds\nRESPONSE|abc-2|PENDING|PENDING|Pending\n", true
+			},
+			cfg: Config{ReasonCodes: true},
+			expected: []Notification{
+				{PaymentID: "abc-1", Status: StatusRejected, Code: "INSUFFICIENT_FUNDS", Reason: "Insufficient funds"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			server := newTestServer(t, test.handle)
+			defer server.close()
+
+			for _, withCallback := range []bool{true, false} {
+				var notifications []Notification
+
+				cfg := test.cfg
+				cfg.Address = server.addr()
+				cfg.MaxConnections = 1
+				if withCallback {
+					cfg.OnNotification = func(n Notification) {
+						notifications = append(notifications, n)
+					}
+				}
+
+				c := New(cfg)
+
+				response, err := c.Send(context.Background(), Payment{ID: "abc-1", Amount: 1, Currency: "GBP"})
+				require.NoError(t, err)
+				assert.Equal(t, StatusPending, response.Status)
+
+				// The notification of the first payment is sent on the pooled connection before the next response.
+				response, err = c.Send(context.Background(), Payment{ID: "abc-2", Amount: 1, Currency: "GBP"})
+				require.NoError(t, err)
+				assert.Equal(t, "abc-2", response.PaymentID)
+				assert.Equal(t, StatusPending, response.Status)
+
+				require.NoError(t, c.Close())
+
+				if withCallback {
+					assert.Equal(t, test.expected, notifications)
+				} else {
+					assert.Empty(t, notifications)
+				}
+			}
+		})
+	}
+}
+
+func Test_Client_MaxConnections(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	release := make(chan struct{})
+
+	var inFlight, maxInFlight atomic.Int32
+	server := newTestServer(t, func(string) (string, bool) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		<-release
+
+		return "RESPONSE|ACCEPTED|Transaction processed\n", true
+	})
+	defer server.close()
+
+	c := New(Config{Address: server.addr(), MaxConnections: 2})
+	defer c.Close() //nolint:errcheck
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := c.Send(context.Background(), Payment{Amount: 1})
+			assert.NoError(t, err)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return inFlight.Load() == 2
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 2, maxInFlight.Load())
+	assert.EqualValues(t, 2, server.connections.Load())
+}
+
+// testServer answers each request line with the response returned by handle.
+// If handle returns false, the connection is closed after writing the response.
+type testServer struct {
+	listener    net.Listener
+	handle      func(string) (string, bool)
+	connections atomic.Int32
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	conns       []net.Conn
+}
+
+func newTestServer(t *testing.T, handle func(string) (string, bool)) *testServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	s := &testServer{listener: listener, handle: handle}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			s.connections.Add(1)
+
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+
+			s.wg.Add(1)
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		response, ok := s.handle(scanner.Text())
+
+		if _, err := conn.Write([]byte(response)); err != nil || !ok {
+			return
+		}
+	}
+}
+
+// close stops accepting connections, closes open connections and waits for them to be served.
+func (s *testServer) close() {
+	s.listener.Close() //nolint:errcheck
+	s.closeConns()
+	s.wg.Wait()
+}
+
+// closeConns closes the open connections, as the scheme does after its idle timeout.
+func (s *testServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close() //nolint:errcheck
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Payment represents a payment submitted to the scheme.
+// Payments without an ID are sent using the legacy format `PAYMENT|<amount>`, otherwise the extended format
+// `PAYMENT|<id>|<amount>|<currency>[|<reference>[|<debtor>[|<creditor>]]]` is used.
+// The reference is sent empty if only accounts are set.
+type Payment struct {
+	ID              string
+	Amount          int
+	Currency        string
+	Reference       string
+	DebtorAccount   string
+	CreditorAccount string
+}
+
+// Request returns the request line of the payment, without the trailing newline.
+func (p Payment) Request() string {
+	if p.ID == "" {
+		return "PAYMENT|" + strconv.Itoa(p.Amount)
+	}
+
+	fields := []string{"PAYMENT", p.ID, strconv.Itoa(p.Amount), p.Currency}
+	switch {
+	case p.CreditorAccount != "":
+		fields = append(fields, p.Reference, p.DebtorAccount, p.CreditorAccount)
+	case p.DebtorAccount != "":
+		fields = append(fields, p.Reference, p.DebtorAccount)
+	case p.Reference != "":
+		fields = append(fields, p.Reference)
+	}
+
+	return strings.Join(fields, "|")
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Payment_Request(t *testing.T) {
+	tests := []struct {
+		name     string
+		payment  Payment
+		expected string
+	}{
+		{
+			name:     "Legacy",
+			payment:  Payment{Amount: 100},
+			expected: "PAYMENT|100",
+		},
+		{
+			name:     "Extended",
+			payment:  Payment{ID: "abc-1", Amount: 100, Currency: "GBP"},
+			expected: "PAYMENT|abc-1|100|GBP",
+		},
+		{
+			name:     "Extended with reference",
+			payment:  Payment{ID: "abc-1", Amount: 100, Currency: "GBP", Reference: "Invoice 42"},
+			expected: "PAYMENT|abc-1|100|GBP|Invoice 42",
+		},
+		{
+			name:     "Extended with debtor account",
+			payment:  Payment{ID: "abc-1", Amount: 100, Currency: "GBP", Reference: "Invoice 42", DebtorAccount: "ACC-1"},
+			expected: "PAYMENT|abc-1|100|GBP|Invoice 42|ACC-1",
+		},
+		{
+			name:     "Extended with accounts",
+			payment:  Payment{ID: "abc-1", Amount: 100, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-2"},
+			expected: "PAYMENT|abc-1|100|GBP||ACC-1|ACC-2",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.payment.Request())
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedResponse is returned when a response doesn't follow the protocol.
+var ErrMalformedResponse = errors.New("malformed response")
+
+// Status is the status of a response.
+type Status string
+
+const (
+	StatusAccepted Status = "ACCEPTED"
+	StatusRejected Status = "REJECTED"
+	// StatusPending is the status of a payment enquired while it is still being processed.
+	StatusPending Status = "PENDING"
+	// StatusUnknown is the status of a payment enquired that wasn't processed, or whose outcome is no longer retained.
+	StatusUnknown Status = "UNKNOWN"
+	// StatusSettled is the status of a notification of an accepted payment, which was acknowledged as pending.
+	StatusSettled Status = "SETTLED"
+)
+
+// Response represents the answer of the scheme to a payment or a status enquiry.
+// PaymentID is only set when the payment was sent using the extended format.
+// Code is only set when the scheme is configured to send reason codes.
+type Response struct {
+	PaymentID string
+	Status    Status
+	Code      string
+	Reason    string
+}
+
+// Accepted returns true if the payment was accepted.
+func (r Response) Accepted() bool {
+	return r.Status == StatusAccepted
+}
+
+// ParseResponse parses a response line in the format `RESPONSE|<status>|<reason>` or `RESPONSE|<id>|<status>|<reason>`.
+// The trailing newline is optional.
+func ParseResponse(line string) (Response, error) {
+	return parseResponse(line, false)
+}
+
+// ParseCodedResponse parses a response line with a reason code in the format `RESPONSE|<status>|<code>|<reason>`
+// or `RESPONSE|<id>|<status>|<code>|<reason>`. The trailing newline is optional.
+func ParseCodedResponse(line string) (Response, error) {
+	return parseResponse(line, true)
+}
+
+func parseResponse(line string, coded bool) (Response, error) {
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), "|")
+	if len(parts) < 3 || parts[0] != "RESPONSE" {
+		return Response{}, fmt.Errorf("%w: %q", ErrMalformedResponse, line)
+	}
+
+	var response Response
+	status, ok := parseStatus(parts[1])
+	if ok {
+		parts = parts[2:]
+	} else {
+		if len(parts) < 4 {
+			return Response{}, fmt.Errorf("%w: %q", ErrMalformedResponse, line)
+		}
+
+		status, ok = parseStatus(parts[2])
+		if !ok {
+			return Response{}, fmt.Errorf("%w: unknown status in %q", ErrMalformedResponse, line)
+		}
+
+		response.PaymentID = parts[1]
+		parts = parts[3:]
+	}
+	response.Status = status
+
+	if coded {
+		if len(parts) < 2 || parts[0] == "" {
+			return Response{}, fmt.Errorf("%w: missing code in %q", ErrMalformedResponse, line)
+		}
+
+		response.Code = parts[0]
+		parts = parts[1:]
+	}
+	response.Reason = strings.Join(parts, "|")
+
+	return response, nil
+}
+
+// notificationPrefix starts notification lines, which are told apart from responses by it.
+const notificationPrefix = "NOTIFY|"
+
+// Notification represents the settlement of a payment acknowledged as pending, which the scheme sends unsolicited.
+// Code is only set when the scheme is configured to send reason codes.
+type Notification struct {
+	PaymentID string
+	Status    Status
+	Code      string
+	Reason    string
+}
+
+// ParseNotification parses a notification line in the format `NOTIFY|<id>|<status>|<reason>`.
+// The status is StatusSettled or StatusRejected. The trailing newline is optional.
+func ParseNotification(line string) (Notification, error) {
+	return parseNotification(line, false)
+}
+
+// ParseCodedNotification parses a notification line with a reason code in the format `NOTIFY|<id>|<status>|<code>|<reason>`.
+// The status is StatusSettled or StatusRejected. The trailing newline is optional.
+func ParseCodedNotification(line string) (Notification, error) {
+	return parseNotification(line, true)
+}
+
+func parseNotification(line string, coded bool) (Notification, error) {
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), "|")
+	if len(parts) < 4 || parts[0] != "NOTIFY" {
+		return Notification{}, fmt.Errorf("%w: %q", ErrMalformedResponse, line)
+	}
+
+	status := Status(parts[2])
+	if status != StatusSettled && status != StatusRejected {
+		return Notification{}, fmt.Errorf("%w: unknown status in %q", ErrMalformedResponse, line)
+	}
+
+	notification := Notification{PaymentID: parts[1], Status: status}
+	parts = parts[3:]
+
+	if coded {
+		if len(parts) < 2 || parts[0] == "" {
+			return Notification{}, fmt.Errorf("%w: missing code in %q", ErrMalformedResponse, line)
+		}
+
+		notification.Code = parts[0]
+		parts = parts[1:]
+	}
+	notification.Reason = strings.Join(parts, "|")
+
+	return notification, nil
+}
+
+func parseStatus(s string) (Status, bool) {
+	switch status := Status(s); status {
+	case StatusAccepted, StatusRejected, StatusPending, StatusUnknown:
+		return status, true
+	default:
+		return "", false
+	}
+}
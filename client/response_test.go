@@ -0,0 +1,209 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedResponse Response
+		expectedErr      string
+	}{
+		{
+			name:             "Accepted",
+			input:            "RESPONSE|ACCEPTED|Transaction processed\n",
+			expectedResponse: Response{Status: StatusAccepted, Reason: "Transaction processed"},
+		},
+		{
+			name:             "Rejected without newline",
+			input:            "RESPONSE|REJECTED|Invalid amount",
+			expectedResponse: Response{Status: StatusRejected, Reason: "Invalid amount"},
+		},
+		{
+			name:             "Reason with separator",
+			input:            "RESPONSE|REJECTED|Limit|exceeded\n",
+			expectedResponse: Response{Status: StatusRejected, Reason: "Limit|exceeded"},
+		},
+		{
+			name:             "Extended",
+			input:            "RESPONSE|abc-1|REJECTED|Duplicate\n",
+			expectedResponse: Response{PaymentID: "abc-1", Status: StatusRejected, Reason: "Duplicate"},
+		},
+		{
+			name:             "Pending",
+			input:            "RESPONSE|abc-1|PENDING|Payment in progress\n",
+			expectedResponse: Response{PaymentID: "abc-1", Status: StatusPending, Reason: "Payment in progress"},
+		},
+		{
+			name:             "Unknown",
+			input:            "RESPONSE|abc-1|UNKNOWN|Payment not found\n",
+			expectedResponse: Response{PaymentID: "abc-1", Status: StatusUnknown, Reason: "Payment not found"},
+		},
+		{
+			name:        "Unknown prefix",
+			input:       "REPLY|ACCEPTED|Transaction processed\n",
+			expectedErr: `malformed response: "REPLY|ACCEPTED|Transaction processed\n"`,
+		},
+		{
+			name:        "Missing reason",
+			input:       "RESPONSE|ACCEPTED",
+			expectedErr: `malformed response: "RESPONSE|ACCEPTED"`,
+		},
+		{
+			name:        "Unknown status",
+			input:       "RESPONSE|abc-1|QUEUED|Queued",
+			expectedErr: `malformed response: unknown status in "RESPONSE|abc-1|QUEUED|Queued"`,
+		},
+		{
+			name:        "Truncated",
+			input:       "RESPONSE|abc-1|ACCEPTED",
+			expectedErr: `malformed response: "RESPONSE|abc-1|ACCEPTED"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := ParseResponse(test.input)
+			if test.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrMalformedResponse)
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func Test_ParseNotification(t *testing.T) {
+	tests := []struct {
+		name                 string
+		input                string
+		expectedNotification Notification
+		expectedErr          string
+	}{
+		{
+			name:                 "Settled",
+			input:                "NOTIFY|abc-1|SETTLED|Transaction processed\n",
+			expectedNotification: Notification{PaymentID: "abc-1", Status: StatusSettled, Reason: "Transaction processed"},
+		},
+		{
+			name:                 "Rejected",
+			input:                "NOTIFY|abc-1|REJECTED|Insufficient funds",
+			expectedNotification: Notification{PaymentID: "abc-1", Status: StatusRejected, Reason: "Insufficient funds"},
+		},
+		{
+			name:        "Response",
+			input:       "RESPONSE|abc-1|PENDING|Payment in progress\n",
+			expectedErr: `malformed response: "RESPONSE|abc-1|PENDING|Payment in progress\n"`,
+		},
+		{
+			name:        "Unknown status",
+			input:       "NOTIFY|abc-1|ACCEPTED|Transaction processed",
+			expectedErr: `malformed response: unknown status in "NOTIFY|abc-1|ACCEPTED|Transaction processed"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notification, err := ParseNotification(test.input)
+			if test.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrMalformedResponse)
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedNotification, notification)
+		})
+	}
+}
+
+func Test_ParseCodedResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedResponse Response
+		expectedErr      string
+	}{
+		{
+			name:             "Accepted",
+			input:            "RESPONSE|ACCEPTED|OK|Transaction processed\n",
+			expectedResponse: Response{Status: StatusAccepted, Code: "OK", Reason: "Transaction processed"},
+		},
+		{
+			name:             "Extended",
+			input:            "RESPONSE|abc-1|REJECTED|INSUFFICIENT_FUNDS|Insufficient funds",
+			expectedResponse: Response{PaymentID: "abc-1", Status: StatusRejected, Code: "INSUFFICIENT_FUNDS", Reason: "Insufficient funds"},
+		},
+		{
+			name:             "Reason with separator",
+			input:            "RESPONSE|REJECTED|REJECTED|Limit|exceeded\n",
+			expectedResponse: Response{Status: StatusRejected, Code: "REJECTED", Reason: "Limit|exceeded"},
+		},
+		{
+			name:        "Missing code",
+			input:       "RESPONSE|abc-1|ACCEPTED|Transaction processed",
+			expectedErr: `malformed response: missing code in "RESPONSE|abc-1|ACCEPTED|Transaction processed"`,
+		},
+		{
+			name:        "Empty code",
+			input:       "RESPONSE|ACCEPTED||Transaction processed",
+			expectedErr: `malformed response: missing code in "RESPONSE|ACCEPTED||Transaction processed"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := ParseCodedResponse(test.input)
+			if test.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrMalformedResponse)
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func Test_ParseCodedNotification(t *testing.T) {
+	tests := []struct {
+		name                 string
+		input                string
+		expectedNotification Notification
+		expectedErr          string
+	}{
+		{
+			name:                 "Settled",
+			input:                "NOTIFY|abc-1|SETTLED|OK|Transaction processed\n",
+			expectedNotification: Notification{PaymentID: "abc-1", Status: StatusSettled, Code: "OK", Reason: "Transaction processed"},
+		},
+		{
+			name:                 "Rejected",
+			input:                "NOTIFY|abc-1|REJECTED|SERVER_BUSY|Server busy",
+			expectedNotification: Notification{PaymentID: "abc-1", Status: StatusRejected, Code: "SERVER_BUSY", Reason: "Server busy"},
+		},
+		{
+			name:        "Missing code",
+			input:       "NOTIFY|abc-1|SETTLED|Transaction processed",
+			expectedErr: `malformed response: missing code in "NOTIFY|abc-1|SETTLED|Transaction processed"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notification, err := ParseCodedNotification(test.input)
+			if test.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrMalformedResponse)
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedNotification, notification)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadTLSConfig creates a TLS configuration verifying the scheme certificate with the CA in caFile.
+// If certFile and keyFile are set, the client certificate is presented for mutual TLS.
+// It returns nil if caFile is empty, in which case TLS is disabled.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" {
+		if certFile != "" || keyFile != "" {
+			return nil, errors.New("client certificate requires a CA file")
+		}
+
+		return nil, nil //nolint:nilnil // TLS is disabled.
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read CA file: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    x509.NewCertPool(),
+		MinVersion: tls.VersionTLS12,
+	}
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("CA file doesn't contain a PEM certificate")
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	invalidCA := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidCA, []byte("not a certificate"), 0o600))
+
+	tests := []struct {
+		name          string
+		caFile        string
+		certFile      string
+		keyFile       string
+		expectedError string
+	}{
+		{
+			name: "TLS disabled",
+		},
+		{
+			name:          "Client certificate without CA",
+			certFile:      "client.pem",
+			keyFile:       "client-key.pem",
+			expectedError: "client certificate requires a CA file",
+		},
+		{
+			name:          "Missing CA file",
+			caFile:        filepath.Join(dir, "missing.pem"),
+			expectedError: "can't read CA file",
+		},
+		{
+			name:          "Invalid CA file",
+			caFile:        invalidCA,
+			expectedError: "CA file doesn't contain a PEM certificate",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig, err := LoadTLSConfig(test.caFile, test.certFile, test.keyFile)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Nil(t, tlsConfig)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/ormanli/form3-te/client"
+	"github.com/ormanli/form3-te/internal/app/loadgen"
+)
+
+func main() {
+	code := 0
+	defer func() {
+		os.Exit(code)
+	}()
+
+	ctx, cncl := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cncl()
+
+	var (
+		address     = flag.String("address", "localhost:11111", "Address of the scheme.")
+		connections = flag.Int("connections", 10, "Number of concurrent connections.")
+		rate        = flag.Float64("rate", 0, "Payments sent per second. 0 sends payments as fast as possible.")
+		duration    = flag.Duration("duration", 10*time.Second, "Duration of the run. 0 runs until -requests are sent.")
+		requests    = flag.Int("requests", 0, "Number of payments to send. 0 sends payments until -duration elapses.")
+		amount      = flag.String("amount", "uniform:1,10000", "Amount distribution: fixed:<amount>, uniform:<min>,<max>, normal:<mean>,<stddev> or exponential:<mean>.")
+		currency    = flag.String("currency", "", "Currency of payments. If set, payments are sent in the extended format with unique IDs.")
+		seed        = flag.Uint64("seed", uint64(time.Now().UnixNano()), "Seed of generated amounts and IDs.") //nolint:gosec // Nanoseconds are positive.
+		timeout     = flag.Duration("timeout", client.DefaultRequestTimeout, "Request timeout.")
+		tlsCA       = flag.String("tls-ca", "", "CA file verifying the scheme certificate. Enables TLS.")
+		tlsCert     = flag.String("tls-cert", "", "Client certificate file for mutual TLS.")
+		tlsKey      = flag.String("tls-key", "", "Client key file for mutual TLS.")
+		jsonOutput  = flag.Bool("json", false, "Print the report as JSON.")
+	)
+	flag.Parse()
+
+	distribution, err := loadgen.ParseDistribution(*amount)
+	if err != nil {
+		slog.Error("Invalid amount distribution", "error", err.Error())
+		code = 1
+		return
+	}
+
+	cfg := loadgen.Config{
+		Connections: *connections,
+		Rate:        *rate,
+		Duration:    *duration,
+		Requests:    *requests,
+		Amount:      distribution,
+		Currency:    *currency,
+		Seed:        *seed,
+	}
+
+	if err := cfg.Validate(); err != nil {
+		slog.Error("Invalid configuration", "error", err.Error())
+		code = 1
+		return
+	}
+
+	tlsConfig, err := client.LoadTLSConfig(*tlsCA, *tlsCert, *tlsKey)
+	if err != nil {
+		slog.Error("Invalid TLS configuration", "error", err.Error())
+		code = 1
+		return
+	}
+
+	c := client.New(client.Config{
+		Address:        *address,
+		TLSConfig:      tlsConfig,
+		RequestTimeout: *timeout,
+		MaxConnections: *connections,
+	})
+	defer c.Close() //nolint:errcheck
+
+	slog.Info("Generating load", "address", *address, "connections", *connections, "rate", *rate,
+		"duration", *duration, "requests", *requests, "amount", distribution.String(), "seed", *seed)
+
+	report := loadgen.NewGenerator(cfg, c, clock.New()).Run(ctx)
+
+	if *jsonOutput {
+		err = json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		slog.Error("Can't write report", "error", err.Error())
+		code = 1
+		return
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/ormanli/form3-te/client"
+	"github.com/ormanli/form3-te/internal/app/replay"
+)
+
+func main() {
+	code := 0
+	defer func() {
+		os.Exit(code)
+	}()
+
+	ctx, cncl := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cncl()
+
+	var (
+		file    = flag.String("file", "", "Replay file recorded by the simulator with APP_RECORD_FILE.")
+		address = flag.String("address", "localhost:11111", "Address of the scheme.")
+		speed   = flag.Float64("speed", 1, "Speed relative to the recorded timing, e.g. 2 replays twice as fast. 0 sends lines as fast as possible.")
+		timeout = flag.Duration("timeout", 5*time.Second, "Time to wait for the response to each line.")
+		tlsCA   = flag.String("tls-ca", "", "CA file verifying the scheme certificate. Enables TLS.")
+		tlsCert = flag.String("tls-cert", "", "Client certificate file for mutual TLS.")
+		tlsKey  = flag.String("tls-key", "", "Client key file for mutual TLS.")
+	)
+	flag.Parse()
+
+	if *file == "" || *speed < 0 {
+		slog.Error("Invalid configuration", "error", "-file must be set and -speed must not be negative")
+		code = 1
+		return
+	}
+
+	tlsConfig, err := client.LoadTLSConfig(*tlsCA, *tlsCert, *tlsKey)
+	if err != nil {
+		slog.Error("Invalid TLS configuration", "error", err.Error())
+		code = 1
+		return
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		slog.Error("Can't open replay file", "error", err.Error())
+		code = 1
+		return
+	}
+	defer f.Close() //nolint:errcheck
+
+	records, err := replay.ReadRecords(f)
+	if err != nil {
+		slog.Error("Can't read replay file", "error", err.Error())
+		code = 1
+		return
+	}
+
+	slog.Info("Replaying", "file", *file, "address", *address, "lines", len(records), "speed", *speed)
+
+	player := replay.NewPlayer(replay.Config{
+		Address:         *address,
+		TLSConfig:       tlsConfig,
+		Speed:           *speed,
+		ResponseTimeout: *timeout,
+	}, clock.New())
+
+	if err := replay.WriteResults(os.Stdout, player.Play(ctx, records)); err != nil {
+		slog.Error("Can't write results", "error", err.Error())
+		code = 1
+		return
+	}
+}
@@ -2,15 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
-	"github.com/kelseyhightower/envconfig"
-
 	"github.com/ormanli/form3-te/internal"
-	"github.com/ormanli/form3-te/internal/app/simulator"
 )
 
 func main() {
@@ -19,22 +17,50 @@ func main() {
 		os.Exit(code)
 	}()
 
+	configFile := flag.String("config", os.Getenv("APP_CONFIG_FILE"), "JSON configuration file, overridden by environment variables")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and exit")
+	flag.Parse()
+
 	ctx, cncl := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cncl()
 
-	var c simulator.Config
-
-	err := envconfig.Process("app", &c)
+	c, instances, err := internal.LoadConfig("app", *configFile)
 	if err != nil {
-		slog.Error("Can't process configuration", "error", err.Error())
+		logErrors("Invalid configuration", err)
 		code = 1
 		return
 	}
 
-	err = internal.Run(ctx, c)
+	if *printConfig {
+		err = internal.PrintConfig(os.Stdout, c, instances)
+		if err != nil {
+			slog.Error("Can't print configuration", "error", err.Error())
+			code = 1
+		}
+		return
+	}
+
+	if len(instances) > 0 {
+		err = internal.RunInstances(ctx, c, instances)
+	} else {
+		err = internal.Run(ctx, c)
+	}
 	if err != nil {
 		slog.Error("Run failed", "error", err.Error())
 		code = 1
 		return
 	}
 }
+
+// logErrors logs each of the joined errors separately, so every problem is readable.
+func logErrors(msg string, err error) {
+	joined, ok := err.(interface{ Unwrap() []error }) //nolint:errorlint // Only joined errors are split.
+	if !ok {
+		slog.Error(msg, "error", err.Error())
+		return
+	}
+
+	for _, err := range joined.Unwrap() {
+		logErrors(msg, err)
+	}
+}
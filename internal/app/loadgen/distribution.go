@@ -0,0 +1,130 @@
+package loadgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Distribution generates payment amounts.
+type Distribution interface {
+	Amount(r *rand.Rand) int
+	String() string
+}
+
+// ParseDistribution parses an amount distribution in one of the formats
+// `fixed:<amount>`, `uniform:<min>,<max>`, `normal:<mean>,<stddev>` or `exponential:<mean>`.
+func ParseDistribution(s string) (Distribution, error) {
+	kind, params, _ := strings.Cut(s, ":")
+
+	var values []float64
+	if params != "" {
+		for _, p := range strings.Split(params, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter %q of %s distribution", p, kind)
+			}
+			values = append(values, v)
+		}
+	}
+
+	expectParams := func(n int) error {
+		if len(values) != n {
+			return fmt.Errorf("%s distribution requires %d parameters, got %d", kind, n, len(values))
+		}
+		for _, v := range values {
+			if v < 0 {
+				return fmt.Errorf("parameters of %s distribution must not be negative", kind)
+			}
+		}
+		return nil
+	}
+
+	switch kind {
+	case "fixed":
+		if err := expectParams(1); err != nil {
+			return nil, err
+		}
+		return fixed{amount: int(values[0])}, nil
+	case "uniform":
+		if err := expectParams(2); err != nil {
+			return nil, err
+		}
+		if values[0] > values[1] {
+			return nil, fmt.Errorf("min %v of uniform distribution is greater than max %v", values[0], values[1])
+		}
+		return uniform{min: int(values[0]), max: int(values[1])}, nil
+	case "normal":
+		if err := expectParams(2); err != nil {
+			return nil, err
+		}
+		return normal{mean: values[0], stddev: values[1]}, nil
+	case "exponential":
+		if err := expectParams(1); err != nil {
+			return nil, err
+		}
+		return exponential{mean: values[0]}, nil
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", kind)
+	}
+}
+
+// fixed always generates the same amount.
+type fixed struct {
+	amount int
+}
+
+func (d fixed) Amount(*rand.Rand) int {
+	return d.amount
+}
+
+func (d fixed) String() string {
+	return fmt.Sprintf("fixed:%d", d.amount)
+}
+
+// uniform generates amounts between min and max, both inclusive, with equal probability.
+type uniform struct {
+	min int
+	max int
+}
+
+func (d uniform) Amount(r *rand.Rand) int {
+	return d.min + r.IntN(d.max-d.min+1)
+}
+
+func (d uniform) String() string {
+	return fmt.Sprintf("uniform:%d,%d", d.min, d.max)
+}
+
+// normal generates normally distributed amounts. Negative amounts are generated as 0.
+type normal struct {
+	mean   float64
+	stddev float64
+}
+
+func (d normal) Amount(r *rand.Rand) int {
+	return nonNegative(r.NormFloat64()*d.stddev + d.mean)
+}
+
+func (d normal) String() string {
+	return fmt.Sprintf("normal:%v,%v", d.mean, d.stddev)
+}
+
+// exponential generates exponentially distributed amounts, so most amounts are small with a long tail of large amounts.
+type exponential struct {
+	mean float64
+}
+
+func (d exponential) Amount(r *rand.Rand) int {
+	return nonNegative(r.ExpFloat64() * d.mean)
+}
+
+func (d exponential) String() string {
+	return fmt.Sprintf("exponential:%v", d.mean)
+}
+
+func nonNegative(v float64) int {
+	return int(math.Round(math.Max(v, 0)))
+}
@@ -0,0 +1,139 @@
+package loadgen
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseDistribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Distribution
+		expectedErr string
+	}{
+		{
+			name:     "Fixed",
+			input:    "fixed:100",
+			expected: fixed{amount: 100},
+		},
+		{
+			name:     "Uniform",
+			input:    "uniform:1, 10000",
+			expected: uniform{min: 1, max: 10000},
+		},
+		{
+			name:     "Normal",
+			input:    "normal:500,100",
+			expected: normal{mean: 500, stddev: 100},
+		},
+		{
+			name:     "Exponential",
+			input:    "exponential:250",
+			expected: exponential{mean: 250},
+		},
+		{
+			name:        "Unknown distribution",
+			input:       "pareto:1",
+			expectedErr: `unknown distribution "pareto"`,
+		},
+		{
+			name:        "Missing parameters",
+			input:       "uniform:1",
+			expectedErr: "uniform distribution requires 2 parameters, got 1",
+		},
+		{
+			name:        "Invalid parameter",
+			input:       "fixed:abc",
+			expectedErr: `invalid parameter "abc" of fixed distribution`,
+		},
+		{
+			name:        "Negative parameter",
+			input:       "normal:-1,10",
+			expectedErr: "parameters of normal distribution must not be negative",
+		},
+		{
+			name:        "Min greater than max",
+			input:       "uniform:10,1",
+			expectedErr: "min 10 of uniform distribution is greater than max 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := ParseDistribution(test.input)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, d)
+		})
+	}
+}
+
+func Test_Distribution_Amount(t *testing.T) {
+	const samples = 10000
+
+	tests := []struct {
+		name         string
+		distribution Distribution
+		min          int
+		max          int
+		mean         float64
+	}{
+		{
+			name:         "Fixed",
+			distribution: fixed{amount: 100},
+			min:          100,
+			max:          100,
+			mean:         100,
+		},
+		{
+			name:         "Uniform",
+			distribution: uniform{min: 10, max: 20},
+			min:          10,
+			max:          20,
+			mean:         15,
+		},
+		{
+			name:         "Normal",
+			distribution: normal{mean: 500, stddev: 50},
+			min:          0,
+			max:          1000,
+			mean:         500,
+		},
+		{
+			name:         "Normal never generates negative amounts",
+			distribution: normal{mean: 0, stddev: 50},
+			min:          0,
+			max:          500,
+			mean:         20,
+		},
+		{
+			name:         "Exponential",
+			distribution: exponential{mean: 200},
+			min:          0,
+			max:          10000,
+			mean:         200,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 1))
+
+			sum := 0
+			for range samples {
+				amount := test.distribution.Amount(r)
+				require.GreaterOrEqual(t, amount, test.min)
+				require.LessOrEqual(t, amount, test.max)
+				sum += amount
+			}
+
+			assert.InEpsilon(t, test.mean, float64(sum)/samples, 0.1)
+		})
+	}
+}
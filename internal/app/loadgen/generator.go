@@ -0,0 +1,155 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/ormanli/form3-te/client"
+)
+
+// Sender defines the interface for sending payments to the scheme.
+type Sender interface {
+	Send(ctx context.Context, payment client.Payment) (client.Response, error)
+}
+
+// Config defines the load generated by Generator.
+type Config struct {
+	// Connections is the number of payments sent concurrently.
+	Connections int
+	// Rate is the number of payments sent per second. If it is 0, payments are sent as fast as possible.
+	Rate float64
+	// Duration stops sending payments after it elapses. If it is 0, payments are sent until Requests are sent.
+	Duration time.Duration
+	// Requests stops sending payments after that many are sent. If it is 0, payments are sent until Duration elapses.
+	Requests int
+	// Amount is the distribution of payment amounts.
+	Amount Distribution
+	// Currency enables the extended format if set. Payments are sent with unique IDs and the currency.
+	Currency string
+	// Seed seeds the generated amounts and IDs, so a run can be repeated.
+	Seed uint64
+}
+
+// Validate returns an error if the configuration can't generate load.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Connections <= 0 {
+		errs = append(errs, fmt.Errorf("connections %d must be positive", c.Connections))
+	}
+	if c.Rate < 0 {
+		errs = append(errs, fmt.Errorf("rate %v must not be negative", c.Rate))
+	}
+	if c.Duration < 0 {
+		errs = append(errs, fmt.Errorf("duration %s must not be negative", c.Duration))
+	}
+	if c.Requests < 0 {
+		errs = append(errs, fmt.Errorf("requests %d must not be negative", c.Requests))
+	}
+	if c.Duration == 0 && c.Requests == 0 {
+		errs = append(errs, errors.New("either duration or requests must be set"))
+	}
+	if c.Amount == nil {
+		errs = append(errs, errors.New("amount distribution must be set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Generator sends payments to the scheme and measures its responses.
+type Generator struct {
+	cfg    Config
+	sender Sender
+	clock  clock.Clock
+}
+
+// NewGenerator creates a new Generator instance.
+func NewGenerator(cfg Config, sender Sender, clock clock.Clock) *Generator {
+	return &Generator{
+		cfg:    cfg,
+		sender: sender,
+		clock:  clock,
+	}
+}
+
+// Run sends payments until the configured duration elapses, the configured number of requests are sent,
+// or the context is cancelled, and returns the report of the responses.
+// Payments already sent when the duration elapses are waited for, but are cancelled when the context is cancelled.
+func (g *Generator) Run(ctx context.Context) Report {
+	payments := make(chan client.Payment)
+
+	sendCtx, stopSending := context.WithCancel(ctx)
+	defer stopSending()
+	if g.cfg.Duration > 0 {
+		sendCtx, stopSending = g.clock.WithTimeout(sendCtx, g.cfg.Duration)
+		defer stopSending()
+	}
+
+	start := g.clock.Now()
+
+	go g.produce(sendCtx, payments)
+
+	results := make([]*recorder, g.cfg.Connections)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		results[i] = newRecorder()
+
+		wg.Add(1)
+		go func(r *recorder) {
+			defer wg.Done()
+
+			for payment := range payments {
+				sent := g.clock.Now()
+				response, err := g.sender.Send(ctx, payment)
+				r.record(response, err, g.clock.Since(sent))
+			}
+		}(results[i])
+	}
+
+	wg.Wait()
+
+	return newReport(g.clock.Since(start), results)
+}
+
+// produce generates payments at the configured rate until enough are sent or the context is cancelled.
+func (g *Generator) produce(ctx context.Context, payments chan<- client.Payment) {
+	defer close(payments)
+
+	r := rand.New(rand.NewPCG(g.cfg.Seed, g.cfg.Seed)) //nolint:gosec // Amounts don't need to be unpredictable.
+	prefix := fmt.Sprintf("%08x", r.Uint32())
+
+	var ticker *clock.Ticker
+	if g.cfg.Rate > 0 {
+		ticker = g.clock.Ticker(time.Duration(float64(time.Second) / g.cfg.Rate))
+		defer ticker.Stop()
+	}
+
+	for i := 1; g.cfg.Requests == 0 || i <= g.cfg.Requests; i++ {
+		payment := client.Payment{Amount: g.cfg.Amount.Amount(r)}
+		if g.cfg.Currency != "" {
+			payment.ID = fmt.Sprintf("%s-%d", prefix, i)
+			payment.Currency = g.cfg.Currency
+		}
+
+		if ticker != nil && i > 1 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case payments <- payment:
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package loadgen
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/client"
+)
+
+func Test_Generator_Run(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockSender := NewMockSender(t)
+	mockSender.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, payment client.Payment) (client.Response, error) {
+			switch payment.Amount % 4 {
+			case 0:
+				return client.Response{Status: client.StatusAccepted, Reason: "Transaction processed"}, nil
+			case 1:
+				return client.Response{Status: client.StatusRejected, Reason: "Insufficient funds"}, nil
+			case 2:
+				return client.Response{}, client.ErrConnectionClosed
+			default:
+				return client.Response{}, os.ErrDeadlineExceeded
+			}
+		}).
+		Times(100)
+
+	cfg := Config{
+		Connections: 4,
+		Requests:    100,
+		Amount:      uniform{min: 0, max: 3},
+		Seed:        1,
+	}
+
+	report := NewGenerator(cfg, mockSender, clock.New()).Run(context.Background())
+
+	assert.Equal(t, 100, report.Requests)
+	assert.Equal(t, report.Requests, report.Accepted+report.Rejected+report.Dropped+report.Errors)
+	assert.Positive(t, report.Accepted)
+	assert.Positive(t, report.Dropped)
+	assert.Positive(t, report.Errors)
+	assert.Equal(t, map[string]int{"Insufficient funds": report.Rejected}, report.RejectionReasons)
+	assert.Positive(t, report.Throughput)
+}
+
+func Test_Generator_Run_ExtendedPayments(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var (
+		mu       sync.Mutex
+		payments []client.Payment
+	)
+
+	mockSender := NewMockSender(t)
+	mockSender.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, payment client.Payment) (client.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			payments = append(payments, payment)
+
+			return client.Response{PaymentID: payment.ID, Status: client.StatusAccepted}, nil
+		})
+
+	cfg := Config{
+		Connections: 2,
+		Requests:    10,
+		Amount:      fixed{amount: 5},
+		Currency:    "GBP",
+	}
+
+	report := NewGenerator(cfg, mockSender, clock.New()).Run(context.Background())
+	assert.Equal(t, 10, report.Accepted)
+
+	ids := make(map[string]struct{})
+	for _, payment := range payments {
+		assert.Equal(t, 5, payment.Amount)
+		assert.Equal(t, "GBP", payment.Currency)
+		ids[payment.ID] = struct{}{}
+	}
+	assert.Len(t, ids, 10)
+}
+
+func Test_Generator_Run_SameSeedSendsSameAmounts(t *testing.T) {
+	amounts := func() []int {
+		var result []int
+
+		mockSender := NewMockSender(t)
+		mockSender.EXPECT().
+			Send(mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, payment client.Payment) (client.Response, error) {
+				result = append(result, payment.Amount)
+				return client.Response{Status: client.StatusAccepted}, nil
+			})
+
+		cfg := Config{Connections: 1, Requests: 20, Amount: uniform{min: 1, max: 10000}, Seed: 42}
+		NewGenerator(cfg, mockSender, clock.New()).Run(context.Background())
+
+		return result
+	}
+
+	assert.Equal(t, amounts(), amounts())
+}
+
+func Test_Generator_Run_Rate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockSender := NewMockSender(t)
+	mockSender.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Return(client.Response{Status: client.StatusAccepted}, nil).
+		Times(5)
+
+	cfg := Config{Connections: 5, Rate: 100, Requests: 5, Amount: fixed{amount: 1}}
+
+	report := NewGenerator(cfg, mockSender, clock.New()).Run(context.Background())
+
+	assert.Equal(t, 5, report.Accepted)
+	assert.GreaterOrEqual(t, report.Duration, 40*time.Millisecond)
+}
+
+func Test_Generator_Run_Duration(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockClock := clock.NewMock()
+
+	mockSender := NewMockSender(t)
+	mockSender.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		RunAndReturn(func(context.Context, client.Payment) (client.Response, error) {
+			mockClock.Add(100 * time.Millisecond)
+			return client.Response{Status: client.StatusAccepted}, nil
+		})
+
+	cfg := Config{Connections: 1, Duration: time.Second, Amount: fixed{amount: 1}}
+
+	report := NewGenerator(cfg, mockSender, mockClock).Run(context.Background())
+
+	assert.InDelta(t, 10, report.Accepted, 1)
+	assert.InDelta(t, 10, report.Throughput, 1)
+	assert.Equal(t, 100*time.Millisecond, report.Latency.P99)
+}
+
+func Test_Generator_Run_Cancelled(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	mockSender := NewMockSender(t)
+	mockSender.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, _ client.Payment) (client.Response, error) {
+			cncl()
+			<-ctx.Done()
+			return client.Response{}, ctx.Err()
+		})
+
+	cfg := Config{Connections: 3, Requests: 100, Amount: fixed{amount: 1}}
+
+	report := NewGenerator(cfg, mockSender, clock.New()).Run(ctx)
+
+	assert.Zero(t, report.Requests)
+}
+
+func Test_Config_Validate(t *testing.T) {
+	err := Config{Connections: 0, Rate: -1}.Validate()
+
+	require.Error(t, err)
+	for _, expected := range []string{
+		"connections 0 must be positive",
+		"rate -1 must not be negative",
+		"either duration or requests must be set",
+		"amount distribution must be set",
+	} {
+		assert.ErrorContains(t, err, expected)
+	}
+
+	assert.NoError(t, Config{Connections: 1, Requests: 1, Amount: fixed{}}.Validate())
+}
@@ -0,0 +1,223 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ormanli/form3-te/client"
+)
+
+// Report summarizes the responses to the payments sent by Generator.
+// Dropped counts payments answered by closing the connection, and Errors counts payments without a response for other reasons,
+// such as timeouts. Payments interrupted by cancelling the run are not counted.
+type Report struct {
+	Duration         time.Duration
+	Requests         int
+	Accepted         int
+	Rejected         int
+	Dropped          int
+	Errors           int
+	Throughput       float64
+	Latency          Latency
+	RejectionReasons map[string]int
+}
+
+// Latency summarizes the time to receive responses. Payments without a response are not included.
+type Latency struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// recorder records the responses received by a single worker, so workers don't contend with each other.
+type recorder struct {
+	accepted  int
+	rejected  int
+	dropped   int
+	errors    int
+	reasons   map[string]int
+	latencies []time.Duration
+}
+
+func newRecorder() *recorder {
+	return &recorder{reasons: make(map[string]int)}
+}
+
+// record records the result of sending a payment, which took latency.
+func (r *recorder) record(response client.Response, err error, latency time.Duration) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case errors.Is(err, client.ErrConnectionClosed):
+		r.dropped++
+		return
+	case err != nil:
+		r.errors++
+		return
+	case response.Accepted():
+		r.accepted++
+	default:
+		r.rejected++
+		r.reasons[response.Reason]++
+	}
+
+	r.latencies = append(r.latencies, latency)
+}
+
+// newReport merges the results of every worker of a run that took duration.
+func newReport(duration time.Duration, recorders []*recorder) Report {
+	report := Report{
+		Duration:         duration,
+		RejectionReasons: make(map[string]int),
+	}
+
+	var latencies []time.Duration
+	for _, r := range recorders {
+		report.Accepted += r.accepted
+		report.Rejected += r.rejected
+		report.Dropped += r.dropped
+		report.Errors += r.errors
+
+		for reason, n := range r.reasons {
+			report.RejectionReasons[reason] += n
+		}
+
+		latencies = append(latencies, r.latencies...)
+	}
+
+	report.Requests = report.Accepted + report.Rejected + report.Dropped + report.Errors
+	if duration > 0 {
+		report.Throughput = float64(report.Requests) / duration.Seconds()
+	}
+
+	report.Latency = newLatency(latencies)
+
+	return report
+}
+
+func newLatency(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	slices.Sort(latencies)
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+
+	return Latency{
+		Min:  latencies[0],
+		Mean: sum / time.Duration(len(latencies)),
+		P50:  percentile(latencies, 50),
+		P90:  percentile(latencies, 90),
+		P95:  percentile(latencies, 95),
+		P99:  percentile(latencies, 99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the p-th percentile of the sorted latencies using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+
+	return sorted[max(rank, 1)-1]
+}
+
+// jsonReport is the JSON representation of the report. Durations are in milliseconds.
+type jsonReport struct {
+	DurationMs       float64        `json:"durationMs"`
+	Requests         int            `json:"requests"`
+	Accepted         int            `json:"accepted"`
+	Rejected         int            `json:"rejected"`
+	Dropped          int            `json:"dropped"`
+	Errors           int            `json:"errors"`
+	Throughput       float64        `json:"throughput"`
+	Latency          jsonLatency    `json:"latencyMs"`
+	RejectionReasons map[string]int `json:"rejectionReasons"`
+}
+
+type jsonLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// MarshalJSON returns the JSON representation of the report.
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonReport{
+		DurationMs: milliseconds(r.Duration),
+		Requests:   r.Requests,
+		Accepted:   r.Accepted,
+		Rejected:   r.Rejected,
+		Dropped:    r.Dropped,
+		Errors:     r.Errors,
+		Throughput: r.Throughput,
+		Latency: jsonLatency{
+			Min:  milliseconds(r.Latency.Min),
+			Mean: milliseconds(r.Latency.Mean),
+			P50:  milliseconds(r.Latency.P50),
+			P90:  milliseconds(r.Latency.P90),
+			P95:  milliseconds(r.Latency.P95),
+			P99:  milliseconds(r.Latency.P99),
+			Max:  milliseconds(r.Latency.Max),
+		},
+		RejectionReasons: r.RejectionReasons,
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteText writes the human-readable report.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Duration:\t%s\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(tw, "Requests:\t%d\n", r.Requests)
+	fmt.Fprintf(tw, "Throughput:\t%.2f/s\n", r.Throughput)
+	fmt.Fprintf(tw, "Accepted:\t%d\n", r.Accepted)
+	fmt.Fprintf(tw, "Rejected:\t%d\n", r.Rejected)
+	fmt.Fprintf(tw, "Dropped:\t%d\n", r.Dropped)
+	fmt.Fprintf(tw, "Errors:\t%d\n", r.Errors)
+	fmt.Fprintf(tw, "Latency:\tmin %s, mean %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P95, r.Latency.P99, r.Latency.Max)
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.RejectionReasons) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w, "Rejection reasons:"); err != nil {
+		return err
+	}
+
+	for _, reason := range slices.Sorted(maps.Keys(r.RejectionReasons)) {
+		if _, err := fmt.Fprintf(w, "  %s: %d\n", reason, r.RejectionReasons[reason]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/client"
+)
+
+func Test_newReport(t *testing.T) {
+	first := newRecorder()
+	second := newRecorder()
+
+	for i := 1; i <= 100; i++ {
+		r := first
+		if i%2 == 0 {
+			r = second
+		}
+
+		response := client.Response{Status: client.StatusAccepted}
+		if i%10 == 0 {
+			response = client.Response{Status: client.StatusRejected, Reason: "Insufficient funds"}
+		}
+
+		r.record(response, nil, time.Duration(i)*time.Millisecond)
+	}
+
+	first.record(client.Response{}, client.ErrConnectionClosed, time.Second)
+	second.record(client.Response{}, context.Canceled, time.Second)
+
+	report := newReport(2*time.Second, []*recorder{first, second})
+
+	assert.Equal(t, Report{
+		Duration:   2 * time.Second,
+		Requests:   101,
+		Accepted:   90,
+		Rejected:   10,
+		Dropped:    1,
+		Throughput: 50.5,
+		Latency: Latency{
+			Min:  time.Millisecond,
+			Mean: 50500 * time.Microsecond,
+			P50:  50 * time.Millisecond,
+			P90:  90 * time.Millisecond,
+			P95:  95 * time.Millisecond,
+			P99:  99 * time.Millisecond,
+			Max:  100 * time.Millisecond,
+		},
+		RejectionReasons: map[string]int{"Insufficient funds": 10},
+	}, report)
+}
+
+func Test_Report_Output(t *testing.T) {
+	report := Report{
+		Duration:   1500 * time.Millisecond,
+		Requests:   3,
+		Accepted:   1,
+		Rejected:   2,
+		Throughput: 2,
+		Latency: Latency{
+			Min:  time.Millisecond,
+			Mean: 2 * time.Millisecond,
+			P50:  2 * time.Millisecond,
+			P90:  3 * time.Millisecond,
+			P95:  3 * time.Millisecond,
+			P99:  3 * time.Millisecond,
+			Max:  3 * time.Millisecond,
+		},
+		RejectionReasons: map[string]int{"Insufficient funds": 1, "Duplicate": 1},
+	}
+
+	var sb strings.Builder
+	require.NoError(t, report.WriteText(&sb))
+	assert.Equal(t, `Duration:    1.5s
+Requests:    3
+Throughput:  2.00/s
+Accepted:    1
+Rejected:    2
+Dropped:     0
+Errors:      0
+Latency:     min 1ms, mean 2ms, p50 2ms, p90 3ms, p95 3ms, p99 3ms, max 3ms
+Rejection reasons:
+  Duplicate: 1
+  Insufficient funds: 1
+`, sb.String())
+
+	b, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"durationMs": 1500,
+		"requests": 3,
+		"accepted": 1,
+		"rejected": 2,
+		"dropped": 0,
+		"errors": 0,
+		"throughput": 2,
+		"latencyMs": {"min": 1, "mean": 2, "p50": 2, "p90": 3, "p95": 3, "p99": 3, "max": 3},
+		"rejectionReasons": {"Duplicate": 1, "Insufficient funds": 1}
+	}`, string(b))
+}
@@ -0,0 +1,235 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/ormanli/form3-te/client"
+)
+
+// ErrNoResponse is the error of lines that weren't answered before the connection was closed or the response timeout was reached.
+var ErrNoResponse = errors.New("no response")
+
+// notificationPrefix starts the notifications the scheme sends between responses when settlement is asynchronous.
+const notificationPrefix = "NOTIFY|"
+
+// Config defines how Player replays records.
+type Config struct {
+	// Address is the host and port of the scheme.
+	Address string
+	// TLSConfig enables TLS if set.
+	TLSConfig *tls.Config
+	// Speed scales the recorded timing, e.g. 2 replays twice as fast. If it is 0, lines are sent as fast as possible.
+	Speed float64
+	// ResponseTimeout is the maximum time to wait for the response to a line.
+	// If it is 0, responses are waited for until the context is cancelled.
+	ResponseTimeout time.Duration
+}
+
+// Result is the response to a replayed record. Err is set if the line couldn't be sent or wasn't answered.
+type Result struct {
+	Record   Record
+	Response string
+	Err      error
+	Latency  time.Duration
+}
+
+// Player replays records against a scheme.
+type Player struct {
+	cfg   Config
+	clock clock.Clock
+}
+
+// NewPlayer creates a new Player instance.
+func NewPlayer(cfg Config, clock clock.Clock) *Player {
+	return &Player{
+		cfg:   cfg,
+		clock: clock,
+	}
+}
+
+// Play replays the records and returns their results in the same order.
+// Each recorded connection is replayed on its own connection, which is established when its first line is due.
+// Lines are due at their recorded time relative to the earliest record, scaled by the speed.
+// As the protocol allows one request at a time on a connection, a line is sent when it is due
+// and the previous line of its connection is answered or its response timeout is reached.
+func (p *Player) Play(ctx context.Context, records []Record) []Result {
+	results := make([]Result, len(records))
+	if len(records) == 0 {
+		return results
+	}
+
+	var order []uint64
+	connections := make(map[uint64][]int)
+	origin := records[0].Time
+	for i, r := range records {
+		results[i].Record = r
+
+		if _, ok := connections[r.Connection]; !ok {
+			order = append(order, r.Connection)
+		}
+		connections[r.Connection] = append(connections[r.Connection], i)
+
+		if r.Time.Before(origin) {
+			origin = r.Time
+		}
+	}
+
+	start := p.clock.Now()
+	due := func(r Record) time.Time {
+		if p.cfg.Speed <= 0 {
+			return start
+		}
+
+		return start.Add(time.Duration(float64(r.Time.Sub(origin)) / p.cfg.Speed))
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(indices []int) {
+			defer wg.Done()
+			p.playConnection(ctx, indices, results, due)
+		}(connections[id])
+	}
+	wg.Wait()
+
+	return results
+}
+
+// connection is a replayed connection with its buffered reader.
+type connection struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// playConnection replays the records at the indices on a new connection, and sets their results.
+// The connection is replaced after a response timeout, so a late response isn't taken as the response to the next line.
+func (p *Player) playConnection(ctx context.Context, indices []int, results []Result, due func(Record) time.Time) {
+	fail := func(from int, err error) {
+		for _, i := range indices[from:] {
+			results[i].Err = err
+		}
+	}
+
+	var conn *connection
+	defer func() {
+		if conn != nil {
+			conn.Close() //nolint:errcheck
+		}
+	}()
+
+	for n, i := range indices {
+		if err := p.waitUntil(ctx, due(results[i].Record)); err != nil {
+			fail(n, err)
+			return
+		}
+
+		if conn == nil {
+			c, err := p.dial(ctx)
+			if err != nil {
+				fail(n, err)
+				return
+			}
+
+			conn = &connection{Conn: c, reader: bufio.NewReader(c)}
+		}
+
+		response, latency, err := p.send(ctx, conn, results[i].Record.Line)
+		switch {
+		case err == nil:
+			results[i].Response, results[i].Latency = response, latency
+		case errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() == nil:
+			results[i].Err = err
+
+			conn.Close() //nolint:errcheck
+			conn = nil
+		default:
+			fail(n, err)
+			return
+		}
+	}
+}
+
+// send writes the line to the connection and reads its response, skipping the notifications received before it.
+func (p *Player) send(ctx context.Context, conn *connection, line string) (string, time.Duration, error) {
+	deadline := time.Time{}
+	if p.cfg.ResponseTimeout > 0 {
+		deadline = time.Now().Add(p.cfg.ResponseTimeout)
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", 0, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0)) //nolint:errcheck
+	})
+	defer stop()
+
+	if _, err := io.WriteString(conn, line+"\n"); err != nil {
+		return "", 0, fmt.Errorf("can't send line: %w", err)
+	}
+
+	at := p.clock.Now()
+
+	for {
+		response, err := conn.reader.ReadString('\n')
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: %w", ErrNoResponse, err)
+		}
+
+		if !strings.HasPrefix(response, notificationPrefix) {
+			return strings.TrimSuffix(response, "\n"), p.clock.Since(at), nil
+		}
+	}
+}
+
+// waitUntil waits until the given time or the context is cancelled.
+func (p *Player) waitUntil(ctx context.Context, at time.Time) error {
+	d := at.Sub(p.clock.Now())
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := p.clock.Timer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// dial establishes a connection to the scheme.
+func (p *Player) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: client.DefaultDialTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if p.cfg.TLSConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: p.cfg.TLSConfig}).DialContext(ctx, "tcp", p.cfg.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", p.cfg.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't connect: %w", err)
+	}
+
+	return conn, nil
+}
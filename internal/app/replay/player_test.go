@@ -0,0 +1,329 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
+	"github.com/ormanli/form3-te/internal/infra/transport/tcp"
+)
+
+func Test_Player(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		speed            float64
+		records          []Record
+		expectedResults  []Result
+		expectedArrivals map[string][]string
+		minDuration      time.Duration
+	}{
+		{
+			name:  "Original timing",
+			speed: 1,
+			records: []Record{
+				{Connection: 1, Time: start, Line: "PAYMENT|1"},
+				{Connection: 2, Time: start.Add(50 * time.Millisecond), Line: "PAYMENT|2"},
+				{Connection: 1, Time: start.Add(100 * time.Millisecond), Line: "PAYMENT|3"},
+			},
+			expectedResults: []Result{
+				{Record: Record{Connection: 1, Time: start, Line: "PAYMENT|1"}, Response: "RESPONSE|ACCEPTED|PAYMENT|1"},
+				{Record: Record{Connection: 2, Time: start.Add(50 * time.Millisecond), Line: "PAYMENT|2"}, Response: "RESPONSE|ACCEPTED|PAYMENT|2"},
+				{Record: Record{Connection: 1, Time: start.Add(100 * time.Millisecond), Line: "PAYMENT|3"}, Response: "RESPONSE|ACCEPTED|PAYMENT|3"},
+			},
+			minDuration: 100 * time.Millisecond,
+		},
+		{
+			name:  "Accelerated",
+			speed: 100,
+			records: []Record{
+				{Connection: 1, Time: start, Line: "PAYMENT|1"},
+				{Connection: 1, Time: start.Add(time.Second), Line: "PAYMENT|2"},
+			},
+			expectedResults: []Result{
+				{Record: Record{Connection: 1, Time: start, Line: "PAYMENT|1"}, Response: "RESPONSE|ACCEPTED|PAYMENT|1"},
+				{Record: Record{Connection: 1, Time: start.Add(time.Second), Line: "PAYMENT|2"}, Response: "RESPONSE|ACCEPTED|PAYMENT|2"},
+			},
+			minDuration: 10 * time.Millisecond,
+		},
+		{
+			name:  "As fast as possible",
+			speed: 0,
+			records: []Record{
+				{Connection: 1, Time: start, Line: "PAYMENT|1"},
+				{Connection: 1, Time: start.Add(time.Hour), Line: "PAYMENT|2"},
+			},
+			expectedResults: []Result{
+				{Record: Record{Connection: 1, Time: start, Line: "PAYMENT|1"}, Response: "RESPONSE|ACCEPTED|PAYMENT|1"},
+				{Record: Record{Connection: 1, Time: start.Add(time.Hour), Line: "PAYMENT|2"}, Response: "RESPONSE|ACCEPTED|PAYMENT|2"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			server := newEchoServer(t, func(line string) (string, bool) {
+				return "RESPONSE|ACCEPTED|" + line + "\n", true
+			})
+			defer server.close()
+
+			player := NewPlayer(Config{Address: server.addr(), Speed: test.speed, ResponseTimeout: time.Second}, clock.New())
+
+			begin := time.Now()
+			results := player.Play(context.Background(), test.records)
+			assert.GreaterOrEqual(t, time.Since(begin), test.minDuration)
+			assert.Less(t, time.Since(begin), time.Second)
+
+			for i := range results {
+				results[i].Latency = 0
+			}
+			assert.Equal(t, test.expectedResults, results)
+
+			connections := make(map[uint64]bool)
+			for _, r := range test.records {
+				connections[r.Connection] = true
+			}
+			assert.EqualValues(t, len(connections), server.connections())
+		})
+	}
+}
+
+func Test_Player_ConnectionClosed(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := newEchoServer(t, func(line string) (string, bool) {
+		if line == "PAYMENT|2" {
+			return "", false
+		}
+
+		return "RESPONSE|ACCEPTED|Transaction processed\n", true
+	})
+	defer server.close()
+
+	player := NewPlayer(Config{Address: server.addr(), ResponseTimeout: time.Second}, clock.New())
+
+	results := player.Play(context.Background(), []Record{
+		{Connection: 1, Line: "PAYMENT|1"},
+		{Connection: 1, Line: "PAYMENT|2"},
+		{Connection: 1, Line: "PAYMENT|3"},
+	})
+
+	require.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, ErrNoResponse)
+	assert.Error(t, results[2].Err)
+}
+
+func Test_Player_ResponseTimeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := newEchoServer(t, func(line string) (string, bool) {
+		if line == "PAYMENT|1" {
+			return "", true
+		}
+
+		return "RESPONSE|ACCEPTED|" + line + "\n", true
+	})
+	defer server.close()
+
+	player := NewPlayer(Config{Address: server.addr(), ResponseTimeout: 50 * time.Millisecond}, clock.New())
+
+	results := player.Play(context.Background(), []Record{
+		{Connection: 1, Line: "PAYMENT|1"},
+		{Connection: 1, Line: "PAYMENT|2"},
+	})
+
+	require.Len(t, results, 2)
+	assert.ErrorIs(t, results[0].Err, ErrNoResponse)
+	assert.ErrorIs(t, results[0].Err, os.ErrDeadlineExceeded)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, "RESPONSE|ACCEPTED|PAYMENT|2", results[1].Response)
+
+	// The line after the timeout is sent on a new connection, so the late response can't be taken as its response.
+	assert.Equal(t, 2, server.connections())
+}
+
+func Test_Player_AsyncSettlement(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+	require.NoError(t, listener.Close())
+
+	var cfg simulator.Config
+	require.NoError(t, envconfig.Process("test_replay", &cfg))
+	cfg.ServerPort = port
+	cfg.AdminPort = 0
+	cfg.ServerAsyncSettlement = true
+
+	transport := tcp.NewTransport(cfg, simulator.NewDummyService(cfg), clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(ctx) //nolint:errcheck
+	}()
+
+	address := fmt.Sprintf("localhost:%d", port)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return false
+		}
+
+		return conn.Close() == nil
+	}, time.Second, 10*time.Millisecond)
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	player := NewPlayer(Config{Address: address, Speed: 1, ResponseTimeout: time.Second}, clock.New())
+
+	// The notification of the first payment is received before the response to the second one.
+	results := player.Play(context.Background(), []Record{
+		{Connection: 1, Time: start, Line: "PAYMENT|abc-1|1|GBP"},
+		{Connection: 1, Time: start.Add(100 * time.Millisecond), Line: "PAYMENT|abc-2|1|GBP"},
+	})
+
+	require.Len(t, results, 2)
+	for i, expected := range []string{"RESPONSE|abc-1|PENDING|Payment in progress", "RESPONSE|abc-2|PENDING|Payment in progress"} {
+		assert.NoError(t, results[i].Err)
+		assert.Equal(t, expected, results[i].Response)
+	}
+
+	cncl()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "transport did not stop")
+	}
+}
+
+func Test_Player_Cancelled(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := newEchoServer(t, func(string) (string, bool) {
+		return "RESPONSE|ACCEPTED|Transaction processed\n", true
+	})
+	defer server.close()
+
+	mockClock := clock.NewMock()
+	player := NewPlayer(Config{Address: server.addr(), Speed: 1}, mockClock)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	resultsChan := make(chan []Result, 1)
+	go func() {
+		resultsChan <- player.Play(ctx, []Record{
+			{Connection: 1, Time: time.Unix(0, 0), Line: "PAYMENT|1"},
+			{Connection: 1, Time: time.Unix(60, 0), Line: "PAYMENT|2"},
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return server.connections() == 1
+	}, time.Second, time.Millisecond)
+	cncl()
+
+	results := <-resultsChan
+	assert.ErrorIs(t, results[1].Err, context.Canceled)
+}
+
+// echoServer answers each request line with the response returned by handle.
+// If handle returns false, the connection is closed without writing the response.
+type echoServer struct {
+	listener net.Listener
+	handle   func(string) (string, bool)
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    []net.Conn
+}
+
+func newEchoServer(t *testing.T, handle func(string) (string, bool)) *echoServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	s := &echoServer{listener: listener, handle: handle}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+
+			s.wg.Add(1)
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *echoServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *echoServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+func (s *echoServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		response, ok := s.handle(strings.TrimSpace(scanner.Text()))
+		if !ok {
+			return
+		}
+
+		if _, err := conn.Write([]byte(response)); err != nil {
+			return
+		}
+	}
+}
+
+// close stops accepting connections, closes open connections and waits for them to be served.
+func (s *echoServer) close() {
+	s.listener.Close() //nolint:errcheck
+
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close() //nolint:errcheck
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
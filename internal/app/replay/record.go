@@ -0,0 +1,88 @@
+// Package replay records the lines clients send to the scheme and replays them against a scheme endpoint.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// maxRecordLength is the maximum length of a record in a replay file. Request lines are up to 64KB,
+// and JSON escaping can make a record up to six times longer.
+const maxRecordLength = 1024 * 1024
+
+// Record is a line received on a connection at the given time.
+type Record struct {
+	Connection uint64    `json:"connection"`
+	Time       time.Time `json:"time"`
+	Line       string    `json:"line"`
+}
+
+// Recorder writes records to a replay file as JSON lines. It is safe for concurrent use.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder creates the replay file configured in cfg, replacing an existing file.
+func NewRecorder(cfg simulator.Config) (*Recorder, error) {
+	file, err := os.OpenFile(cfg.RecordFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("can't create replay file: %w", err)
+	}
+
+	return &Recorder{file: file}, nil
+}
+
+// Record writes the line received on the connection at the given time. Errors are logged, as they must not affect the response.
+func (r *Recorder) Record(connectionID uint64, at time.Time, line string) {
+	b, err := json.Marshal(Record{Connection: connectionID, Time: at, Line: line})
+	if err != nil {
+		slog.Error("Can't encode replay record", "error", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.file.Write(append(b, '\n')); err != nil {
+		slog.Error("Can't write replay record", "error", err, "file", r.file.Name())
+	}
+}
+
+// Close closes the replay file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+// ReadRecords reads the records of a replay file in the order they were recorded.
+func ReadRecords(reader io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxRecordLength)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
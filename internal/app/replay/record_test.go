@@ -0,0 +1,64 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_Recorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("previous recording\n"), 0o600))
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expected := []Record{
+		{Connection: 1, Time: start, Line: "PAYMENT|1"},
+		{Connection: 2, Time: start.Add(time.Millisecond), Line: "PAYMENT|abc-1|2|GBP"},
+		{Connection: 1, Time: start.Add(time.Second), Line: "HELLO"},
+	}
+
+	recorder, err := NewRecorder(simulator.Config{RecordFile: path})
+	require.NoError(t, err)
+
+	for _, r := range expected {
+		recorder.Record(r.Connection, r.Time, r.Line)
+	}
+	require.NoError(t, recorder.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+
+	actual, err := ReadRecords(f)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func Test_ReadRecords_LongLine(t *testing.T) {
+	// A line near the transport limit, made of characters which are escaped in JSON.
+	line := strings.Repeat("\x01", bufio.MaxScanTokenSize-1)
+
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(Record{Connection: 1, Line: line}))
+	require.Greater(t, buf.Len(), bufio.MaxScanTokenSize)
+
+	records, err := ReadRecords(&buf)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, line, records[0].Line)
+}
+
+func Test_ReadRecords_Invalid(t *testing.T) {
+	_, err := ReadRecords(strings.NewReader(`{"connection":1,"time":"2024-01-02T03:04:05Z","line":"PAYMENT|1"}` + "\nHELLO\n"))
+	require.ErrorContains(t, err, "line 2:")
+}
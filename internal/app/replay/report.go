@@ -0,0 +1,34 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteResults writes a line for each result, followed by the number of lines without a response.
+func WriteResults(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "CONNECTION\tLINE\tRESPONSE\tLATENCY")
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(tw, "%d\t%s\terror: %s\t\n", r.Record.Connection, r.Record.Line, r.Err)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", r.Record.Connection, r.Record.Line, r.Response, r.Latency.Round(time.Microsecond))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "Replayed %d lines, %d without response.\n", len(results), failed)
+
+	return err
+}
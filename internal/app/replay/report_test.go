@@ -0,0 +1,29 @@
+package replay
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteResults(t *testing.T) {
+	var sb strings.Builder
+
+	err := WriteResults(&sb, []Result{
+		{Record: Record{Connection: 1, Line: "PAYMENT|1"}, Response: "RESPONSE|ACCEPTED|Transaction processed", Latency: 1500 * time.Microsecond},
+		{Record: Record{Connection: 2, Line: "PAYMENT|2"}, Err: ErrNoResponse},
+		{Record: Record{Connection: 1, Line: "PAYMENT|3"}, Err: errors.New("can't connect")},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `CONNECTION  LINE       RESPONSE                                 LATENCY
+1           PAYMENT|1  RESPONSE|ACCEPTED|Transaction processed  1.5ms
+2           PAYMENT|2  error: no response                       
+1           PAYMENT|3  error: can't connect                     
+Replayed 3 lines, 2 without response.
+`, sb.String())
+}
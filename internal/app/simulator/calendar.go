@@ -0,0 +1,266 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// ClosureAction defines what the scheme does while it is closed.
+type ClosureAction string
+
+const (
+	// ClosureReject rejects payments with the reason of the closure.
+	ClosureReject ClosureAction = "reject"
+	// ClosureRefuse refuses new connections and closes connections sending payments without a response.
+	ClosureRefuse ClosureAction = "refuse"
+)
+
+// Calendar defines when the scheme is closed: outside its open hours, during recurring maintenance windows and during outages.
+// Times are in the time zone of the calendar, which is UTC by default.
+// Action and Reason are the defaults of the closures that don't set their own.
+type Calendar struct {
+	Timezone    string        `json:"timezone,omitempty"`
+	Action      ClosureAction `json:"action,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+	OpenHours   []Hours       `json:"openHours,omitempty"`
+	Maintenance []Maintenance `json:"maintenance,omitempty"`
+	Outages     []Outage      `json:"outages,omitempty"`
+
+	location *time.Location
+}
+
+// Hours is a recurring period on the days of the week, or every day if no days are set.
+// If To is before From, the period ends on the next day.
+type Hours struct {
+	Days []Weekday `json:"days,omitempty"`
+	From TimeOfDay `json:"from"`
+	To   TimeOfDay `json:"to"`
+}
+
+// Maintenance is a recurring maintenance window, during which the scheme is closed.
+type Maintenance struct {
+	Hours
+	Action ClosureAction `json:"action,omitempty"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// Outage is an ad-hoc period between From and Until, during which the scheme is closed.
+type Outage struct {
+	From   time.Time     `json:"from"`
+	Until  time.Time     `json:"until"`
+	Action ClosureAction `json:"action,omitempty"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// Closure is what the scheme does with payments while it is closed.
+type Closure struct {
+	Action ClosureAction
+	Reason string
+}
+
+// result returns the error returned for payments received during the closure.
+func (c Closure) result() error {
+	if c.Action == ClosureRefuse {
+		return ErrDropConnection
+	}
+
+	return ClosedError{Reason: c.Reason}
+}
+
+// Weekday is a day of the week, represented as its lowercase three letter abbreviation such as "mon" in JSON.
+type Weekday time.Weekday
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// UnmarshalText parses the day of the week.
+func (d *Weekday) UnmarshalText(b []byte) error {
+	i := slices.Index(weekdays, string(b))
+	if i < 0 {
+		return fmt.Errorf("unknown day %q", b)
+	}
+
+	*d = Weekday(i)
+
+	return nil
+}
+
+// MarshalText returns the abbreviation of the day of the week.
+func (d Weekday) MarshalText() ([]byte, error) {
+	return []byte(weekdays[d]), nil
+}
+
+// TimeOfDay is the time since midnight, represented as "15:04" in JSON. "24:00" is the end of the day.
+type TimeOfDay time.Duration
+
+// UnmarshalText parses the time of day.
+func (t *TimeOfDay) UnmarshalText(b []byte) error {
+	if string(b) == "24:00" {
+		*t = TimeOfDay(24 * time.Hour)
+		return nil
+	}
+
+	parsed, err := time.Parse("15:04", string(b))
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q", b)
+	}
+
+	*t = TimeOfDay(time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute)
+
+	return nil
+}
+
+// MarshalText returns the time of day as hours and minutes.
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	d := time.Duration(t)
+
+	return []byte(fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)), nil
+}
+
+// ParseCalendar reads a JSON calendar and validates it.
+func ParseCalendar(r io.Reader) (Calendar, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var c Calendar
+	if err := decoder.Decode(&c); err != nil {
+		return Calendar{}, fmt.Errorf("can't decode calendar: %w", err)
+	}
+
+	if err := c.validate(); err != nil {
+		return Calendar{}, err
+	}
+
+	return c, nil
+}
+
+// validate checks the calendar and loads its time zone.
+func (c *Calendar) validate() error {
+	var errs []error
+
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("timezone: %w", err))
+	}
+	c.location = location
+
+	if err := c.Action.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for i, h := range c.OpenHours {
+		if err := h.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("open hours %d: %w", i, err))
+		}
+	}
+
+	for i, m := range c.Maintenance {
+		if err := errors.Join(m.validate(), m.Action.validate()); err != nil {
+			errs = append(errs, fmt.Errorf("maintenance %d: %w", i, err))
+		}
+	}
+
+	for i, o := range c.Outages {
+		if !o.Until.After(o.From) {
+			errs = append(errs, fmt.Errorf("outage %d: until must be after from", i))
+		}
+		if err := o.Action.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("outage %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a ClosureAction) validate() error {
+	switch a {
+	case "", ClosureReject, ClosureRefuse:
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", a)
+	}
+}
+
+func (h Hours) validate() error {
+	switch {
+	case h.From == h.To:
+		return errors.New("from and to must differ")
+	case h.From >= TimeOfDay(24*time.Hour):
+		return errors.New("from must be before 24:00")
+	default:
+		return nil
+	}
+}
+
+// ClosureAt returns the closure at t. It returns false if the scheme is open.
+// Outages take precedence over maintenance windows, which take precedence over the open hours.
+func (c Calendar) ClosureAt(t time.Time) (Closure, bool) {
+	if c.location != nil {
+		t = t.In(c.location)
+	} else {
+		t = t.UTC()
+	}
+
+	for _, o := range c.Outages {
+		if !t.Before(o.From) && t.Before(o.Until) {
+			return c.closure(o.Action, o.Reason), true
+		}
+	}
+
+	for _, m := range c.Maintenance {
+		if m.contains(t) {
+			return c.closure(m.Action, m.Reason), true
+		}
+	}
+
+	if len(c.OpenHours) == 0 {
+		return Closure{}, false
+	}
+
+	for _, h := range c.OpenHours {
+		if h.contains(t) {
+			return Closure{}, false
+		}
+	}
+
+	return c.closure("", ""), true
+}
+
+// closure returns the closure with the action and reason, or the defaults of the calendar if they aren't set.
+func (c Calendar) closure(action ClosureAction, reason string) Closure {
+	if action == "" {
+		action = c.Action
+	}
+	if action == "" {
+		action = ClosureReject
+	}
+
+	if reason == "" {
+		reason = c.Reason
+	}
+	if reason == "" {
+		reason = ErrSchemeClosed.Error()
+	}
+
+	return Closure{Action: action, Reason: reason}
+}
+
+// contains returns true if t is within the hours.
+func (h Hours) contains(t time.Time) bool {
+	elapsed := TimeOfDay(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second)
+
+	if h.From < h.To {
+		return h.on(t.Weekday()) && elapsed >= h.From && elapsed < h.To
+	}
+
+	// The hours end on the next day.
+	return (h.on(t.Weekday()) && elapsed >= h.From) || (h.on((t.Weekday()+6)%7) && elapsed < h.To)
+}
+
+// on returns true if the hours apply to the day.
+func (h Hours) on(day time.Weekday) bool {
+	return len(h.Days) == 0 || slices.Contains(h.Days, Weekday(day))
+}
@@ -0,0 +1,34 @@
+package simulator
+
+import (
+	"context"
+
+	"github.com/benbjohnson/clock"
+)
+
+// CalendarService rejects payments while the scheme is closed according to its calendar,
+// and processes them using an underlying service while it is open.
+type CalendarService struct {
+	calendar Calendar
+	service  Service
+	clock    clock.Clock
+}
+
+// NewCalendarService creates a new CalendarService with the given calendar and service.
+func NewCalendarService(calendar Calendar, service Service, clock clock.Clock) *CalendarService {
+	return &CalendarService{
+		calendar: calendar,
+		service:  service,
+		clock:    clock,
+	}
+}
+
+// Process processes the payment using the underlying service if the scheme is open.
+// While the scheme is closed, payments are rejected with a ClosedError, or ErrDropConnection is returned if connections are refused.
+func (c *CalendarService) Process(ctx context.Context, payment Payment) error {
+	if closure, ok := c.calendar.ClosureAt(c.clock.Now()); ok {
+		return closure.result()
+	}
+
+	return c.service.Process(ctx, payment)
+}
@@ -0,0 +1,44 @@
+package simulator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CalendarService(t *testing.T) {
+	calendar, err := ParseCalendar(strings.NewReader(`{
+		"openHours": [{"from": "06:00", "to": "22:00"}],
+		"maintenance": [{"from": "12:00", "to": "12:30", "action": "refuse"}]
+	}`))
+	require.NoError(t, err)
+
+	payment := Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Twice()
+
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC))
+
+	service := NewCalendarService(calendar, mockService, mockClock)
+
+	err = service.Process(context.Background(), payment)
+	assert.ErrorIs(t, err, ErrSchemeClosed)
+	assert.EqualError(t, err, "scheme closed")
+
+	mockClock.Add(time.Hour)
+	assert.NoError(t, service.Process(context.Background(), payment))
+
+	mockClock.Add(6 * time.Hour)
+	assert.ErrorIs(t, service.Process(context.Background(), payment), ErrDropConnection)
+
+	mockClock.Add(30 * time.Minute)
+	assert.NoError(t, service.Process(context.Background(), payment))
+}
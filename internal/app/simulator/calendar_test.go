@@ -0,0 +1,168 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseCalendar(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		assertFunc func(*testing.T, Calendar, error)
+	}{
+		{
+			name: "Valid calendar",
+			input: `{
+				"timezone": "UTC",
+				"reason": "Scheme closed for the day",
+				"openHours": [{"days": ["mon", "tue", "wed", "thu", "fri"], "from": "06:00", "to": "24:00"}],
+				"maintenance": [{"days": ["sun"], "from": "23:00", "to": "01:00", "action": "refuse"}],
+				"outages": [{"from": "2026-10-20T10:00:00Z", "until": "2026-10-20T11:00:00Z", "reason": "Outage"}]
+			}`,
+			assertFunc: func(t *testing.T, c Calendar, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, "Scheme closed for the day", c.Reason)
+				require.Len(t, c.OpenHours, 1)
+				assert.Equal(t, Hours{
+					Days: []Weekday{Weekday(time.Monday), Weekday(time.Tuesday), Weekday(time.Wednesday), Weekday(time.Thursday), Weekday(time.Friday)},
+					From: TimeOfDay(6 * time.Hour),
+					To:   TimeOfDay(24 * time.Hour),
+				}, c.OpenHours[0])
+				require.Len(t, c.Maintenance, 1)
+				assert.Equal(t, ClosureRefuse, c.Maintenance[0].Action)
+				assert.Equal(t, []Weekday{Weekday(time.Sunday)}, c.Maintenance[0].Days)
+				require.Len(t, c.Outages, 1)
+				assert.Equal(t, "Outage", c.Outages[0].Reason)
+			},
+		},
+		{
+			name:  "Empty calendar",
+			input: `{}`,
+			assertFunc: func(t *testing.T, c Calendar, err error) {
+				require.NoError(t, err)
+				_, closed := c.ClosureAt(time.Now())
+				assert.False(t, closed)
+			},
+		},
+		{
+			name:  "Unknown field",
+			input: `{"hours": []}`,
+			assertFunc: func(t *testing.T, _ Calendar, err error) {
+				assert.ErrorContains(t, err, `unknown field "hours"`)
+			},
+		},
+		{
+			name:  "Unknown day",
+			input: `{"openHours": [{"days": ["monday"], "from": "06:00", "to": "22:00"}]}`,
+			assertFunc: func(t *testing.T, _ Calendar, err error) {
+				assert.ErrorContains(t, err, `unknown day "monday"`)
+			},
+		},
+		{
+			name:  "Invalid time of day",
+			input: `{"openHours": [{"from": "6am", "to": "22:00"}]}`,
+			assertFunc: func(t *testing.T, _ Calendar, err error) {
+				assert.ErrorContains(t, err, `invalid time of day "6am"`)
+			},
+		},
+		{
+			name: "Invalid calendar",
+			input: `{
+				"timezone": "Mars/Olympus",
+				"action": "ignore",
+				"openHours": [{"from": "06:00", "to": "06:00"}],
+				"maintenance": [{"from": "24:00", "to": "01:00", "action": "close"}],
+				"outages": [{"from": "2026-10-20T11:00:00Z", "until": "2026-10-20T10:00:00Z"}]
+			}`,
+			assertFunc: func(t *testing.T, _ Calendar, err error) {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, "timezone: unknown time zone Mars/Olympus")
+				assert.ErrorContains(t, err, `unknown action "ignore"`)
+				assert.ErrorContains(t, err, "open hours 0: from and to must differ")
+				assert.ErrorContains(t, err, "maintenance 0: from must be before 24:00\nunknown action \"close\"")
+				assert.ErrorContains(t, err, "outage 0: until must be after from")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := ParseCalendar(strings.NewReader(test.input))
+			test.assertFunc(t, c, err)
+		})
+	}
+}
+
+func Test_Calendar_ClosureAt(t *testing.T) {
+	c, err := ParseCalendar(strings.NewReader(`{
+		"timezone": "Europe/London",
+		"reason": "outside operating hours",
+		"openHours": [
+			{"days": ["mon", "tue", "wed", "thu", "fri"], "from": "06:00", "to": "22:00"},
+			{"days": ["sat"], "from": "08:00", "to": "12:00"}
+		],
+		"maintenance": [{"days": ["tue"], "from": "21:30", "to": "06:30", "action": "refuse", "reason": "maintenance"}],
+		"outages": [{"from": "2026-10-21T10:00:00+01:00", "until": "2026-10-21T11:00:00+01:00"}]
+	}`))
+	require.NoError(t, err)
+
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	closed := Closure{Action: ClosureReject, Reason: "outside operating hours"}
+	maintenance := Closure{Action: ClosureRefuse, Reason: "maintenance"}
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected *Closure
+	}{
+		{name: "Monday within open hours", time: time.Date(2026, 10, 19, 12, 0, 0, 0, london)},
+		{name: "Monday at opening", time: time.Date(2026, 10, 19, 6, 0, 0, 0, london)},
+		{name: "Monday before opening", time: time.Date(2026, 10, 19, 5, 59, 59, 0, london), expected: &closed},
+		{name: "Monday at cut-off", time: time.Date(2026, 10, 19, 22, 0, 0, 0, london), expected: &closed},
+		{name: "Cut-off in UTC", time: time.Date(2026, 10, 19, 21, 0, 0, 0, time.UTC), expected: &closed},
+		{name: "Saturday morning", time: time.Date(2026, 10, 24, 9, 0, 0, 0, london)},
+		{name: "Saturday afternoon", time: time.Date(2026, 10, 24, 13, 0, 0, 0, london), expected: &closed},
+		{name: "Sunday", time: time.Date(2026, 10, 25, 12, 0, 0, 0, london), expected: &closed},
+		{name: "Maintenance starts on Tuesday", time: time.Date(2026, 10, 20, 21, 30, 0, 0, london), expected: &maintenance},
+		{name: "Maintenance ends on Wednesday", time: time.Date(2026, 10, 21, 6, 15, 0, 0, london), expected: &maintenance},
+		{name: "After maintenance", time: time.Date(2026, 10, 21, 6, 30, 0, 0, london)},
+		{name: "No maintenance on Wednesday night", time: time.Date(2026, 10, 21, 21, 45, 0, 0, london)},
+		{name: "Outage", time: time.Date(2026, 10, 21, 10, 30, 0, 0, london), expected: &closed},
+		{name: "After outage", time: time.Date(2026, 10, 21, 11, 0, 0, 0, london)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			closure, ok := c.ClosureAt(test.time)
+			if test.expected == nil {
+				assert.False(t, ok)
+				return
+			}
+
+			assert.True(t, ok)
+			assert.Equal(t, *test.expected, closure)
+		})
+	}
+}
+
+func Test_Calendar_DefaultReason(t *testing.T) {
+	c, err := ParseCalendar(strings.NewReader(`{"openHours": [{"from": "06:00", "to": "22:00"}]}`))
+	require.NoError(t, err)
+
+	closure, ok := c.ClosureAt(time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, Closure{Action: ClosureReject, Reason: "scheme closed"}, closure)
+}
+
+func Test_Closure_result(t *testing.T) {
+	err := Closure{Action: ClosureReject, Reason: "maintenance"}.result()
+	assert.ErrorIs(t, err, ErrSchemeClosed)
+	assert.EqualError(t, err, "maintenance")
+
+	assert.ErrorIs(t, Closure{Action: ClosureRefuse, Reason: "maintenance"}.result(), ErrDropConnection)
+}
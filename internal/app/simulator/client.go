@@ -0,0 +1,46 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+)
+
+// ClientAuth defines whether clients must present a certificate when TLS is enabled.
+type ClientAuth string
+
+const (
+	// ClientAuthNone doesn't request a client certificate.
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthOptional verifies the client certificate if the client presents one.
+	ClientAuthOptional ClientAuth = "optional"
+	// ClientAuthRequired rejects clients without a valid certificate.
+	ClientAuthRequired ClientAuth = "required"
+)
+
+// UnmarshalText parses the client authentication and returns an error if it is not supported.
+func (a *ClientAuth) UnmarshalText(b []byte) error {
+	auth := ClientAuth(b)
+
+	switch auth {
+	case ClientAuthNone, ClientAuthOptional, ClientAuthRequired:
+		*a = auth
+		return nil
+	default:
+		return fmt.Errorf("unknown client auth %q", auth)
+	}
+}
+
+// clientSubjectKey is the context key of the client certificate subject.
+type clientSubjectKey struct{}
+
+// ContextWithClientSubject returns a copy of the context carrying the subject of the client certificate.
+func ContextWithClientSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, clientSubjectKey{}, subject)
+}
+
+// ClientSubject returns the subject of the certificate presented by the client that submitted the payment.
+// It returns false if the client didn't present a certificate.
+func ClientSubject(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(clientSubjectKey{}).(string)
+	return subject, ok
+}
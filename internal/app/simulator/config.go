@@ -1,15 +1,127 @@
 package simulator
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
 // Config defines configuration of application. Values are parsed from environment variables.
 type Config struct {
-	ServerPort                    int           `split_words:"true" default:"11111"`
-	ServerHost                    string        `split_words:"true" default:"localhost"`
-	ServerGracefulShutdownTimeout time.Duration `split_words:"true" default:"3s"`
-	InitDebug                     bool          `split_words:"true"`
-	DummyMinAmountToWait          int           `split_words:"true" default:"100"`
-	DummyMaxAmountToWait          int           `split_words:"true" default:"10000"`
+	ServerPort                    int                   `split_words:"true" default:"11111"`
+	ServerHost                    string                `split_words:"true" default:"localhost"`
+	ServerGracefulShutdownTimeout time.Duration         `split_words:"true" default:"3s"`
+	ServerIdleTimeout             time.Duration         `split_words:"true" default:"5m"`
+	ServerReadTimeout             time.Duration         `split_words:"true" default:"10s"`
+	ServerWriteTimeout            time.Duration         `split_words:"true" default:"10s"`
+	ServerKeepAlivePeriod         time.Duration         `split_words:"true" default:"15s"`
+	ServerMaxConnections          int                   `split_words:"true"`
+	ServerConnectionLimitPolicy   ConnectionLimitPolicy `split_words:"true" default:"reject"`
+	ServerWorkers                 int                   `split_words:"true"`
+	ServerWorkerQueueTimeout      time.Duration         `split_words:"true"`
+	ServerAsyncSettlement         bool                  `split_words:"true"`
+	ServerReasonCodes             bool                  `split_words:"true"`
+	ServerRateLimit               float64               `split_words:"true"`
+	ServerRateBurst               int                   `split_words:"true"`
+	ServerConnectionRateLimit     float64               `split_words:"true"`
+	ServerConnectionRateBurst     int                   `split_words:"true"`
+	ServerIPRateLimit             float64               `split_words:"true"`
+	ServerIPRateBurst             int                   `split_words:"true"`
+	ServerThrottlePolicy          ThrottlePolicy        `split_words:"true" default:"reject"`
+	ServerTLSCertFile             string                `split_words:"true"`
+	ServerTLSKeyFile              string                `split_words:"true"`
+	ServerTLSClientCAFile         string                `split_words:"true"`
+	ServerTLSClientAuth           ClientAuth            `split_words:"true" default:"none"`
+	InitDebug                     bool                  `split_words:"true"`
+	AdminPort                     int                   `split_words:"true" default:"11112"`
+	DummyMinAmountToWait          int                   `split_words:"true" default:"100"`
+	DummyMaxAmountToWait          int                   `split_words:"true" default:"10000"`
+	Latency                       Latency               `default:"amount"`
+	LatencySeed                   uint64                `split_words:"true"`
+	IdempotencyWindow             time.Duration         `split_words:"true" default:"10m"`
+	StatusRetention               time.Duration         `split_words:"true" default:"1h"`
+	RecallOutcome                 RecallOutcome         `split_words:"true" default:"accept"`
+	RecallDelay                   time.Duration         `split_words:"true"`
+	ScenarioFile                  string                `split_words:"true"`
+	FaultProbability              float64               `split_words:"true"`
+	FaultKinds                    []Fault               `split_words:"true" default:"close,truncate,no-newline,stall"`
+	JournalFile                   string                `split_words:"true"`
+	JournalMaxSize                int64                 `split_words:"true" default:"10485760"`
+	JournalMaxFiles               int                   `split_words:"true" default:"5"`
+	RecordFile                    string                `split_words:"true"`
+	LedgerFile                    string                `split_words:"true"`
+	CalendarFile                  string                `split_words:"true"`
+
+	// Scenario, Accounts and Calendar are defined in the configuration file instead of their files.
+	// They aren't parsed from environment variables. The ledger is enabled if Accounts isn't nil.
+	Scenario *Scenario `ignored:"true"`
+	Accounts []Account `ignored:"true"`
+	Calendar *Calendar `ignored:"true"`
+}
+
+// Validate checks that the values are consistent and within their ranges, and reports every problem.
+func (c Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ServerPort > 0 && c.ServerPort <= 65535, "server port %d must be between 1 and 65535", c.ServerPort)
+	check(c.AdminPort >= 0 && c.AdminPort <= 65535, "admin port %d must be between 0 and 65535", c.AdminPort)
+	check(c.AdminPort == 0 || c.AdminPort != c.ServerPort, "admin port %d must differ from server port", c.AdminPort)
+	check(c.ServerHost != "", "server host must be set")
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: "server graceful shutdown timeout", value: c.ServerGracefulShutdownTimeout},
+		{name: "server idle timeout", value: c.ServerIdleTimeout},
+		{name: "server read timeout", value: c.ServerReadTimeout},
+		{name: "server write timeout", value: c.ServerWriteTimeout},
+		{name: "server worker queue timeout", value: c.ServerWorkerQueueTimeout},
+		{name: "idempotency window", value: c.IdempotencyWindow},
+		{name: "status retention", value: c.StatusRetention},
+		{name: "recall delay", value: c.RecallDelay},
+	} {
+		check(d.value >= 0, "%s %s must not be negative", d.name, d.value)
+	}
+
+	check(c.ServerMaxConnections >= 0, "server max connections %d must not be negative", c.ServerMaxConnections)
+	check(c.ServerWorkers >= 0, "server workers %d must not be negative", c.ServerWorkers)
+
+	for _, l := range []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{name: "server", rate: c.ServerRateLimit, burst: c.ServerRateBurst},
+		{name: "server connection", rate: c.ServerConnectionRateLimit, burst: c.ServerConnectionRateBurst},
+		{name: "server IP", rate: c.ServerIPRateLimit, burst: c.ServerIPRateBurst},
+	} {
+		check(l.rate >= 0, "%s rate limit %v must not be negative", l.name, l.rate)
+		check(l.burst >= 0, "%s rate burst %d must not be negative", l.name, l.burst)
+	}
+
+	check((c.ServerTLSCertFile == "") == (c.ServerTLSKeyFile == ""), "TLS requires both a server certificate and key")
+	check(c.ServerTLSCertFile != "" || (c.ServerTLSClientCAFile == "" && (c.ServerTLSClientAuth == "" || c.ServerTLSClientAuth == ClientAuthNone)),
+		"client authentication requires a server certificate and key")
+
+	check(c.DummyMinAmountToWait >= 0, "dummy min amount to wait %d must not be negative", c.DummyMinAmountToWait)
+	check(c.DummyMinAmountToWait <= c.DummyMaxAmountToWait,
+		"dummy min amount to wait %d must not be greater than dummy max amount to wait %d", c.DummyMinAmountToWait, c.DummyMaxAmountToWait)
+
+	check(c.FaultProbability >= 0 && c.FaultProbability <= 1, "fault probability %v must be between 0 and 1", c.FaultProbability)
+
+	check(c.JournalMaxSize >= 0, "journal max size %d must not be negative", c.JournalMaxSize)
+	check(c.JournalMaxFiles >= 0, "journal max files %d must not be negative", c.JournalMaxFiles)
+
+	check(c.Scenario == nil || c.ScenarioFile == "", "scenario and scenario file must not both be set")
+	check(c.Accounts == nil || c.LedgerFile == "", "ledger and ledger file must not both be set")
+	check(c.Calendar == nil || c.CalendarFile == "", "calendar and calendar file must not both be set")
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,132 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Config_Validate(t *testing.T) {
+	valid := Config{
+		ServerPort:           11111,
+		ServerHost:           "localhost",
+		ServerWorkers:        100,
+		AdminPort:            11112,
+		DummyMinAmountToWait: 100,
+		DummyMaxAmountToWait: 10000,
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(*Config)
+		expectedErr []string
+	}{
+		{name: "Valid", modify: func(*Config) {}},
+		{name: "Admin server disabled", modify: func(c *Config) { c.AdminPort = 0 }},
+		{name: "Unlimited workers and connections", modify: func(c *Config) { c.ServerWorkers, c.ServerMaxConnections = 0, 0 }},
+		{name: "TLS", modify: func(c *Config) {
+			c.ServerTLSCertFile, c.ServerTLSKeyFile, c.ServerTLSClientAuth = "cert.pem", "key.pem", ClientAuthRequired
+		}},
+		{
+			name: "Ports",
+			modify: func(c *Config) {
+				c.ServerPort, c.AdminPort = 0, 70000
+			},
+			expectedErr: []string{"server port 0 must be between 1 and 65535", "admin port 70000 must be between 0 and 65535"},
+		},
+		{
+			name:        "Same ports",
+			modify:      func(c *Config) { c.AdminPort = c.ServerPort },
+			expectedErr: []string{"admin port 11111 must differ from server port"},
+		},
+		{
+			name: "Negative durations",
+			modify: func(c *Config) {
+				c.ServerGracefulShutdownTimeout, c.StatusRetention = -time.Second, -time.Minute
+			},
+			expectedErr: []string{"server graceful shutdown timeout -1s must not be negative", "status retention -1m0s must not be negative"},
+		},
+		{
+			name: "Server",
+			modify: func(c *Config) {
+				c.ServerHost, c.ServerWorkers, c.ServerMaxConnections = "", -1, -1
+			},
+			expectedErr: []string{"server host must be set", "server workers -1 must not be negative", "server max connections -1 must not be negative"},
+		},
+		{
+			name: "Rate limits",
+			modify: func(c *Config) {
+				c.ServerIPRateLimit, c.ServerConnectionRateBurst = -1, -1
+			},
+			expectedErr: []string{"server IP rate limit -1 must not be negative", "server connection rate burst -1 must not be negative"},
+		},
+		{
+			name:        "TLS without key",
+			modify:      func(c *Config) { c.ServerTLSCertFile = "cert.pem" },
+			expectedErr: []string{"TLS requires both a server certificate and key"},
+		},
+		{
+			name:        "Client authentication without TLS",
+			modify:      func(c *Config) { c.ServerTLSClientAuth = ClientAuthOptional },
+			expectedErr: []string{"client authentication requires a server certificate and key"},
+		},
+		{
+			name: "Delay bounds",
+			modify: func(c *Config) {
+				c.DummyMinAmountToWait, c.DummyMaxAmountToWait = -200, -300
+			},
+			expectedErr: []string{
+				"dummy min amount to wait -200 must not be negative",
+				"dummy min amount to wait -200 must not be greater than dummy max amount to wait -300",
+			},
+		},
+		{
+			name: "Faults and journal",
+			modify: func(c *Config) {
+				c.FaultProbability, c.JournalMaxSize, c.JournalMaxFiles = 1.5, -1, -1
+			},
+			expectedErr: []string{
+				"fault probability 1.5 must be between 0 and 1",
+				"journal max size -1 must not be negative",
+				"journal max files -1 must not be negative",
+			},
+		},
+		{
+			name: "Inline scenario, ledger and calendar",
+			modify: func(c *Config) {
+				c.Scenario, c.Accounts, c.Calendar = &Scenario{}, []Account{}, &Calendar{}
+			},
+		},
+		{
+			name: "Inline and file scenario, ledger and calendar",
+			modify: func(c *Config) {
+				c.Scenario, c.Accounts, c.Calendar = &Scenario{}, []Account{}, &Calendar{}
+				c.ScenarioFile, c.LedgerFile, c.CalendarFile = "scenario.json", "ledger.json", "calendar.json"
+			},
+			expectedErr: []string{
+				"scenario and scenario file must not both be set",
+				"ledger and ledger file must not both be set",
+				"calendar and calendar file must not both be set",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := valid
+			test.modify(&cfg)
+
+			err := cfg.Validate()
+			if len(test.expectedErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, expected := range test.expectedErr {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package simulator
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ConfigurableService processes payments using DummyService or, when a scenario is active, using ScenarioService.
+// The active scenario and the delay bounds of DummyService can be changed while payments are processed.
+type ConfigurableService struct {
+	dummy    *DummyService
+	scenario atomic.Pointer[scenarioState]
+}
+
+// scenarioState holds the active scenario together with the service processing it.
+type scenarioState struct {
+	scenario Scenario
+	service  *ScenarioService
+}
+
+// NewConfigurableService creates a new ConfigurableService with the given configuration.
+// If scenario is not nil, it is activated.
+func NewConfigurableService(cfg Config, scenario *Scenario) *ConfigurableService {
+	c := &ConfigurableService{dummy: NewDummyService(cfg)}
+	c.SetScenario(scenario)
+
+	return c
+}
+
+// Process processes the payment using the active scenario, or DummyService if no scenario is active.
+func (c *ConfigurableService) Process(ctx context.Context, payment Payment) error {
+	if state := c.scenario.Load(); state != nil {
+		return state.service.Process(ctx, payment)
+	}
+
+	return c.dummy.Process(ctx, payment)
+}
+
+// SetScenario activates the scenario for payments processed afterwards. If scenario is nil, DummyService is used.
+// Payments already being processed complete with the scenario active when they were received.
+func (c *ConfigurableService) SetScenario(scenario *Scenario) {
+	if scenario == nil {
+		c.scenario.Store(nil)
+		return
+	}
+
+	c.scenario.Store(&scenarioState{
+		scenario: *scenario,
+		service:  NewScenarioService(*scenario),
+	})
+}
+
+// Scenario returns the active scenario, or nil if no scenario is active.
+func (c *ConfigurableService) Scenario() *Scenario {
+	state := c.scenario.Load()
+	if state == nil {
+		return nil
+	}
+
+	scenario := state.scenario
+
+	return &scenario
+}
+
+// SetDelayBounds changes the delay bounds of DummyService.
+func (c *ConfigurableService) SetDelayBounds(minAmount, maxAmount int) {
+	c.dummy.SetDelayBounds(minAmount, maxAmount)
+}
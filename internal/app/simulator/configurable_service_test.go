@@ -0,0 +1,37 @@
+package simulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConfigurableService(t *testing.T) {
+	service := NewConfigurableService(Config{DummyMinAmountToWait: 0, DummyMaxAmountToWait: 10}, nil)
+	assert.Nil(t, service.Scenario())
+
+	assert.NoError(t, service.Process(context.Background(), Payment{Amount: 1}))
+
+	scenario := &Scenario{Default: Outcome{Action: ActionReject, Reason: "Closed"}}
+	service.SetScenario(scenario)
+	assert.Equal(t, scenario, service.Scenario())
+
+	err := service.Process(context.Background(), Payment{Amount: 1})
+	assert.Equal(t, RejectionError{Reason: "Closed"}, err)
+
+	service.SetScenario(nil)
+	assert.Nil(t, service.Scenario())
+
+	assert.NoError(t, service.Process(context.Background(), Payment{Amount: 1}))
+}
+
+func Test_ConfigurableService_SetDelayBounds(t *testing.T) {
+	service := NewConfigurableService(Config{DummyMinAmountToWait: 0, DummyMaxAmountToWait: 10000}, nil)
+	service.SetDelayBounds(0, 10)
+
+	now := time.Now()
+	assert.NoError(t, service.Process(context.Background(), Payment{Amount: 5000}))
+	assert.Less(t, time.Since(now), time.Second)
+}
@@ -0,0 +1,26 @@
+package simulator
+
+import "fmt"
+
+// ConnectionLimitPolicy defines what happens to connections accepted while the maximum number of connections are open.
+type ConnectionLimitPolicy string
+
+const (
+	// ConnectionLimitReject closes excess connections immediately.
+	ConnectionLimitReject ConnectionLimitPolicy = "reject"
+	// ConnectionLimitQueue keeps excess connections open without reading from them until another connection is closed.
+	ConnectionLimitQueue ConnectionLimitPolicy = "queue"
+)
+
+// UnmarshalText parses the connection limit policy and returns an error if it is not supported.
+func (p *ConnectionLimitPolicy) UnmarshalText(b []byte) error {
+	policy := ConnectionLimitPolicy(b)
+
+	switch policy {
+	case ConnectionLimitReject, ConnectionLimitQueue:
+		*p = policy
+		return nil
+	default:
+		return fmt.Errorf("unknown connection limit policy %q", policy)
+	}
+}
@@ -1,28 +1,55 @@
 package simulator
 
 import (
+	"context"
+	"sync/atomic"
 	"time"
 )
 
-// DummyService is a service that processes amounts with configurable delays.
+// DummyService is a service that processes payments with configurable delays.
+// Delays are derived from the amount, or sampled from the latency model if another one is configured.
 type DummyService struct {
-	cfg Config
+	cfg  atomic.Pointer[Config]
+	rand *lockedRand
 }
 
 // NewDummyService creates a new instance of DummyService with the given configuration.
+// Delays are sampled with LatencySeed, so the same seed gives the same sequence of delays.
 func NewDummyService(cfg Config) *DummyService {
-	return &DummyService{cfg: cfg}
+	d := &DummyService{rand: newLockedRand(cfg.LatencySeed)}
+	d.cfg.Store(&cfg)
+
+	return d
+}
+
+// SetDelayBounds changes DummyMinAmountToWait and DummyMaxAmountToWait for payments processed afterwards.
+// The bounds only apply to the amount based latency model.
+func (d *DummyService) SetDelayBounds(minAmount, maxAmount int) {
+	cfg := *d.cfg.Load()
+	cfg.DummyMinAmountToWait = minAmount
+	cfg.DummyMaxAmountToWait = maxAmount
+
+	d.cfg.Store(&cfg)
 }
 
-// Process processes the amount with configurable delays based on the service's configuration.
+// Process processes the payment with configurable delays based on the payment amount and the service's configuration.
 // If the amount is greater than DummyMinAmountToWait, it will sleep for the specified duration.
 // If the amount exceeds DummyMaxAmountToWait, it will cap the delay at DummyMaxAmountToWait.
-func (d *DummyService) Process(amount int) error {
-	if amount > d.cfg.DummyMinAmountToWait {
-		if amount > d.cfg.DummyMaxAmountToWait {
-			amount = d.cfg.DummyMaxAmountToWait
+// If a latency model other than the amount is configured, the delay is sampled from it instead.
+// The delay is interrupted when the context is cancelled, in which case the context error is returned.
+func (d *DummyService) Process(ctx context.Context, payment Payment) error {
+	cfg := d.cfg.Load()
+	if !cfg.Latency.amountBased() {
+		return wait(ctx, d.rand.delay(cfg.Latency))
+	}
+
+	amount := payment.Amount
+	if amount > cfg.DummyMinAmountToWait {
+		if amount > cfg.DummyMaxAmountToWait {
+			amount = cfg.DummyMaxAmountToWait
 		}
-		time.Sleep(time.Duration(amount) * time.Millisecond)
+
+		return wait(ctx, time.Duration(amount)*time.Millisecond)
 	}
 
 	return nil
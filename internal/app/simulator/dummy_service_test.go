@@ -1,6 +1,7 @@
 package simulator
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -46,9 +47,26 @@ func Test_DummyService(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			now := time.Now()
-			err := service.Process(test.amount)
+			err := service.Process(context.Background(), Payment{Amount: test.amount})
 			duration := time.Since(now)
 			test.assertFunc(t, duration, err)
 		})
 	}
 }
+
+func Test_DummyService_Cancelled(t *testing.T) {
+	service := NewDummyService(Config{
+		DummyMinAmountToWait: 100,
+		DummyMaxAmountToWait: 10000,
+	})
+
+	ctx, cncl := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cncl)
+
+	now := time.Now()
+	err := service.Process(ctx, Payment{Amount: 10000})
+	duration := time.Since(now)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, duration, time.Second)
+}
@@ -7,3 +7,167 @@ var ErrInvalidRequest = errors.New("invalid request")
 
 // ErrInvalidAmount represents an error indicating that the amount provided is invalid.
 var ErrInvalidAmount = errors.New("invalid amount")
+
+// ErrInvalidPaymentID represents an error indicating that the payment ID provided is invalid.
+var ErrInvalidPaymentID = errors.New("invalid payment id")
+
+// ErrInvalidCurrency represents an error indicating that the currency provided is invalid.
+var ErrInvalidCurrency = errors.New("invalid currency")
+
+// ErrDuplicate represents an error indicating that the payment ID was already used for a different payment.
+var ErrDuplicate = errors.New("duplicate")
+
+// ErrInsufficientFunds represents an error indicating that the debtor account doesn't have enough funds for the payment.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrUnknownAccount represents an error indicating that an account of the payment doesn't exist.
+var ErrUnknownAccount = errors.New("unknown account")
+
+// ErrPaymentNotFound represents an error indicating that no outcome is known for the payment ID.
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// ErrPaymentNotAccepted represents an error indicating that the payment can't be recalled, because it wasn't accepted.
+var ErrPaymentNotAccepted = errors.New("payment not accepted")
+
+// ErrAlreadyRecalled represents an error indicating that the payment was already recalled.
+var ErrAlreadyRecalled = errors.New("already recalled")
+
+// ErrAlreadySettled represents an error indicating that the payment can't be recalled, because it is already settled.
+var ErrAlreadySettled = errors.New("already settled")
+
+// ErrServerBusy represents an error indicating that the request couldn't be processed in time, because all workers are busy.
+var ErrServerBusy = errors.New("server busy")
+
+// ErrCancelled represents an error indicating that the request was cancelled, because the grace period of a graceful shutdown is finished.
+var ErrCancelled = errors.New("cancelled")
+
+// ErrNotificationsDisabled represents an error indicating that registrations for notifications are rejected,
+// because asynchronous settlement is disabled.
+var ErrNotificationsDisabled = errors.New("notifications are disabled")
+
+// ErrSchemeClosed represents an error indicating that the scheme is closed, because of its calendar.
+var ErrSchemeClosed = errors.New("scheme closed")
+
+// ErrThrottled represents an error indicating that the request is rejected, because it exceeds a rate limit.
+var ErrThrottled = errors.New("throttled")
+
+// ErrDropConnection represents an error indicating that the connection must be closed without a response.
+var ErrDropConnection = errors.New("drop connection")
+
+// RejectionError represents an error indicating that the payment is rejected with the given reason.
+type RejectionError struct {
+	Reason string
+}
+
+// Error returns the rejection reason.
+func (e RejectionError) Error() string {
+	return e.Reason
+}
+
+// ClosedError represents an error indicating that the payment is rejected with the given reason, because the scheme is closed.
+// It matches ErrSchemeClosed.
+type ClosedError struct {
+	Reason string
+}
+
+// Error returns the reason of the closure.
+func (e ClosedError) Error() string {
+	return e.Reason
+}
+
+// Is returns true if target is ErrSchemeClosed.
+func (e ClosedError) Is(target error) bool {
+	return target == ErrSchemeClosed
+}
+
+// Code is a stable mnemonic code identifying the result of a request, independent of its human readable reason.
+type Code string
+
+const (
+	CodeOK                    Code = "OK"
+	CodePending               Code = "PENDING"
+	CodeRejected              Code = "REJECTED"
+	CodeInternal              Code = "INTERNAL"
+	CodeInvalidRequest        Code = "INVALID_REQUEST"
+	CodeInvalidAmount         Code = "INVALID_AMOUNT"
+	CodeInvalidPaymentID      Code = "INVALID_PAYMENT_ID"
+	CodeInvalidCurrency       Code = "INVALID_CURRENCY"
+	CodeDuplicate             Code = "DUPLICATE"
+	CodeInsufficientFunds     Code = "INSUFFICIENT_FUNDS"
+	CodeUnknownAccount        Code = "UNKNOWN_ACCOUNT"
+	CodePaymentNotFound       Code = "PAYMENT_NOT_FOUND"
+	CodePaymentNotAccepted    Code = "PAYMENT_NOT_ACCEPTED"
+	CodeAlreadyRecalled       Code = "ALREADY_RECALLED"
+	CodeAlreadySettled        Code = "ALREADY_SETTLED"
+	CodeServerBusy            Code = "SERVER_BUSY"
+	CodeCancelled             Code = "CANCELLED"
+	CodeNotificationsDisabled Code = "NOTIFICATIONS_DISABLED"
+	CodeSchemeClosed          Code = "SCHEME_CLOSED"
+	CodeThrottled             Code = "THROTTLED"
+)
+
+// catalogue maps the errors reported to clients to their codes.
+// ErrDropConnection isn't part of it, because dropped payments aren't answered.
+var catalogue = []struct {
+	err  error
+	code Code
+}{
+	{err: ErrInvalidRequest, code: CodeInvalidRequest},
+	{err: ErrInvalidAmount, code: CodeInvalidAmount},
+	{err: ErrInvalidPaymentID, code: CodeInvalidPaymentID},
+	{err: ErrInvalidCurrency, code: CodeInvalidCurrency},
+	{err: ErrDuplicate, code: CodeDuplicate},
+	{err: ErrInsufficientFunds, code: CodeInsufficientFunds},
+	{err: ErrUnknownAccount, code: CodeUnknownAccount},
+	{err: ErrPaymentNotFound, code: CodePaymentNotFound},
+	{err: ErrPaymentNotAccepted, code: CodePaymentNotAccepted},
+	{err: ErrAlreadyRecalled, code: CodeAlreadyRecalled},
+	{err: ErrAlreadySettled, code: CodeAlreadySettled},
+	{err: ErrServerBusy, code: CodeServerBusy},
+	{err: ErrCancelled, code: CodeCancelled},
+	{err: ErrNotificationsDisabled, code: CodeNotificationsDisabled},
+	{err: ErrSchemeClosed, code: CodeSchemeClosed},
+	{err: ErrThrottled, code: CodeThrottled},
+}
+
+// CodeOf returns the code of the error. Nil errors have the OK code, rejections without a catalogued error
+// have the rejected code and unexpected errors have the internal code.
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+
+	for _, c := range catalogue {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+
+	var rejectionErr RejectionError
+	if errors.As(err, &rejectionErr) {
+		return CodeRejected
+	}
+
+	return CodeInternal
+}
+
+// ErrorOf returns the catalogued error with the code, or nil if no error has the code.
+func ErrorOf(code Code) error {
+	for _, c := range catalogue {
+		if c.code == code {
+			return c.err
+		}
+	}
+
+	return nil
+}
+
+// Codes returns every code, starting with the codes that don't belong to a catalogued error.
+func Codes() []Code {
+	codes := []Code{CodeOK, CodePending, CodeRejected, CodeInternal}
+	for _, c := range catalogue {
+		codes = append(codes, c.code)
+	}
+
+	return codes
+}
@@ -0,0 +1,84 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Catalogue(t *testing.T) {
+	tests := []struct {
+		err  error
+		code Code
+	}{
+		{err: ErrInvalidRequest, code: "INVALID_REQUEST"},
+		{err: ErrInvalidAmount, code: "INVALID_AMOUNT"},
+		{err: ErrInvalidPaymentID, code: "INVALID_PAYMENT_ID"},
+		{err: ErrInvalidCurrency, code: "INVALID_CURRENCY"},
+		{err: ErrDuplicate, code: "DUPLICATE"},
+		{err: ErrInsufficientFunds, code: "INSUFFICIENT_FUNDS"},
+		{err: ErrUnknownAccount, code: "UNKNOWN_ACCOUNT"},
+		{err: ErrPaymentNotFound, code: "PAYMENT_NOT_FOUND"},
+		{err: ErrPaymentNotAccepted, code: "PAYMENT_NOT_ACCEPTED"},
+		{err: ErrAlreadyRecalled, code: "ALREADY_RECALLED"},
+		{err: ErrAlreadySettled, code: "ALREADY_SETTLED"},
+		{err: ErrServerBusy, code: "SERVER_BUSY"},
+		{err: ErrCancelled, code: "CANCELLED"},
+		{err: ErrNotificationsDisabled, code: "NOTIFICATIONS_DISABLED"},
+		{err: ErrSchemeClosed, code: "SCHEME_CLOSED"},
+		{err: ErrThrottled, code: "THROTTLED"},
+	}
+
+	require.Len(t, catalogue, len(tests), "every catalogued error must be listed")
+	assert.Len(t, Codes(), len(tests)+4)
+
+	codes := map[Code]bool{}
+	for _, test := range tests {
+		t.Run(string(test.code), func(t *testing.T) {
+			assert.False(t, codes[test.code], "code must be unique")
+			codes[test.code] = true
+
+			assert.Equal(t, test.code, CodeOf(test.err))
+			assert.Equal(t, test.code, CodeOf(fmt.Errorf("wrapped: %w", test.err)))
+			assert.Equal(t, test.code, CodeOf(FaultError{Fault: FaultTruncate, Err: test.err}))
+
+			assert.Equal(t, test.err, ErrorOf(test.code))
+		})
+	}
+}
+
+func Test_CodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code Code
+	}{
+		{name: "Nil", err: nil, code: CodeOK},
+		{name: "Rejection", err: RejectionError{Reason: "Closed"}, code: CodeRejected},
+		{name: "Closed", err: ClosedError{Reason: "Maintenance"}, code: CodeSchemeClosed},
+		{name: "Unexpected", err: errors.New("boom"), code: CodeInternal},
+		{name: "Context cancelled", err: context.Canceled, code: CodeInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.code, CodeOf(test.err))
+		})
+	}
+}
+
+func Test_Codes_Unique(t *testing.T) {
+	codes := Codes()
+	assert.ElementsMatch(t, codes, slices.Compact(slices.Sorted(slices.Values(codes))))
+}
+
+func Test_ErrorOf_Unknown(t *testing.T) {
+	for _, code := range []Code{CodeOK, CodePending, CodeRejected, CodeInternal, "UNKNOWN"} {
+		assert.NoError(t, ErrorOf(code), code)
+	}
+}
@@ -0,0 +1,56 @@
+package simulator
+
+import "fmt"
+
+// Fault defines how the scheme misbehaves when responding to a request.
+type Fault string
+
+const (
+	// FaultClose closes the connection without sending the response.
+	FaultClose Fault = "close"
+	// FaultTruncate sends a part of the response and closes the connection.
+	FaultTruncate Fault = "truncate"
+	// FaultNoNewline sends the response without the trailing newline.
+	FaultNoNewline Fault = "no-newline"
+	// FaultStall never sends the response.
+	FaultStall Fault = "stall"
+)
+
+// Faults returns all supported faults.
+func Faults() []Fault {
+	return []Fault{FaultClose, FaultTruncate, FaultNoNewline, FaultStall}
+}
+
+// UnmarshalText parses the fault and returns an error if it is not supported.
+func (f *Fault) UnmarshalText(b []byte) error {
+	fault := Fault(b)
+
+	switch fault {
+	case FaultClose, FaultTruncate, FaultNoNewline, FaultStall:
+		*f = fault
+		return nil
+	default:
+		return fmt.Errorf("unknown fault %q", fault)
+	}
+}
+
+// FaultError represents an error indicating that the response to the payment must be corrupted by the fault.
+// Err is the result of processing the payment and is used to build the corrupted response.
+type FaultError struct {
+	Fault Fault
+	Err   error
+}
+
+// Error returns a description of the fault and the result of the payment.
+func (e FaultError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("fault %s", e.Fault)
+	}
+
+	return fmt.Sprintf("fault %s: %s", e.Fault, e.Err)
+}
+
+// Unwrap returns the result of processing the payment.
+func (e FaultError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,112 @@
+package simulator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// IdempotencyService detects duplicate payments by payment ID before processing them using an underlying service.
+// Retries of a payment within the configured window receive the original result,
+// while reusing a payment ID for a different payment is rejected as a duplicate.
+// Payments without ID are always processed.
+type IdempotencyService struct {
+	service Service
+	window  time.Duration
+	clock   clock.Clock
+
+	mu       sync.Mutex
+	payments map[string]*processedPayment
+	expiries []*processedPayment
+}
+
+// processedPayment holds the result of a payment, which is available once done is closed.
+type processedPayment struct {
+	payment   Payment
+	done      chan struct{}
+	err       error
+	expiresAt time.Time
+}
+
+// NewIdempotencyService creates a new IdempotencyService with the given configuration and service.
+func NewIdempotencyService(cfg Config, service Service, clock clock.Clock) *IdempotencyService {
+	return &IdempotencyService{
+		service:  service,
+		window:   cfg.IdempotencyWindow,
+		clock:    clock,
+		payments: make(map[string]*processedPayment),
+	}
+}
+
+// Process processes the payment using the underlying service unless a payment with the same ID was seen within the window.
+// If the same payment is still being processed, it waits for the original result.
+// It returns ErrDuplicate if the payment ID was used for a different payment.
+func (i *IdempotencyService) Process(ctx context.Context, payment Payment) error {
+	if payment.ID == "" {
+		return i.service.Process(ctx, payment)
+	}
+
+	i.mu.Lock()
+
+	i.purgeExpiredLocked()
+
+	if original, ok := i.payments[payment.ID]; ok {
+		i.mu.Unlock()
+
+		if original.payment != payment {
+			return ErrDuplicate
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-original.done:
+			return original.err
+		}
+	}
+
+	processed := &processedPayment{
+		payment: payment,
+		done:    make(chan struct{}),
+	}
+	i.payments[payment.ID] = processed
+
+	i.mu.Unlock()
+
+	err := i.service.Process(ctx, payment)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	processed.err = err
+	close(processed.done)
+
+	if ctx.Err() != nil {
+		// Cancelled payments are not recorded, so they can be retried.
+		delete(i.payments, payment.ID)
+		return err
+	}
+
+	processed.expiresAt = i.clock.Now().Add(i.window)
+	i.expiries = append(i.expiries, processed)
+
+	return err
+}
+
+// purgeExpiredLocked removes payments whose window has passed. It must be called with mu held.
+// Payments are appended to expiries in completion order, so the oldest payments are always at the front.
+func (i *IdempotencyService) purgeExpiredLocked() {
+	now := i.clock.Now()
+
+	for len(i.expiries) > 0 && !now.Before(i.expiries[0].expiresAt) {
+		expired := i.expiries[0]
+		i.expiries[0] = nil
+		i.expiries = i.expiries[1:]
+
+		if i.payments[expired.payment.ID] == expired {
+			delete(i.payments, expired.payment.ID)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IdempotencyService(t *testing.T) {
+	payment := Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}
+
+	tests := []struct {
+		name               string
+		prepareMockService func(*MockService)
+		run                func(*testing.T, *IdempotencyService, *clock.Mock)
+	}{
+		{
+			name: "Payment without ID is always processed",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, Payment{Amount: 1}).
+					Return(nil).
+					Times(2)
+			},
+			run: func(t *testing.T, service *IdempotencyService, _ *clock.Mock) {
+				assert.NoError(t, service.Process(context.Background(), Payment{Amount: 1}))
+				assert.NoError(t, service.Process(context.Background(), Payment{Amount: 1}))
+			},
+		},
+		{
+			name: "Retry receives original result",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					Return(nil).
+					Once()
+			},
+			run: func(t *testing.T, service *IdempotencyService, _ *clock.Mock) {
+				assert.NoError(t, service.Process(context.Background(), payment))
+				assert.NoError(t, service.Process(context.Background(), payment))
+			},
+		},
+		{
+			name: "Retry receives original error",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					Return(errors.New("service failure")).
+					Once()
+			},
+			run: func(t *testing.T, service *IdempotencyService, _ *clock.Mock) {
+				assert.EqualError(t, service.Process(context.Background(), payment), "service failure")
+				assert.EqualError(t, service.Process(context.Background(), payment), "service failure")
+			},
+		},
+		{
+			name: "Reusing payment ID for different payment is rejected",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					Return(nil).
+					Once()
+			},
+			run: func(t *testing.T, service *IdempotencyService, _ *clock.Mock) {
+				assert.NoError(t, service.Process(context.Background(), payment))
+
+				conflicting := payment
+				conflicting.Amount = 2
+				assert.ErrorIs(t, service.Process(context.Background(), conflicting), ErrDuplicate)
+			},
+		},
+		{
+			name: "Payment is processed again after window",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					Return(nil).
+					Times(2)
+			},
+			run: func(t *testing.T, service *IdempotencyService, mockClock *clock.Mock) {
+				assert.NoError(t, service.Process(context.Background(), payment))
+
+				mockClock.Add(time.Minute)
+
+				assert.NoError(t, service.Process(context.Background(), payment))
+				assert.Len(t, service.payments, 1)
+			},
+		},
+		{
+			name: "Cancelled payment is processed again",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					RunAndReturn(func(ctx context.Context, _ Payment) error {
+						return ctx.Err()
+					}).
+					Once()
+
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					Return(nil).
+					Once()
+			},
+			run: func(t *testing.T, service *IdempotencyService, _ *clock.Mock) {
+				ctx, cncl := context.WithCancel(context.Background())
+				cncl()
+
+				assert.ErrorIs(t, service.Process(ctx, payment), context.Canceled)
+				assert.NoError(t, service.Process(context.Background(), payment))
+			},
+		},
+		{
+			name: "Retry waits for payment in progress",
+			prepareMockService: func(mockService *MockService) {
+				release := make(chan struct{})
+
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					RunAndReturn(func(context.Context, Payment) error {
+						<-release
+						return errors.New("service failure")
+					}).
+					Once()
+
+				time.AfterFunc(50*time.Millisecond, func() {
+					close(release)
+				})
+			},
+			run: func(t *testing.T, service *IdempotencyService, _ *clock.Mock) {
+				errChan := make(chan error, 1)
+				go func() {
+					errChan <- service.Process(context.Background(), payment)
+				}()
+
+				require.Eventually(t, func() bool {
+					service.mu.Lock()
+					defer service.mu.Unlock()
+
+					return len(service.payments) == 1
+				}, time.Second, time.Millisecond)
+
+				assert.EqualError(t, service.Process(context.Background(), payment), "service failure")
+				assert.EqualError(t, <-errChan, "service failure")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockService := NewMockService(t)
+			test.prepareMockService(mockService)
+
+			mockClock := clock.NewMock()
+			service := NewIdempotencyService(Config{IdempotencyWindow: time.Minute}, mockService, mockClock)
+
+			test.run(t, service, mockClock)
+		})
+	}
+}
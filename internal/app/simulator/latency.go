@@ -0,0 +1,519 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyAmount is the default latency model, which waits as many milliseconds as the payment amount,
+// bounded by DummyMinAmountToWait and DummyMaxAmountToWait.
+const LatencyAmount = "amount"
+
+// Latency is the model of the time taken to process a payment. It is parsed from `<kind>` or `<kind>:<key>=<value>,...`.
+//
+//   - `amount` - The amount as milliseconds.
+//   - `fixed:delay=<d>` - Always the same delay.
+//   - `uniform:min=<d>,max=<d>` - Delays between min and max with equal probability.
+//   - `normal:mean=<d>,stddev=<d>` - Normally distributed delays.
+//   - `lognormal:median=<d>,sigma=<f>` - Log-normally distributed delays, skewed towards long delays.
+//   - `exponential:mean=<d>` - Exponentially distributed delays.
+//   - `pareto:scale=<d>,shape=<f>` - Heavy-tailed delays of at least scale. Smaller shapes have heavier tails.
+//
+// Instead of their parameters, normal, log-normal and Pareto distributions can be defined by two percentiles,
+// and exponential distributions by one, for example `lognormal:p50=20ms,p99=250ms`.
+// Every distribution accepts `cap=<d>` to cap the delays. It is a separate key from max, which is the upper bound of uniform distributions.
+type Latency struct {
+	spec         string
+	distribution latencyDistribution
+	limit        time.Duration
+}
+
+// latencyDistribution samples delays in nanoseconds.
+type latencyDistribution interface {
+	sample(r *rand.Rand) float64
+}
+
+// ParseLatency parses the latency model.
+func ParseLatency(s string) (Latency, error) {
+	kind, params, _ := strings.Cut(s, ":")
+	if kind == LatencyAmount {
+		if params != "" {
+			return Latency{}, fmt.Errorf("%s latency doesn't have parameters", kind)
+		}
+
+		return Latency{spec: s}, nil
+	}
+
+	p, err := parseLatencyParams(kind, params)
+	if err != nil {
+		return Latency{}, err
+	}
+
+	var distribution latencyDistribution
+	switch kind {
+	case "fixed":
+		distribution, err = p.fixed()
+	case "uniform":
+		distribution, err = p.uniform()
+	case "normal":
+		distribution, err = p.normal()
+	case "lognormal":
+		distribution, err = p.lognormal()
+	case "exponential":
+		distribution, err = p.exponential()
+	case "pareto":
+		distribution, err = p.pareto()
+	default:
+		return Latency{}, fmt.Errorf("unknown latency %q", kind)
+	}
+	if err != nil {
+		return Latency{}, err
+	}
+
+	limit, _, err := p.duration("cap")
+	if err != nil {
+		return Latency{}, err
+	}
+
+	if err := p.checkUsed(); err != nil {
+		return Latency{}, err
+	}
+
+	return Latency{spec: s, distribution: distribution, limit: limit}, nil
+}
+
+// UnmarshalText parses the latency model and returns an error if it is invalid.
+func (l *Latency) UnmarshalText(b []byte) error {
+	latency, err := ParseLatency(string(b))
+	if err != nil {
+		return err
+	}
+
+	*l = latency
+
+	return nil
+}
+
+// MarshalText returns the latency model in the format it was parsed from.
+func (l Latency) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// String returns the latency model in the format it was parsed from.
+func (l Latency) String() string {
+	if l.spec == "" {
+		return LatencyAmount
+	}
+
+	return l.spec
+}
+
+// amountBased returns true if the delay is derived from the payment amount.
+func (l Latency) amountBased() bool {
+	return l.distribution == nil
+}
+
+// delay samples a delay from the distribution.
+func (l Latency) delay(r *rand.Rand) time.Duration {
+	ns := l.distribution.sample(r)
+	if l.limit > 0 {
+		ns = math.Min(ns, float64(l.limit))
+	}
+
+	switch {
+	case ns <= 0 || math.IsNaN(ns):
+		return 0
+	case ns >= math.MaxInt64:
+		return math.MaxInt64
+	default:
+		return time.Duration(ns)
+	}
+}
+
+// lockedRand is a random number generator safe for concurrent use.
+// Concurrent requests take samples in the order they acquire the lock, so a seed only repeats the delays of each request
+// if requests are processed one at a time.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// newLockedRand creates a random number generator with the seed, or a random seed if it is 0.
+func newLockedRand(seed uint64) *lockedRand {
+	if seed == 0 {
+		seed = rand.Uint64() //nolint:gosec // Latencies don't need to be unpredictable.
+	}
+
+	return &lockedRand{r: rand.New(rand.NewPCG(seed, seed))} //nolint:gosec // Latencies don't need to be unpredictable.
+}
+
+// delay samples a delay of the latency model.
+func (r *lockedRand) delay(l Latency) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return l.delay(r.r)
+}
+
+// percentile is the value below which the fraction q of the delays fall.
+type percentile struct {
+	q     float64
+	value float64
+}
+
+// latencyParams holds the parameters of a latency model and tracks which of them were used.
+type latencyParams struct {
+	kind   string
+	values map[string]string
+	used   map[string]bool
+}
+
+func parseLatencyParams(kind, params string) (latencyParams, error) {
+	p := latencyParams{kind: kind, values: map[string]string{}, used: map[string]bool{}}
+	if params == "" {
+		return p, nil
+	}
+
+	for _, param := range strings.Split(params, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || key == "" {
+			return latencyParams{}, fmt.Errorf("invalid parameter %q of %s latency", param, kind)
+		}
+		if _, ok := p.values[key]; ok {
+			return latencyParams{}, fmt.Errorf("duplicate parameter %q of %s latency", key, kind)
+		}
+
+		p.values[key] = value
+	}
+
+	return p, nil
+}
+
+// duration returns the parameter as a duration in nanoseconds. It returns false if the parameter isn't set.
+func (p latencyParams) duration(key string) (time.Duration, bool, error) {
+	value, ok := p.values[key]
+	if !ok {
+		return 0, false, nil
+	}
+	p.used[key] = true
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, false, fmt.Errorf("parameter %s of %s latency must be a non-negative duration, got %q", key, p.kind, value)
+	}
+
+	return d, true, nil
+}
+
+// float returns the parameter as a positive number. It returns false if the parameter isn't set.
+func (p latencyParams) float(key string) (float64, bool, error) {
+	value, ok := p.values[key]
+	if !ok {
+		return 0, false, nil
+	}
+	p.used[key] = true
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 || math.IsInf(f, 0) {
+		return 0, false, fmt.Errorf("parameter %s of %s latency must be a positive number, got %q", key, p.kind, value)
+	}
+
+	return f, true, nil
+}
+
+// requireDurations returns the parameters as durations in nanoseconds and returns an error if any of them isn't set.
+func (p latencyParams) requireDurations(keys ...string) ([]float64, error) {
+	values := make([]float64, 0, len(keys))
+	for _, key := range keys {
+		d, ok, err := p.duration(key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s latency requires parameter %s", p.kind, key)
+		}
+
+		values = append(values, float64(d))
+	}
+
+	return values, nil
+}
+
+// percentiles returns the percentile parameters, such as `p99=250ms`, ordered by percentile.
+// It returns an error unless n percentiles are set with values increasing with the percentile.
+func (p latencyParams) percentiles(n int) ([]percentile, error) {
+	var percentiles []percentile
+	for key := range p.values {
+		if !strings.HasPrefix(key, "p") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(key[1:], 64)
+		if err != nil || q <= 0 || q >= 100 {
+			return nil, fmt.Errorf("invalid percentile %q of %s latency", key, p.kind)
+		}
+
+		d, _, err := p.duration(key)
+		if err != nil {
+			return nil, err
+		}
+
+		percentiles = append(percentiles, percentile{q: q / 100, value: float64(d)})
+	}
+
+	if len(percentiles) != n {
+		return nil, fmt.Errorf("%s latency requires %d percentiles, got %d", p.kind, n, len(percentiles))
+	}
+
+	slices.SortFunc(percentiles, func(a, b percentile) int {
+		switch {
+		case a.q < b.q:
+			return -1
+		case a.q > b.q:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for i := 1; i < len(percentiles); i++ {
+		if percentiles[i].q == percentiles[i-1].q {
+			return nil, fmt.Errorf("duplicate percentile p%v of %s latency", percentiles[i].q*100, p.kind)
+		}
+
+		if percentiles[i].value <= percentiles[i-1].value {
+			return nil, fmt.Errorf("percentiles of %s latency must increase with the percentile", p.kind)
+		}
+	}
+
+	return percentiles, nil
+}
+
+// byPercentiles returns true if the distribution is defined by percentiles instead of its parameters.
+func (p latencyParams) byPercentiles() bool {
+	for key := range p.values {
+		if strings.HasPrefix(key, "p") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkUsed returns an error if a parameter isn't supported by the latency model.
+func (p latencyParams) checkUsed() error {
+	keys := make([]string, 0, len(p.values))
+	for key := range p.values {
+		if !p.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	slices.Sort(keys)
+
+	return fmt.Errorf("unknown parameters %s of %s latency", strings.Join(keys, ","), p.kind)
+}
+
+func (p latencyParams) fixed() (latencyDistribution, error) {
+	values, err := p.requireDurations("delay")
+	if err != nil {
+		return nil, err
+	}
+
+	return fixedLatency{delay: values[0]}, nil
+}
+
+func (p latencyParams) uniform() (latencyDistribution, error) {
+	values, err := p.requireDurations("min", "max")
+	if err != nil {
+		return nil, err
+	}
+	if values[0] > values[1] {
+		return nil, fmt.Errorf("min of %s latency is greater than max", p.kind)
+	}
+
+	return uniformLatency{min: values[0], max: values[1]}, nil
+}
+
+func (p latencyParams) normal() (latencyDistribution, error) {
+	if p.byPercentiles() {
+		percentiles, err := p.percentiles(2)
+		if err != nil {
+			return nil, err
+		}
+
+		z1, z2 := zScore(percentiles[0].q), zScore(percentiles[1].q)
+		stddev := (percentiles[1].value - percentiles[0].value) / (z2 - z1)
+
+		return normalLatency{mean: percentiles[0].value - stddev*z1, stddev: stddev}, nil
+	}
+
+	values, err := p.requireDurations("mean", "stddev")
+	if err != nil {
+		return nil, err
+	}
+
+	return normalLatency{mean: values[0], stddev: values[1]}, nil
+}
+
+func (p latencyParams) lognormal() (latencyDistribution, error) {
+	if p.byPercentiles() {
+		percentiles, err := p.percentiles(2)
+		if err != nil {
+			return nil, err
+		}
+		if percentiles[0].value == 0 {
+			return nil, fmt.Errorf("percentiles of %s latency must be positive", p.kind)
+		}
+
+		z1, z2 := zScore(percentiles[0].q), zScore(percentiles[1].q)
+		l1, l2 := math.Log(percentiles[0].value), math.Log(percentiles[1].value)
+		sigma := (l2 - l1) / (z2 - z1)
+
+		return lognormalLatency{mu: l1 - sigma*z1, sigma: sigma}, nil
+	}
+
+	values, err := p.requireDurations("median")
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == 0 {
+		return nil, fmt.Errorf("median of %s latency must be positive", p.kind)
+	}
+
+	sigma, ok, err := p.float("sigma")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s latency requires parameter sigma", p.kind)
+	}
+
+	return lognormalLatency{mu: math.Log(values[0]), sigma: sigma}, nil
+}
+
+func (p latencyParams) exponential() (latencyDistribution, error) {
+	if p.byPercentiles() {
+		percentiles, err := p.percentiles(1)
+		if err != nil {
+			return nil, err
+		}
+
+		return exponentialLatency{mean: -percentiles[0].value / math.Log(1-percentiles[0].q)}, nil
+	}
+
+	values, err := p.requireDurations("mean")
+	if err != nil {
+		return nil, err
+	}
+
+	return exponentialLatency{mean: values[0]}, nil
+}
+
+func (p latencyParams) pareto() (latencyDistribution, error) {
+	if p.byPercentiles() {
+		percentiles, err := p.percentiles(2)
+		if err != nil {
+			return nil, err
+		}
+		if percentiles[0].value == 0 {
+			return nil, fmt.Errorf("percentiles of %s latency must be positive", p.kind)
+		}
+
+		// The q-th quantile is scale * (1-q)^(-1/shape), so the ratio of two quantiles gives the shape.
+		p1, p2 := percentiles[0], percentiles[1]
+		inverseShape := math.Log(p2.value/p1.value) / (math.Log(1-p1.q) - math.Log(1-p2.q))
+
+		return paretoLatency{scale: p1.value * math.Pow(1-p1.q, inverseShape), shape: 1 / inverseShape}, nil
+	}
+
+	values, err := p.requireDurations("scale")
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == 0 {
+		return nil, fmt.Errorf("scale of %s latency must be positive", p.kind)
+	}
+
+	shape, ok, err := p.float("shape")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s latency requires parameter shape", p.kind)
+	}
+
+	return paretoLatency{scale: values[0], shape: shape}, nil
+}
+
+// zScore returns the number of standard deviations below which the fraction q of a normal distribution falls.
+func zScore(q float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*q-1)
+}
+
+// fixedLatency always samples the same delay.
+type fixedLatency struct {
+	delay float64
+}
+
+func (d fixedLatency) sample(*rand.Rand) float64 {
+	return d.delay
+}
+
+// uniformLatency samples delays between min and max with equal probability.
+type uniformLatency struct {
+	min float64
+	max float64
+}
+
+func (d uniformLatency) sample(r *rand.Rand) float64 {
+	return d.min + r.Float64()*(d.max-d.min)
+}
+
+// normalLatency samples normally distributed delays. Negative delays are sampled as 0.
+type normalLatency struct {
+	mean   float64
+	stddev float64
+}
+
+func (d normalLatency) sample(r *rand.Rand) float64 {
+	return r.NormFloat64()*d.stddev + d.mean
+}
+
+// lognormalLatency samples delays whose logarithm is normally distributed with mean mu and standard deviation sigma.
+type lognormalLatency struct {
+	mu    float64
+	sigma float64
+}
+
+func (d lognormalLatency) sample(r *rand.Rand) float64 {
+	return math.Exp(r.NormFloat64()*d.sigma + d.mu)
+}
+
+// exponentialLatency samples exponentially distributed delays.
+type exponentialLatency struct {
+	mean float64
+}
+
+func (d exponentialLatency) sample(r *rand.Rand) float64 {
+	return r.ExpFloat64() * d.mean
+}
+
+// paretoLatency samples Pareto distributed delays of at least scale.
+type paretoLatency struct {
+	scale float64
+	shape float64
+}
+
+func (d paretoLatency) sample(r *rand.Rand) float64 {
+	return d.scale / math.Pow(1-r.Float64(), 1/d.shape)
+}
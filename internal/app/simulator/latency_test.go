@@ -0,0 +1,212 @@
+package simulator
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseLatency(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectedErr string
+	}{
+		{name: "Amount", input: "amount"},
+		{name: "Fixed", input: "fixed:delay=50ms"},
+		{name: "Uniform", input: "uniform:min=10ms,max=50ms"},
+		{name: "Normal", input: "normal:mean=50ms,stddev=10ms"},
+		{name: "Normal by percentiles", input: "normal:p50=50ms,p99=80ms"},
+		{name: "Log-normal", input: "lognormal:median=20ms,sigma=0.5"},
+		{name: "Log-normal by percentiles", input: "lognormal:p50=20ms,p99.9=1s"},
+		{name: "Exponential", input: "exponential:mean=30ms"},
+		{name: "Exponential by percentile", input: "exponential:p90=100ms"},
+		{name: "Pareto", input: "pareto:scale=10ms,shape=1.5"},
+		{name: "Pareto by percentiles", input: "pareto:p50=20ms,p99=2s,cap=5s"},
+		{name: "Spaces", input: "uniform:min=10ms, max=50ms"},
+		{name: "Capped uniform", input: "uniform:min=10ms,max=50ms,cap=20ms"},
+		{name: "Max of other distributions", input: "lognormal:p50=20ms,p99=250ms,max=2s", expectedErr: "unknown parameters max of lognormal latency"},
+		{name: "Unknown kind", input: "gamma:mean=10ms", expectedErr: `unknown latency "gamma"`},
+		{name: "Amount with parameters", input: "amount:cap=1s", expectedErr: "amount latency doesn't have parameters"},
+		{name: "Missing parameter", input: "uniform:min=10ms", expectedErr: "uniform latency requires parameter max"},
+		{name: "Missing all parameters", input: "fixed", expectedErr: "fixed latency requires parameter delay"},
+		{name: "Unknown parameter", input: "fixed:delay=1ms,mean=2ms", expectedErr: "unknown parameters mean of fixed latency"},
+		{name: "Invalid parameter", input: "fixed:50ms", expectedErr: `invalid parameter "50ms" of fixed latency`},
+		{name: "Duplicate parameter", input: "fixed:delay=1ms,delay=2ms", expectedErr: `duplicate parameter "delay" of fixed latency`},
+		{
+			name:        "Negative duration",
+			input:       "fixed:delay=-1ms",
+			expectedErr: `parameter delay of fixed latency must be a non-negative duration, got "-1ms"`,
+		},
+		{
+			name:        "Invalid number",
+			input:       "pareto:scale=10ms,shape=0",
+			expectedErr: `parameter shape of pareto latency must be a positive number, got "0"`,
+		},
+		{name: "Min greater than max", input: "uniform:min=50ms,max=10ms", expectedErr: "min of uniform latency is greater than max"},
+		{name: "Invalid percentile", input: "normal:p50=10ms,p100=20ms", expectedErr: `invalid percentile "p100" of normal latency`},
+		{name: "Missing percentile", input: "lognormal:p50=10ms", expectedErr: "lognormal latency requires 2 percentiles, got 1"},
+		{
+			name:        "Decreasing percentiles",
+			input:       "pareto:p50=100ms,p99=10ms",
+			expectedErr: "percentiles of pareto latency must increase with the percentile",
+		},
+		{name: "Duplicate percentile", input: "normal:p50=10ms,p50.0=20ms", expectedErr: "duplicate percentile p50 of normal latency"},
+		{name: "Zero percentile", input: "lognormal:p50=0s,p99=10ms", expectedErr: "percentiles of lognormal latency must be positive"},
+		{name: "Percentiles with parameters", input: "normal:p50=10ms,p99=20ms,mean=10ms", expectedErr: "unknown parameters mean of normal latency"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			latency, err := ParseLatency(test.input)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.input, latency.String())
+			assert.Equal(t, test.input == LatencyAmount, latency.amountBased())
+		})
+	}
+}
+
+func Test_Latency_Percentiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		quantiles map[float64]time.Duration
+	}{
+		{
+			name:      "Fixed",
+			input:     "fixed:delay=50ms",
+			quantiles: map[float64]time.Duration{0.01: 50 * time.Millisecond, 0.99: 50 * time.Millisecond},
+		},
+		{
+			name:      "Uniform",
+			input:     "uniform:min=10ms,max=50ms",
+			quantiles: map[float64]time.Duration{0.5: 30 * time.Millisecond, 0.9: 46 * time.Millisecond},
+		},
+		{
+			name:      "Normal",
+			input:     "normal:mean=50ms,stddev=10ms",
+			quantiles: map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.8413: 60 * time.Millisecond},
+		},
+		{
+			name:      "Normal by percentiles",
+			input:     "normal:p50=50ms,p99=80ms",
+			quantiles: map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.99: 80 * time.Millisecond},
+		},
+		{
+			name:      "Log-normal",
+			input:     "lognormal:median=20ms,sigma=0.5",
+			quantiles: map[float64]time.Duration{0.5: 20 * time.Millisecond},
+		},
+		{
+			name:      "Log-normal by percentiles",
+			input:     "lognormal:p50=20ms,p99=250ms",
+			quantiles: map[float64]time.Duration{0.5: 20 * time.Millisecond, 0.99: 250 * time.Millisecond},
+		},
+		{
+			name:      "Exponential",
+			input:     "exponential:mean=100ms",
+			quantiles: map[float64]time.Duration{0.5: 69315 * time.Microsecond},
+		},
+		{
+			name:      "Exponential by percentile",
+			input:     "exponential:p90=100ms",
+			quantiles: map[float64]time.Duration{0.9: 100 * time.Millisecond},
+		},
+		{
+			name:      "Pareto",
+			input:     "pareto:scale=10ms,shape=1",
+			quantiles: map[float64]time.Duration{0.5: 20 * time.Millisecond, 0.9: 100 * time.Millisecond},
+		},
+		{
+			name:      "Pareto by percentiles",
+			input:     "pareto:p50=20ms,p99=2s",
+			quantiles: map[float64]time.Duration{0.5: 20 * time.Millisecond, 0.99: 2 * time.Second},
+		},
+		{
+			name:      "Capped",
+			input:     "pareto:p50=20ms,p99=2s,cap=500ms",
+			quantiles: map[float64]time.Duration{0.5: 20 * time.Millisecond, 0.99: 500 * time.Millisecond},
+		},
+		{
+			name:      "Capped uniform",
+			input:     "uniform:min=0s,max=100ms,cap=50ms",
+			quantiles: map[float64]time.Duration{0.25: 25 * time.Millisecond, 0.75: 50 * time.Millisecond},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			latency, err := ParseLatency(test.input)
+			require.NoError(t, err)
+
+			r := rand.New(rand.NewPCG(1, 1)) //nolint:gosec // Test data.
+
+			const samples = 200000
+			delays := make([]time.Duration, samples)
+			for i := range delays {
+				delays[i] = latency.delay(r)
+			}
+			slices.Sort(delays)
+
+			for q, expected := range test.quantiles {
+				actual := delays[int(q*samples)]
+				assert.InEpsilon(t, float64(expected), float64(actual), 0.05, "percentile %v", q*100)
+			}
+		})
+	}
+}
+
+func Test_Latency_Seed(t *testing.T) {
+	latency, err := ParseLatency("lognormal:p50=20ms,p99=250ms")
+	require.NoError(t, err)
+
+	sample := func(seed uint64) []time.Duration {
+		r := newLockedRand(seed)
+
+		delays := make([]time.Duration, 10)
+		for i := range delays {
+			delays[i] = r.delay(latency)
+		}
+
+		return delays
+	}
+
+	assert.Equal(t, sample(42), sample(42))
+	assert.NotEqual(t, sample(42), sample(43))
+}
+
+func Test_Latency_NonNegative(t *testing.T) {
+	latency, err := ParseLatency("normal:mean=1ms,stddev=10ms")
+	require.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 1)) //nolint:gosec // Test data.
+	for range 1000 {
+		assert.GreaterOrEqual(t, latency.delay(r), time.Duration(0))
+	}
+}
+
+func Test_DummyService_Latency(t *testing.T) {
+	latency, err := ParseLatency("fixed:delay=20ms")
+	require.NoError(t, err)
+
+	service := NewDummyService(Config{
+		DummyMinAmountToWait: 100,
+		DummyMaxAmountToWait: 10000,
+		Latency:              latency,
+	})
+
+	now := time.Now()
+	err = service.Process(context.Background(), Payment{Amount: 10000})
+	duration := time.Since(now)
+
+	assert.NoError(t, err)
+	assert.InDelta(t, 20*time.Millisecond, duration, float64(5*time.Millisecond))
+}
@@ -0,0 +1,56 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Account is an account of the ledger. Payments can debit the account until its balance reaches the negative limit.
+type Account struct {
+	ID      string `json:"id"`
+	Balance int    `json:"balance"`
+	Limit   int    `json:"limit"`
+}
+
+// available returns the amount that can be debited from the account.
+func (a Account) available() int {
+	return a.Balance + a.Limit
+}
+
+// ledgerFile is the JSON representation of the accounts seeding the ledger.
+type ledgerFile struct {
+	Accounts []Account `json:"accounts"`
+}
+
+// ParseAccounts parses the accounts seeding the ledger from JSON and validates them.
+func ParseAccounts(r io.Reader) ([]Account, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var f ledgerFile
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("can't decode ledger: %w", err)
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for i, a := range f.Accounts {
+		switch {
+		case a.ID == "":
+			errs = append(errs, fmt.Errorf("account %d: id must be set", i))
+		case seen[a.ID]:
+			errs = append(errs, fmt.Errorf("account %d %q: duplicate id", i, a.ID))
+		case a.Limit < 0:
+			errs = append(errs, fmt.Errorf("account %d %q: limit %d must not be negative", i, a.ID, a.Limit))
+		}
+		seen[a.ID] = true
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return f.Accounts, nil
+}
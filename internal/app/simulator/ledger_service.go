@@ -0,0 +1,129 @@
+package simulator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// LedgerService checks and moves funds between accounts before processing payments using an underlying service.
+// The amount is debited from the debtor account when the payment is received, and credited to the creditor account
+// once the payment is accepted. If the payment is not accepted, the amount is returned to the debtor account.
+// Payments without accounts are processed without a funds check.
+type LedgerService struct {
+	service Service
+
+	mu       sync.Mutex
+	accounts map[string]*Account
+}
+
+// NewLedgerService creates a new LedgerService with the given accounts and service.
+func NewLedgerService(accounts []Account, service Service) *LedgerService {
+	l := &LedgerService{
+		service:  service,
+		accounts: make(map[string]*Account, len(accounts)),
+	}
+
+	for _, a := range accounts {
+		l.accounts[a.ID] = &a
+	}
+
+	return l
+}
+
+// Process debits the debtor account and processes the payment using the underlying service.
+// It returns ErrUnknownAccount if an account of the payment doesn't exist,
+// and ErrInsufficientFunds if the debtor account doesn't have enough funds for the amount.
+func (l *LedgerService) Process(ctx context.Context, payment Payment) error {
+	if payment.DebtorAccount == "" && payment.CreditorAccount == "" {
+		return l.service.Process(ctx, payment)
+	}
+
+	if err := l.debit(payment); err != nil {
+		return err
+	}
+
+	err := l.service.Process(ctx, payment)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if isAccepted(err) {
+		l.transferLocked(payment.CreditorAccount, payment.Amount)
+	} else {
+		l.transferLocked(payment.DebtorAccount, payment.Amount)
+	}
+
+	return err
+}
+
+// debit debits the amount of the payment from its debtor account, after checking that both accounts exist.
+func (l *LedgerService) debit(payment Payment) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, id := range []string{payment.DebtorAccount, payment.CreditorAccount} {
+		if _, ok := l.accounts[id]; id != "" && !ok {
+			return ErrUnknownAccount
+		}
+	}
+
+	if debtor, ok := l.accounts[payment.DebtorAccount]; ok {
+		if debtor.available() < payment.Amount {
+			return ErrInsufficientFunds
+		}
+
+		debtor.Balance -= payment.Amount
+	}
+
+	return nil
+}
+
+// transferLocked credits the amount to the account, if it is set. It must be called with mu held.
+func (l *LedgerService) transferLocked(id string, amount int) {
+	if account, ok := l.accounts[id]; ok {
+		account.Balance += amount
+	}
+}
+
+// Accounts returns the accounts of the ledger ordered by ID.
+func (l *LedgerService) Accounts() []Account {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	accounts := make([]Account, 0, len(l.accounts))
+	for _, a := range l.accounts {
+		accounts = append(accounts, *a)
+	}
+
+	slices.SortFunc(accounts, func(a, b Account) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return accounts
+}
+
+// Account returns the account with the ID. It returns false if the account doesn't exist.
+func (l *LedgerService) Account(id string) (Account, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.accounts[id]
+	if !ok {
+		return Account{}, false
+	}
+
+	return *a, true
+}
+
+// isAccepted returns true if the payment was accepted, even if its response is corrupted by a fault.
+func isAccepted(err error) bool {
+	var faultErr FaultError
+	if errors.As(err, &faultErr) {
+		return faultErr.Err == nil
+	}
+
+	return err == nil
+}
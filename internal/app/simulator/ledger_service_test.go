@@ -0,0 +1,118 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LedgerService(t *testing.T) {
+	accounts := []Account{
+		{ID: "ACC-1", Balance: 100, Limit: 50},
+		{ID: "ACC-2", Balance: 10},
+	}
+
+	tests := []struct {
+		name               string
+		payment            Payment
+		prepareMockService func(*MockService, Payment)
+		expectedErr        error
+		expectedAccounts   []Account
+	}{
+		{
+			name:    "Payment without accounts is processed",
+			payment: Payment{ID: "abc-1", Amount: 1000, Currency: "GBP"},
+			prepareMockService: func(mockService *MockService, payment Payment) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Once()
+			},
+			expectedAccounts: accounts,
+		},
+		{
+			name:    "Accepted payment moves funds",
+			payment: Payment{ID: "abc-1", Amount: 30, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-2"},
+			prepareMockService: func(mockService *MockService, payment Payment) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Once()
+			},
+			expectedAccounts: []Account{{ID: "ACC-1", Balance: 70, Limit: 50}, {ID: "ACC-2", Balance: 40}},
+		},
+		{
+			name:    "Payment can use the limit",
+			payment: Payment{ID: "abc-1", Amount: 150, Currency: "GBP", DebtorAccount: "ACC-1"},
+			prepareMockService: func(mockService *MockService, payment Payment) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Once()
+			},
+			expectedAccounts: []Account{{ID: "ACC-1", Balance: -50, Limit: 50}, {ID: "ACC-2", Balance: 10}},
+		},
+		{
+			name:               "Insufficient funds",
+			payment:            Payment{ID: "abc-1", Amount: 11, Currency: "GBP", DebtorAccount: "ACC-2", CreditorAccount: "ACC-1"},
+			prepareMockService: func(*MockService, Payment) {},
+			expectedErr:        ErrInsufficientFunds,
+			expectedAccounts:   accounts,
+		},
+		{
+			name:               "Unknown debtor account",
+			payment:            Payment{ID: "abc-1", Amount: 1, Currency: "GBP", DebtorAccount: "ACC-3"},
+			prepareMockService: func(*MockService, Payment) {},
+			expectedErr:        ErrUnknownAccount,
+			expectedAccounts:   accounts,
+		},
+		{
+			name:               "Unknown creditor account",
+			payment:            Payment{ID: "abc-1", Amount: 1, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-3"},
+			prepareMockService: func(*MockService, Payment) {},
+			expectedErr:        ErrUnknownAccount,
+			expectedAccounts:   accounts,
+		},
+		{
+			name:    "Rejected payment is refunded",
+			payment: Payment{ID: "abc-1", Amount: 30, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-2"},
+			prepareMockService: func(mockService *MockService, payment Payment) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(errors.New("service failure")).Once()
+			},
+			expectedErr:      errors.New("service failure"),
+			expectedAccounts: accounts,
+		},
+		{
+			name:    "Accepted payment with corrupted response moves funds",
+			payment: Payment{ID: "abc-1", Amount: 30, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-2"},
+			prepareMockService: func(mockService *MockService, payment Payment) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(FaultError{Fault: FaultTruncate}).Once()
+			},
+			expectedErr:      FaultError{Fault: FaultTruncate},
+			expectedAccounts: []Account{{ID: "ACC-1", Balance: 70, Limit: 50}, {ID: "ACC-2", Balance: 40}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockService := NewMockService(t)
+			test.prepareMockService(mockService, test.payment)
+
+			service := NewLedgerService(accounts, mockService)
+
+			err := service.Process(context.Background(), test.payment)
+			if test.expectedErr != nil {
+				assert.EqualError(t, err, test.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.expectedAccounts, service.Accounts())
+		})
+	}
+}
+
+func Test_LedgerService_Account(t *testing.T) {
+	service := NewLedgerService([]Account{{ID: "ACC-1", Balance: 100}}, NewMockService(t))
+
+	account, ok := service.Account("ACC-1")
+	require.True(t, ok)
+	assert.Equal(t, Account{ID: "ACC-1", Balance: 100}, account)
+
+	_, ok = service.Account("ACC-2")
+	assert.False(t, ok)
+}
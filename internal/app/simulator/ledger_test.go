@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseAccounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		assertFunc func(*testing.T, []Account, error)
+	}{
+		{
+			name:  "Valid accounts",
+			input: `{"accounts": [{"id": "ACC-1", "balance": 100, "limit": 50}, {"id": "ACC-2"}]}`,
+			assertFunc: func(t *testing.T, accounts []Account, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []Account{{ID: "ACC-1", Balance: 100, Limit: 50}, {ID: "ACC-2"}}, accounts)
+			},
+		},
+		{
+			name:  "Unknown field",
+			input: `{"accounts": [{"id": "ACC-1", "overdraft": 50}]}`,
+			assertFunc: func(t *testing.T, _ []Account, err error) {
+				assert.ErrorContains(t, err, `unknown field "overdraft"`)
+			},
+		},
+		{
+			name:  "Every problem is reported",
+			input: `{"accounts": [{"balance": 1}, {"id": "ACC-1"}, {"id": "ACC-1"}, {"id": "ACC-2", "limit": -1}]}`,
+			assertFunc: func(t *testing.T, _ []Account, err error) {
+				assert.EqualError(t, err, "account 0: id must be set\n"+
+					`account 2 "ACC-1": duplicate id`+"\n"+
+					`account 3 "ACC-2": limit -1 must not be negative`)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accounts, err := ParseAccounts(strings.NewReader(test.input))
+			test.assertFunc(t, accounts, err)
+		})
+	}
+}
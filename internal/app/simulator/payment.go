@@ -0,0 +1,13 @@
+package simulator
+
+// Payment represents a payment submitted to the scheme.
+// ID, Currency and Reference are empty for payments submitted with the legacy request format.
+// The debtor and creditor accounts are optional, and are only used by the ledger.
+type Payment struct {
+	ID              string
+	Amount          int
+	Currency        string
+	Reference       string
+	DebtorAccount   string
+	CreditorAccount string
+}
@@ -0,0 +1,32 @@
+package simulator
+
+import "fmt"
+
+// Recall represents a request to recall a previously accepted payment.
+type Recall struct {
+	PaymentID string
+	Reason    string
+}
+
+// RecallOutcome defines how recalls of accepted payments are resolved.
+type RecallOutcome string
+
+const (
+	// RecallAccept accepts recalls of accepted payments.
+	RecallAccept RecallOutcome = "accept"
+	// RecallReject rejects recalls of accepted payments, because they are already settled.
+	RecallReject RecallOutcome = "reject"
+)
+
+// UnmarshalText parses the recall outcome and returns an error if it is not supported.
+func (o *RecallOutcome) UnmarshalText(b []byte) error {
+	outcome := RecallOutcome(b)
+
+	switch outcome {
+	case RecallAccept, RecallReject:
+		*o = outcome
+		return nil
+	default:
+		return fmt.Errorf("unknown recall outcome %q", outcome)
+	}
+}
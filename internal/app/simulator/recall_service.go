@@ -0,0 +1,112 @@
+package simulator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// PaymentStatuses looks up the outcome of processed payments.
+type PaymentStatuses interface {
+	Status(paymentID string) (PaymentStatus, bool)
+}
+
+// RecallService resolves recalls against the outcome of previously processed payments.
+// Recalls of accepted payments are answered with the configured outcome after the configured delay,
+// and a payment can only be recalled once. Recalled payments are remembered as long as payment statuses are retained.
+type RecallService struct {
+	statuses  PaymentStatuses
+	outcome   RecallOutcome
+	delay     time.Duration
+	retention time.Duration
+	clock     clock.Clock
+
+	mu       sync.Mutex
+	recalled map[string]*recalledPayment
+	expiries []*recalledPayment
+}
+
+// recalledPayment is a payment that was recalled. Its expiry is only set once the recall is accepted.
+type recalledPayment struct {
+	paymentID string
+	expiresAt time.Time
+}
+
+// NewRecallService creates a new RecallService with the given configuration and payment statuses.
+func NewRecallService(cfg Config, statuses PaymentStatuses, clock clock.Clock) *RecallService {
+	return &RecallService{
+		statuses:  statuses,
+		outcome:   cfg.RecallOutcome,
+		delay:     cfg.RecallDelay,
+		retention: cfg.StatusRetention,
+		clock:     clock,
+		recalled:  make(map[string]*recalledPayment),
+	}
+}
+
+// Recall resolves the recall of a payment. It returns ErrPaymentNotFound if the payment is unknown,
+// ErrPaymentNotAccepted if the payment is still being processed or was rejected, ErrAlreadyRecalled if the payment
+// was already recalled, and ErrAlreadySettled if recalls are rejected.
+// The delay is interrupted when the context is cancelled, in which case the context error is returned and
+// the payment can be recalled again.
+func (r *RecallService) Recall(ctx context.Context, recall Recall) error {
+	status, ok := r.statuses.Status(recall.PaymentID)
+	switch {
+	case !ok:
+		return ErrPaymentNotFound
+	case status.Pending || !isAccepted(status.Err):
+		return ErrPaymentNotAccepted
+	}
+
+	if r.outcome == RecallReject {
+		if err := wait(ctx, r.delay); err != nil {
+			return err
+		}
+
+		return ErrAlreadySettled
+	}
+
+	recalled := &recalledPayment{paymentID: recall.PaymentID}
+
+	r.mu.Lock()
+	r.purgeExpiredLocked()
+	if _, ok := r.recalled[recall.PaymentID]; ok {
+		r.mu.Unlock()
+		return ErrAlreadyRecalled
+	}
+	r.recalled[recall.PaymentID] = recalled
+	r.mu.Unlock()
+
+	err := wait(ctx, r.delay)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		delete(r.recalled, recall.PaymentID)
+		return err
+	}
+
+	recalled.expiresAt = r.clock.Now().Add(r.retention)
+	r.expiries = append(r.expiries, recalled)
+
+	return nil
+}
+
+// purgeExpiredLocked forgets recalled payments whose retention has passed. It must be called with mu held.
+// Recalls are appended to expiries in completion order, so the oldest recalls are always at the front.
+func (r *RecallService) purgeExpiredLocked() {
+	now := r.clock.Now()
+
+	for len(r.expiries) > 0 && !now.Before(r.expiries[0].expiresAt) {
+		expired := r.expiries[0]
+		r.expiries[0] = nil
+		r.expiries = r.expiries[1:]
+
+		if r.recalled[expired.paymentID] == expired {
+			delete(r.recalled, expired.paymentID)
+		}
+	}
+}
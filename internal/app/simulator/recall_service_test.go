@@ -0,0 +1,140 @@
+package simulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RecallService(t *testing.T) {
+	recall := Recall{PaymentID: "abc-1", Reason: "Fraud"}
+	accepted := PaymentStatus{Payment: Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}}
+
+	tests := []struct {
+		name                       string
+		cfg                        Config
+		prepareMockPaymentStatuses func(*MockPaymentStatuses)
+		run                        func(*testing.T, *RecallService, *clock.Mock)
+	}{
+		{
+			name: "Accepted payment is recalled once",
+			cfg:  Config{RecallOutcome: RecallAccept},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(accepted, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				assert.NoError(t, service.Recall(context.Background(), recall))
+				assert.ErrorIs(t, service.Recall(context.Background(), recall), ErrAlreadyRecalled)
+			},
+		},
+		{
+			name: "Accepted payment with corrupted response is recalled",
+			cfg:  Config{RecallOutcome: RecallAccept},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(PaymentStatus{Err: FaultError{Fault: FaultClose}}, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				assert.NoError(t, service.Recall(context.Background(), recall))
+			},
+		},
+		{
+			name: "Recall is rejected as already settled",
+			cfg:  Config{RecallOutcome: RecallReject},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(accepted, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				assert.ErrorIs(t, service.Recall(context.Background(), recall), ErrAlreadySettled)
+			},
+		},
+		{
+			name: "Unknown payment",
+			cfg:  Config{RecallOutcome: RecallAccept},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(PaymentStatus{}, false)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				assert.ErrorIs(t, service.Recall(context.Background(), recall), ErrPaymentNotFound)
+			},
+		},
+		{
+			name: "Rejected payment",
+			cfg:  Config{RecallOutcome: RecallAccept},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(PaymentStatus{Err: ErrInsufficientFunds}, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				assert.ErrorIs(t, service.Recall(context.Background(), recall), ErrPaymentNotAccepted)
+			},
+		},
+		{
+			name: "Pending payment",
+			cfg:  Config{RecallOutcome: RecallAccept},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(PaymentStatus{Pending: true}, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				assert.ErrorIs(t, service.Recall(context.Background(), recall), ErrPaymentNotAccepted)
+			},
+		},
+		{
+			name: "Recall is delayed",
+			cfg:  Config{RecallOutcome: RecallAccept, RecallDelay: 50 * time.Millisecond},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(accepted, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				start := time.Now()
+				assert.NoError(t, service.Recall(context.Background(), recall))
+				assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+			},
+		},
+		{
+			name: "Cancelled recall can be retried",
+			cfg:  Config{RecallOutcome: RecallAccept, RecallDelay: time.Hour},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(accepted, true)
+			},
+			run: func(t *testing.T, service *RecallService, _ *clock.Mock) {
+				ctx, cncl := context.WithCancel(context.Background())
+				cncl()
+
+				assert.ErrorIs(t, service.Recall(ctx, recall), context.Canceled)
+
+				service.delay = 0
+				assert.NoError(t, service.Recall(context.Background(), recall))
+			},
+		},
+		{
+			name: "Recalled payment is forgotten after retention",
+			cfg:  Config{RecallOutcome: RecallAccept},
+			prepareMockPaymentStatuses: func(mockStatuses *MockPaymentStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(accepted, true)
+			},
+			run: func(t *testing.T, service *RecallService, mockClock *clock.Mock) {
+				require.NoError(t, service.Recall(context.Background(), recall))
+
+				mockClock.Add(time.Minute)
+				assert.NoError(t, service.Recall(context.Background(), recall))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStatuses := NewMockPaymentStatuses(t)
+			test.prepareMockPaymentStatuses(mockStatuses)
+
+			cfg := test.cfg
+			cfg.StatusRetention = time.Minute
+
+			mockClock := clock.NewMock()
+			service := NewRecallService(cfg, mockStatuses, mockClock)
+
+			test.run(t, service, mockClock)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Action defines what the scheme does with a payment matched by a rule.
+type Action string
+
+const (
+	ActionAccept Action = "accept"
+	ActionReject Action = "reject"
+	ActionDrop   Action = "drop"
+)
+
+// Scenario is an ordered list of rules describing the scheme behaviour.
+// The outcome of the first matching rule is applied, or the default outcome if no rule matches.
+type Scenario struct {
+	Rules   []Rule  `json:"rules"`
+	Default Outcome `json:"default"`
+}
+
+// Rule applies its outcome to payments matching all of its conditions.
+type Rule struct {
+	Name    string  `json:"name"`
+	Match   Match   `json:"match"`
+	Outcome Outcome `json:"outcome"`
+}
+
+// Match defines the conditions of a rule. Conditions that are not set match every payment.
+type Match struct {
+	Amount    *Range   `json:"amount,omitempty"`
+	Currency  string   `json:"currency,omitempty"`
+	PaymentID *Pattern `json:"paymentId,omitempty"`
+	Request   *Range   `json:"request,omitempty"`
+}
+
+// Range matches integers between Min and Max, both inclusive.
+// If Every is set, only every Every-th value, counted from Min, matches.
+type Range struct {
+	Min   *int `json:"min,omitempty"`
+	Max   *int `json:"max,omitempty"`
+	Every int  `json:"every,omitempty"`
+}
+
+// Outcome defines the action applied to a matching payment after an optional delay.
+// Reason is the rejection reason and is only used by the reject action.
+// If Fault is set, the response to the payment is corrupted by the fault.
+type Outcome struct {
+	Action Action   `json:"action"`
+	Reason string   `json:"reason,omitempty"`
+	Delay  Duration `json:"delay,omitempty"`
+	Fault  Fault    `json:"fault,omitempty"`
+}
+
+// Pattern is a regular expression, represented as a string in JSON.
+type Pattern struct {
+	*regexp.Regexp
+}
+
+// UnmarshalJSON compiles the regular expression in the JSON string.
+func (p *Pattern) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	r, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+
+	p.Regexp = r
+
+	return nil
+}
+
+// MarshalJSON returns the regular expression as a JSON string.
+func (p Pattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// Duration is a time.Duration, represented as a string such as "250ms" in JSON.
+type Duration time.Duration
+
+// UnmarshalJSON parses the duration in the JSON string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+// MarshalJSON returns the duration as a JSON string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ParseScenario reads a JSON scenario and validates it.
+func ParseScenario(r io.Reader) (Scenario, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var s Scenario
+	if err := decoder.Decode(&s); err != nil {
+		return Scenario{}, fmt.Errorf("can't decode scenario: %w", err)
+	}
+
+	if err := s.validate(); err != nil {
+		return Scenario{}, err
+	}
+
+	return s, nil
+}
+
+// validate checks that every rule and outcome of the scenario is valid.
+func (s Scenario) validate() error {
+	var errs []error
+
+	for i, rule := range s.Rules {
+		for _, err := range rule.validate() {
+			errs = append(errs, fmt.Errorf("rule %d %q: %w", i, rule.Name, err))
+		}
+	}
+
+	if s.Default.Action != "" {
+		if err := s.Default.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("default outcome: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r Rule) validate() []error {
+	var errs []error
+
+	if r.Match.Amount != nil {
+		if err := r.Match.Amount.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("amount: %w", err))
+		}
+	}
+
+	if r.Match.Request != nil {
+		if err := r.Match.Request.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("request: %w", err))
+		}
+	}
+
+	if err := r.Outcome.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func (r Range) validate() error {
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("min %d is greater than max %d", *r.Min, *r.Max)
+	}
+
+	if r.Every < 0 {
+		return fmt.Errorf("every %d is negative", r.Every)
+	}
+
+	return nil
+}
+
+func (o Outcome) validate() error {
+	switch o.Action {
+	case ActionAccept, ActionDrop:
+	case ActionReject:
+		if o.Reason == "" {
+			return errors.New("reject action requires a reason")
+		}
+		// The reason is written as the last field of the response line, so it can't contain separators or line breaks.
+		if strings.ContainsAny(o.Reason, "|\r\n") {
+			return fmt.Errorf("reason %q must not contain '|' or line breaks", o.Reason)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", o.Action)
+	}
+
+	if o.Delay < 0 {
+		return fmt.Errorf("delay %s is negative", time.Duration(o.Delay))
+	}
+
+	return nil
+}
+
+// outcome returns the outcome of the first rule matching the payment, which is the n-th request processed.
+func (s Scenario) outcome(payment Payment, n int) Outcome {
+	for _, rule := range s.Rules {
+		if rule.Match.matches(payment, n) {
+			return rule.Outcome
+		}
+	}
+
+	if s.Default.Action == "" {
+		return Outcome{Action: ActionAccept}
+	}
+
+	return s.Default
+}
+
+// result returns the error returned for the action of the outcome.
+func (o Outcome) result() error {
+	switch o.Action {
+	case ActionReject:
+		return RejectionError{Reason: o.Reason}
+	case ActionDrop:
+		return ErrDropConnection
+	default:
+		return nil
+	}
+}
+
+func (m Match) matches(payment Payment, n int) bool {
+	if m.Amount != nil && !m.Amount.contains(payment.Amount) {
+		return false
+	}
+
+	if m.Currency != "" && m.Currency != payment.Currency {
+		return false
+	}
+
+	if m.PaymentID != nil && !m.PaymentID.MatchString(payment.ID) {
+		return false
+	}
+
+	if m.Request != nil && !m.Request.contains(n) {
+		return false
+	}
+
+	return true
+}
+
+func (r Range) contains(v int) bool {
+	if r.Min != nil && v < *r.Min {
+		return false
+	}
+
+	if r.Max != nil && v > *r.Max {
+		return false
+	}
+
+	if r.Every > 0 {
+		start := 0
+		if r.Min != nil {
+			start = *r.Min
+		}
+
+		return (v-start)%r.Every == 0
+	}
+
+	return true
+}
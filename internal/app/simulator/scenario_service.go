@@ -0,0 +1,39 @@
+package simulator
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ScenarioService is a service that processes payments according to the rules of a scenario.
+type ScenarioService struct {
+	scenario Scenario
+	requests atomic.Int64
+}
+
+// NewScenarioService creates a new instance of ScenarioService with the given scenario.
+func NewScenarioService(scenario Scenario) *ScenarioService {
+	return &ScenarioService{scenario: scenario}
+}
+
+// Process applies the outcome of the first rule matching the payment.
+// Rejected payments return a RejectionError and dropped payments return ErrDropConnection.
+// If the outcome has a fault, the result is wrapped in a FaultError.
+// The delay of the outcome is interrupted when the context is cancelled, in which case the context error is returned.
+func (s *ScenarioService) Process(ctx context.Context, payment Payment) error {
+	n := int(s.requests.Add(1))
+
+	outcome := s.scenario.outcome(payment, n)
+
+	if err := wait(ctx, time.Duration(outcome.Delay)); err != nil {
+		return err
+	}
+
+	err := outcome.result()
+	if outcome.Fault != "" {
+		return FaultError{Fault: outcome.Fault, Err: err}
+	}
+
+	return err
+}
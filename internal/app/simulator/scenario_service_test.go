@@ -0,0 +1,55 @@
+package simulator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ScenarioService(t *testing.T) {
+	scenario, err := ParseScenario(strings.NewReader(`{
+		"rules": [
+			{"match": {"request": {"max": 1}}, "outcome": {"action": "accept", "delay": "20ms"}},
+			{"match": {"currency": "EUR"}, "outcome": {"action": "reject", "reason": "Unsupported currency"}},
+			{"match": {"currency": "USD"}, "outcome": {"action": "drop"}},
+			{"match": {"currency": "CHF"}, "outcome": {"action": "accept", "fault": "truncate"}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	service := NewScenarioService(scenario)
+
+	now := time.Now()
+	err = service.Process(context.Background(), Payment{Amount: 1, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.InDelta(t, 20*time.Millisecond, time.Since(now), float64(10*time.Millisecond))
+
+	err = service.Process(context.Background(), Payment{Amount: 1, Currency: "EUR"})
+	assert.Equal(t, RejectionError{Reason: "Unsupported currency"}, err)
+
+	err = service.Process(context.Background(), Payment{Amount: 1, Currency: "USD"})
+	assert.ErrorIs(t, err, ErrDropConnection)
+
+	err = service.Process(context.Background(), Payment{Amount: 1, Currency: "CHF"})
+	assert.Equal(t, FaultError{Fault: FaultTruncate}, err)
+
+	err = service.Process(context.Background(), Payment{Amount: 1, Currency: "GBP"})
+	assert.NoError(t, err)
+}
+
+func Test_ScenarioService_Cancelled(t *testing.T) {
+	scenario, err := ParseScenario(strings.NewReader(`{"default": {"action": "accept", "delay": "10s"}}`))
+	require.NoError(t, err)
+
+	service := NewScenarioService(scenario)
+
+	ctx, cncl := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cncl)
+
+	err = service.Process(ctx, Payment{Amount: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,163 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseScenario(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		assertFunc func(*testing.T, Scenario, error)
+	}{
+		{
+			name: "Valid scenario",
+			input: `{
+				"rules": [
+					{
+						"name": "slow payments",
+						"match": {"amount": {"min": 100, "max": 200}, "currency": "GBP", "paymentId": "^slow-", "request": {"every": 2}},
+						"outcome": {"action": "accept", "delay": "150ms"}
+					}
+				],
+				"default": {"action": "reject", "reason": "Unsupported"}
+			}`,
+			assertFunc: func(t *testing.T, s Scenario, err error) {
+				require.NoError(t, err)
+				require.Len(t, s.Rules, 1)
+				assert.Equal(t, "slow payments", s.Rules[0].Name)
+				assert.Equal(t, 100, *s.Rules[0].Match.Amount.Min)
+				assert.Equal(t, 200, *s.Rules[0].Match.Amount.Max)
+				assert.Equal(t, "GBP", s.Rules[0].Match.Currency)
+				assert.Equal(t, "^slow-", s.Rules[0].Match.PaymentID.String())
+				assert.Equal(t, 2, s.Rules[0].Match.Request.Every)
+				assert.Equal(t, Outcome{Action: ActionAccept, Delay: Duration(150 * time.Millisecond)}, s.Rules[0].Outcome)
+				assert.Equal(t, Outcome{Action: ActionReject, Reason: "Unsupported"}, s.Default)
+			},
+		},
+		{
+			name:  "Unknown field",
+			input: `{"rulez": []}`,
+			assertFunc: func(t *testing.T, _ Scenario, err error) {
+				assert.ErrorContains(t, err, `unknown field "rulez"`)
+			},
+		},
+		{
+			name:  "Invalid pattern",
+			input: `{"rules": [{"match": {"paymentId": "("}, "outcome": {"action": "accept"}}]}`,
+			assertFunc: func(t *testing.T, _ Scenario, err error) {
+				assert.ErrorContains(t, err, "missing closing )")
+			},
+		},
+		{
+			name:  "Invalid delay",
+			input: `{"rules": [{"outcome": {"action": "accept", "delay": "soon"}}]}`,
+			assertFunc: func(t *testing.T, _ Scenario, err error) {
+				assert.ErrorContains(t, err, `invalid duration "soon"`)
+			},
+		},
+		{
+			name:  "Unknown fault",
+			input: `{"rules": [{"outcome": {"action": "accept", "fault": "explode"}}]}`,
+			assertFunc: func(t *testing.T, _ Scenario, err error) {
+				assert.ErrorContains(t, err, `unknown fault "explode"`)
+			},
+		},
+		{
+			name: "Every problem is reported",
+			input: `{
+				"rules": [
+					{"name": "a", "match": {"amount": {"min": 2, "max": 1}}, "outcome": {"action": "reject"}},
+					{"name": "b", "outcome": {"action": "explode"}}
+				],
+				"default": {"action": "accept", "delay": "-1s"}
+			}`,
+			assertFunc: func(t *testing.T, _ Scenario, err error) {
+				assert.ErrorContains(t, err, `rule 0 "a": amount: min 2 is greater than max 1`)
+				assert.ErrorContains(t, err, `rule 0 "a": reject action requires a reason`)
+				assert.ErrorContains(t, err, `rule 1 "b": unknown action "explode"`)
+				assert.ErrorContains(t, err, "default outcome: delay -1s is negative")
+			},
+		},
+		{
+			name: "Reasons breaking the response line",
+			input: `{
+				"rules": [
+					{"name": "pipe", "outcome": {"action": "reject", "reason": "Limit|exceeded"}},
+					{"name": "newline", "outcome": {"action": "reject", "reason": "Busy\nNOTIFY|p1|SETTLED|OK"}}
+				],
+				"default": {"action": "reject", "reason": "Closed\r"}
+			}`,
+			assertFunc: func(t *testing.T, _ Scenario, err error) {
+				assert.ErrorContains(t, err, `rule 0 "pipe": reason "Limit|exceeded" must not contain '|' or line breaks`)
+				assert.ErrorContains(t, err, `rule 1 "newline": reason "Busy\nNOTIFY|p1|SETTLED|OK" must not contain '|' or line breaks`)
+				assert.ErrorContains(t, err, `default outcome: reason "Closed\r" must not contain '|' or line breaks`)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseScenario(strings.NewReader(test.input))
+			test.assertFunc(t, s, err)
+		})
+	}
+}
+
+func Test_Scenario_outcome(t *testing.T) {
+	scenario, err := ParseScenario(strings.NewReader(`{
+		"rules": [
+			{"match": {"paymentId": "^drop-"}, "outcome": {"action": "drop"}},
+			{"match": {"amount": {"min": 1000}, "currency": "EUR"}, "outcome": {"action": "reject", "reason": "Limit exceeded"}},
+			{"match": {"request": {"min": 3, "every": 3}}, "outcome": {"action": "reject", "reason": "Busy"}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		payment  Payment
+		request  int
+		expected Outcome
+	}{
+		{
+			name:     "Payment ID pattern",
+			payment:  Payment{ID: "drop-1", Amount: 1},
+			request:  1,
+			expected: Outcome{Action: ActionDrop},
+		},
+		{
+			name:     "Amount and currency",
+			payment:  Payment{ID: "abc-1", Amount: 1000, Currency: "EUR"},
+			request:  1,
+			expected: Outcome{Action: ActionReject, Reason: "Limit exceeded"},
+		},
+		{
+			name:     "Amount without currency",
+			payment:  Payment{ID: "abc-1", Amount: 1000, Currency: "GBP"},
+			request:  1,
+			expected: Outcome{Action: ActionAccept},
+		},
+		{
+			name:     "Every third request",
+			payment:  Payment{Amount: 1},
+			request:  6,
+			expected: Outcome{Action: ActionReject, Reason: "Busy"},
+		},
+		{
+			name:     "Not every third request",
+			payment:  Payment{Amount: 1},
+			request:  5,
+			expected: Outcome{Action: ActionAccept},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, scenario.outcome(test.payment, test.request))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package simulator
 
-// Service defines a contract for processing amounts.
+import "context"
+
+// Service defines a contract for processing payments.
 type Service interface {
-	Process(amount int) error
+	Process(ctx context.Context, payment Payment) error
 }
@@ -0,0 +1,135 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// PaymentStatus is the recorded outcome of a payment. Err is the result of processing the payment,
+// and is only set once the payment is no longer pending.
+type PaymentStatus struct {
+	Payment Payment
+	Pending bool
+	Err     error
+}
+
+// StatusService records the outcome of payments processed using an underlying service,
+// so it can be enquired after the response is lost. Outcomes are kept for the configured retention.
+// Payments without ID, cancelled payments and dropped payments are not recorded.
+type StatusService struct {
+	service   Service
+	retention time.Duration
+	clock     clock.Clock
+
+	mu       sync.Mutex
+	statuses map[string]*recordedStatus
+	expiries []*recordedStatus
+}
+
+// recordedStatus holds the status of a payment until it expires.
+type recordedStatus struct {
+	status    PaymentStatus
+	expiresAt time.Time
+}
+
+// NewStatusService creates a new StatusService with the given configuration and service.
+func NewStatusService(cfg Config, service Service, clock clock.Clock) *StatusService {
+	return &StatusService{
+		service:   service,
+		retention: cfg.StatusRetention,
+		clock:     clock,
+		statuses:  make(map[string]*recordedStatus),
+	}
+}
+
+// Process processes the payment using the underlying service and records its outcome.
+func (s *StatusService) Process(ctx context.Context, payment Payment) error {
+	if payment.ID == "" {
+		return s.service.Process(ctx, payment)
+	}
+
+	recorded := &recordedStatus{status: PaymentStatus{Payment: payment, Pending: true}}
+
+	s.mu.Lock()
+	s.purgeExpiredLocked()
+	s.statuses[payment.ID] = recorded
+	s.mu.Unlock()
+
+	err := s.service.Process(ctx, payment)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ctx.Err() != nil || errors.Is(err, ErrDropConnection) {
+		if s.statuses[payment.ID] == recorded {
+			delete(s.statuses, payment.ID)
+		}
+		return err
+	}
+
+	recorded.status = PaymentStatus{Payment: payment, Err: err}
+	recorded.expiresAt = s.clock.Now().Add(s.retention)
+	s.expiries = append(s.expiries, recorded)
+
+	return err
+}
+
+// RecordPending records the payment as pending before it is handed to Process, so its status can be enquired
+// as soon as it is acknowledged. The returned release removes the pending status unless Process has recorded
+// the payment since.
+func (s *StatusService) RecordPending(payment Payment) (release func()) {
+	if payment.ID == "" {
+		return func() {}
+	}
+
+	recorded := &recordedStatus{status: PaymentStatus{Payment: payment, Pending: true}}
+
+	s.mu.Lock()
+	s.purgeExpiredLocked()
+	s.statuses[payment.ID] = recorded
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.statuses[payment.ID] == recorded {
+			delete(s.statuses, payment.ID)
+		}
+	}
+}
+
+// Status returns the status of the payment with the ID. It returns false if the payment is unknown or its status expired.
+func (s *StatusService) Status(paymentID string) (PaymentStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpiredLocked()
+
+	recorded, ok := s.statuses[paymentID]
+	if !ok {
+		return PaymentStatus{}, false
+	}
+
+	return recorded.status, true
+}
+
+// purgeExpiredLocked removes statuses whose retention has passed. It must be called with mu held.
+// Statuses are appended to expiries in completion order, so the oldest statuses are always at the front.
+func (s *StatusService) purgeExpiredLocked() {
+	now := s.clock.Now()
+
+	for len(s.expiries) > 0 && !now.Before(s.expiries[0].expiresAt) {
+		expired := s.expiries[0]
+		s.expiries[0] = nil
+		s.expiries = s.expiries[1:]
+
+		if s.statuses[expired.status.Payment.ID] == expired {
+			delete(s.statuses, expired.status.Payment.ID)
+		}
+	}
+}
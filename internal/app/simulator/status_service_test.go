@@ -0,0 +1,194 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StatusService(t *testing.T) {
+	payment := Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}
+
+	tests := []struct {
+		name               string
+		prepareMockService func(*MockService)
+		run                func(*testing.T, *StatusService, *clock.Mock)
+	}{
+		{
+			name: "Accepted payment is recorded",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Once()
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				require.NoError(t, service.Process(context.Background(), payment))
+
+				status, ok := service.Status("abc-1")
+				require.True(t, ok)
+				assert.Equal(t, PaymentStatus{Payment: payment}, status)
+			},
+		},
+		{
+			name: "Rejected payment is recorded",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(ErrInsufficientFunds).Once()
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				require.ErrorIs(t, service.Process(context.Background(), payment), ErrInsufficientFunds)
+
+				status, ok := service.Status("abc-1")
+				require.True(t, ok)
+				assert.Equal(t, PaymentStatus{Payment: payment, Err: ErrInsufficientFunds}, status)
+			},
+		},
+		{
+			name: "Payment in progress is pending",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, payment).
+					RunAndReturn(func(ctx context.Context, _ Payment) error {
+						<-ctx.Done()
+						return ctx.Err()
+					}).
+					Once()
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				ctx, cncl := context.WithCancel(context.Background())
+
+				errChan := make(chan error, 1)
+				go func() {
+					errChan <- service.Process(ctx, payment)
+				}()
+
+				require.Eventually(t, func() bool {
+					status, ok := service.Status("abc-1")
+					return ok && status.Pending
+				}, time.Second, time.Millisecond)
+
+				cncl()
+				require.ErrorIs(t, <-errChan, context.Canceled)
+
+				_, ok := service.Status("abc-1")
+				assert.False(t, ok, "cancelled payments are not recorded")
+			},
+		},
+		{
+			name: "Dropped payment is not recorded",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(ErrDropConnection).Once()
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				require.ErrorIs(t, service.Process(context.Background(), payment), ErrDropConnection)
+
+				_, ok := service.Status("abc-1")
+				assert.False(t, ok)
+			},
+		},
+		{
+			name: "Payment without ID is not recorded",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, Payment{Amount: 1}).Return(nil).Once()
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				require.NoError(t, service.Process(context.Background(), Payment{Amount: 1}))
+
+				_, ok := service.Status("")
+				assert.False(t, ok)
+			},
+		},
+		{
+			name: "Status expires after retention",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(errors.New("service failure")).Once()
+			},
+			run: func(t *testing.T, service *StatusService, mockClock *clock.Mock) {
+				require.Error(t, service.Process(context.Background(), payment))
+
+				mockClock.Add(time.Minute - time.Nanosecond)
+				_, ok := service.Status("abc-1")
+				assert.True(t, ok)
+
+				mockClock.Add(time.Nanosecond)
+				_, ok = service.Status("abc-1")
+				assert.False(t, ok)
+			},
+		},
+		{
+			name: "Later payment replaces status",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(errors.New("service failure")).Once()
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Once()
+			},
+			run: func(t *testing.T, service *StatusService, mockClock *clock.Mock) {
+				require.Error(t, service.Process(context.Background(), payment))
+
+				mockClock.Add(30 * time.Second)
+				require.NoError(t, service.Process(context.Background(), payment))
+
+				mockClock.Add(30 * time.Second)
+				status, ok := service.Status("abc-1")
+				require.True(t, ok, "expiry of the first payment doesn't remove the later status")
+				assert.NoError(t, status.Err)
+			},
+		},
+		{
+			name: "Acknowledged payment is pending",
+			prepareMockService: func(*MockService) {
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				release := service.RecordPending(payment)
+
+				status, ok := service.Status("abc-1")
+				require.True(t, ok)
+				assert.Equal(t, PaymentStatus{Payment: payment, Pending: true}, status)
+
+				release()
+				_, ok = service.Status("abc-1")
+				assert.False(t, ok, "released pending status is removed")
+			},
+		},
+		{
+			name: "Processed payment is kept after release",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil).Once()
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				release := service.RecordPending(payment)
+				require.NoError(t, service.Process(context.Background(), payment))
+				release()
+
+				status, ok := service.Status("abc-1")
+				require.True(t, ok)
+				assert.Equal(t, PaymentStatus{Payment: payment}, status)
+			},
+		},
+		{
+			name: "Acknowledged payment without ID is not recorded",
+			prepareMockService: func(*MockService) {
+			},
+			run: func(t *testing.T, service *StatusService, _ *clock.Mock) {
+				release := service.RecordPending(Payment{Amount: 1})
+				release()
+
+				_, ok := service.Status("")
+				assert.False(t, ok)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockService := NewMockService(t)
+			test.prepareMockService(mockService)
+
+			mockClock := clock.NewMock()
+			service := NewStatusService(Config{StatusRetention: time.Minute}, mockService, mockClock)
+
+			test.run(t, service, mockClock)
+		})
+	}
+}
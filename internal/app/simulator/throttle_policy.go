@@ -0,0 +1,28 @@
+package simulator
+
+import "fmt"
+
+// ThrottlePolicy defines what happens to requests exceeding a rate limit.
+type ThrottlePolicy string
+
+const (
+	// ThrottleReject rejects excess requests as throttled.
+	ThrottleReject ThrottlePolicy = "reject"
+	// ThrottleDelay delays excess requests until the rate limits allow them.
+	ThrottleDelay ThrottlePolicy = "delay"
+	// ThrottleDisconnect closes the connection sending an excess request without a response.
+	ThrottleDisconnect ThrottlePolicy = "disconnect"
+)
+
+// UnmarshalText parses the throttle policy and returns an error if it is not supported.
+func (p *ThrottlePolicy) UnmarshalText(b []byte) error {
+	policy := ThrottlePolicy(b)
+
+	switch policy {
+	case ThrottleReject, ThrottleDelay, ThrottleDisconnect:
+		*p = policy
+		return nil
+	default:
+		return fmt.Errorf("unknown throttle policy %q", policy)
+	}
+}
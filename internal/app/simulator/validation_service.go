@@ -1,6 +1,8 @@
 package simulator
 
-// ValidationService validates and processes amounts using an underlying service.
+import "context"
+
+// ValidationService validates and processes payments using an underlying service.
 type ValidationService struct {
 	service Service
 }
@@ -10,12 +12,12 @@ func NewValidationService(service Service) *ValidationService {
 	return &ValidationService{service: service}
 }
 
-// Process validates and processes the amount using the underlying service.
+// Process validates and processes the payment using the underlying service.
 // It returns an error if the amount is invalid (i.e., less than 0).
-func (v *ValidationService) Process(amount int) error {
-	if amount < 0 {
+func (v *ValidationService) Process(ctx context.Context, payment Payment) error {
+	if payment.Amount < 0 {
 		return ErrInvalidAmount
 	}
 
-	return v.service.Process(amount)
+	return v.service.Process(ctx, payment)
 }
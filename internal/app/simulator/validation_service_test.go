@@ -1,6 +1,7 @@
 package simulator
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,7 +10,7 @@ import (
 func Test_ValidationService_InvalidAmount(t *testing.T) {
 	validationService := NewValidationService(nil)
 
-	err := validationService.Process(-1)
+	err := validationService.Process(context.Background(), Payment{Amount: -1})
 	assert.ErrorIs(t, err, ErrInvalidAmount)
 }
 
@@ -17,8 +18,8 @@ func Test_ValidationService_ValidAmount(t *testing.T) {
 	mockService := NewMockService(t)
 	validationService := NewValidationService(mockService)
 
-	mockService.EXPECT().Process(1).Return(nil)
+	mockService.EXPECT().Process(context.Background(), Payment{Amount: 1}).Return(nil)
 
-	err := validationService.Process(1)
+	err := validationService.Process(context.Background(), Payment{Amount: 1})
 	assert.NoError(t, err)
 }
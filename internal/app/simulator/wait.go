@@ -0,0 +1,23 @@
+package simulator
+
+import (
+	"context"
+	"time"
+)
+
+// wait blocks for the given duration or until the context is cancelled, in which case the context error is returned.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,607 @@
+package internal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// instancesSetting is the setting listing the instances, both in the configuration file and as an environment variable.
+const instancesSetting = "instances"
+
+// Settings of the configuration file which are JSON objects in the format of the scenario, ledger and calendar files.
+const (
+	scenarioSetting = "scenario"
+	ledgerSetting   = "ledger"
+	calendarSetting = "calendar"
+)
+
+// setting is a field of simulator.Config, with its environment variable name without prefix and its name in the configuration file.
+type setting struct {
+	field reflect.StructField
+	key   string
+	name  string
+}
+
+// LoadConfig reads the configuration from the defaults, the JSON configuration file at path if it is set,
+// and the environment variables with the prefix, each overriding the previous ones.
+// Instances inherit the configuration, and override it with their settings in the file
+// and the environment variables prefixed with their names.
+// Every invalid setting and configuration is reported in the returned error.
+func LoadConfig(prefix, path string) (simulator.Config, []Instance, error) {
+	settings, err := configSettings()
+	if err != nil {
+		return simulator.Config{}, nil, err
+	}
+
+	file, err := readConfigFile(path, settings)
+	if err != nil {
+		return simulator.Config{}, nil, err
+	}
+
+	var (
+		cfg  simulator.Config
+		errs []error
+	)
+	errs = append(errs, processSettings(prefix, &cfg, settings, file.values, "config file setting", true)...)
+	file.objects.apply(&cfg)
+	// Settings which can't be parsed are left unset, so the configuration is only validated when every setting is parsed.
+	valid := len(errs) == 0
+
+	names := file.instanceNames()
+	if value, ok := os.LookupEnv(strings.ToUpper(prefix + "_" + instancesSetting)); ok {
+		names = splitList(value)
+	}
+
+	if len(names) == 0 {
+		if valid {
+			errs = append(errs, cfg.Validate())
+		}
+
+		if err := errors.Join(errs...); err != nil {
+			return simulator.Config{}, nil, err
+		}
+
+		return cfg, nil, nil
+	}
+
+	instances := make([]Instance, len(names))
+	for i, name := range names {
+		instances[i] = Instance{Name: name, Config: cfg}
+
+		fileInstance := file.instance(name)
+
+		instanceErrs := processSettings(prefix+"_"+name, &instances[i].Config, settings, fileInstance.values,
+			"config file setting of instance "+name, false)
+		fileInstance.objects.apply(&instances[i].Config)
+		if valid && len(instanceErrs) == 0 {
+			instanceErrs = append(instanceErrs, instances[i].Config.Validate())
+		}
+
+		errs = append(errs, prefixErrors("instance "+name, instanceErrs)...)
+	}
+
+	errs = append(errs, validateInstances(instances))
+
+	if err := errors.Join(errs...); err != nil {
+		return simulator.Config{}, nil, err
+	}
+
+	return cfg, instances, nil
+}
+
+// PrintConfig writes the configuration and the instances as JSON, in the format of the configuration file.
+func PrintConfig(w io.Writer, cfg simulator.Config, instances []Instance) error {
+	settings, err := configSettings()
+	if err != nil {
+		return err
+	}
+
+	root := configObject(cfg, settings)
+	if len(instances) > 0 {
+		values := make([]object, len(instances))
+		for i, instance := range instances {
+			values[i] = append(object{{name: "name", value: instance.Name}}, configObject(instance.Config, settings)...)
+		}
+
+		root = append(root, member{name: instancesSetting, value: values})
+	}
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", b)
+
+	return err
+}
+
+// EncodeConfig returns the configuration as JSON, in the format PrintConfig writes it.
+func EncodeConfig(cfg simulator.Config) (json.RawMessage, error) {
+	settings, err := configSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(configObject(cfg, settings))
+}
+
+// configSettings lists the settings of simulator.Config parsed from environment variables, in the order of its fields.
+// The environment variable names are taken from envconfig, so they match the documented variables.
+func configSettings() ([]setting, error) {
+	var sb strings.Builder
+	if err := envconfig.Usagef("", &simulator.Config{}, &sb, "{{range .}}{{.Name}} {{usage_key .}}\n{{end}}"); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeFor[simulator.Config]()
+
+	var settings []setting
+	for _, line := range strings.Split(strings.TrimSpace(sb.String()), "\n") {
+		name, key, _ := strings.Cut(line, " ")
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+
+		settings = append(settings, setting{field: field, key: key, name: camelCase(key)})
+	}
+
+	return settings, nil
+}
+
+// camelCase converts an environment variable name such as SERVER_TLS_CERT_FILE to serverTlsCertFile.
+func camelCase(key string) string {
+	parts := strings.Split(strings.ToLower(key), "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+// processSettings sets the settings from the environment variables with the prefix,
+// or from the values of the file, named by source, if the variables aren't set.
+// Settings defined by neither are set to their defaults if defaults is true, and left unchanged otherwise.
+// Every setting is processed on its own, so every invalid value is reported.
+func processSettings(prefix string, cfg *simulator.Config, settings []setting, values map[string]string, source string, defaults bool) []error {
+	v := reflect.ValueOf(cfg).Elem()
+
+	var errs []error
+	for _, s := range settings {
+		key := strings.ToUpper(prefix + "_" + s.key)
+		value, ok := os.LookupEnv(key)
+
+		origin := key
+		if !ok {
+			value, ok = values[s.name]
+			origin = fmt.Sprintf("%s %q", source, s.name)
+		}
+		if !ok {
+			if !defaults {
+				continue
+			}
+
+			value, ok = s.field.Tag.Lookup("default")
+			origin = "default of " + s.name
+		}
+
+		// Settings without a default are zero.
+		field := reflect.New(s.field.Type).Elem()
+		if ok {
+			if err := decodeValue(field, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", origin, err))
+				continue
+			}
+		}
+
+		v.FieldByIndex(s.field.Index).Set(field)
+	}
+
+	return errs
+}
+
+// decodeValue parses the value into the field, as envconfig parses environment variables.
+// Text values are parsed with their UnmarshalText method, and list items are separated with commas.
+func decodeValue(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	if field.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(value)
+		field.SetInt(int64(d))
+
+		return err
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		items := splitList(value)
+		list := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(list.Index(i), item); err != nil {
+				return err
+			}
+		}
+		field.Set(list)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+// prefixErrors adds the prefix to the errors, and splits the joined errors, so every problem has the prefix.
+func prefixErrors(prefix string, errs []error) []error {
+	var prefixed []error
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // Only joined errors are split.
+			prefixed = append(prefixed, prefixErrors(prefix, joined.Unwrap())...)
+		} else if err != nil {
+			prefixed = append(prefixed, fmt.Errorf("%s: %w", prefix, err))
+		}
+	}
+
+	return prefixed
+}
+
+// splitList splits a comma separated list. An empty string is an empty list.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
+// configFile is the JSON configuration file, with the settings converted to the format of environment variables.
+type configFile struct {
+	configFileInstance
+	instances []configFileInstance
+}
+
+// configFileInstance is an instance in the configuration file with its settings, or the top level settings.
+type configFileInstance struct {
+	name    string
+	values  map[string]string
+	objects configObjects
+}
+
+// configObjects are the scenario, ledger and calendar defined in the configuration file. They are nil if they aren't defined.
+type configObjects struct {
+	scenario *simulator.Scenario
+	accounts []simulator.Account
+	calendar *simulator.Calendar
+}
+
+// readConfigFile reads the configuration file at path. The file is empty if path isn't set.
+// Unknown and invalid settings are reported with every problem.
+func readConfigFile(path string, settings []setting) (configFile, error) {
+	if path == "" {
+		return configFile{}, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return configFile{}, fmt.Errorf("can't read config file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return configFile{}, fmt.Errorf("can't decode config file: %w", err)
+	}
+
+	var (
+		file configFile
+		errs []error
+	)
+
+	instances, ok := raw[instancesSetting]
+	delete(raw, instancesSetting)
+
+	file.configFileInstance, errs = readFileInstance(raw, settings)
+
+	if ok {
+		var rawInstances []map[string]json.RawMessage
+		if err := json.Unmarshal(instances, &rawInstances); err != nil {
+			return configFile{}, fmt.Errorf("can't decode instances of config file: %w", err)
+		}
+
+		for i, rawInstance := range rawInstances {
+			var name string
+			if err := json.Unmarshal(rawInstance["name"], &name); err != nil || name == "" {
+				errs = append(errs, fmt.Errorf("instance %d: name must be set", i))
+				continue
+			}
+			delete(rawInstance, "name")
+
+			instance, instanceErrs := readFileInstance(rawInstance, settings)
+			instance.name = name
+			errs = append(errs, prefixErrors("instance "+name, instanceErrs)...)
+
+			file.instances = append(file.instances, instance)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return configFile{}, err
+	}
+
+	return file, nil
+}
+
+// readFileInstance reads the settings and objects of a JSON object of the configuration file.
+func readFileInstance(raw map[string]json.RawMessage, settings []setting) (configFileInstance, []error) {
+	var (
+		instance configFileInstance
+		errs     []error
+	)
+
+	for _, o := range []struct {
+		name  string
+		parse func(r io.Reader) error
+	}{
+		{name: scenarioSetting, parse: func(r io.Reader) error {
+			scenario, err := simulator.ParseScenario(r)
+			instance.objects.scenario = &scenario
+
+			return err
+		}},
+		{name: ledgerSetting, parse: func(r io.Reader) error {
+			accounts, err := simulator.ParseAccounts(r)
+			// A ledger without accounts is still enabled.
+			instance.objects.accounts = append([]simulator.Account{}, accounts...)
+
+			return err
+		}},
+		{name: calendarSetting, parse: func(r io.Reader) error {
+			calendar, err := simulator.ParseCalendar(r)
+			instance.objects.calendar = &calendar
+
+			return err
+		}},
+	} {
+		value, ok := raw[o.name]
+		delete(raw, o.name)
+
+		if !ok || string(bytes.TrimSpace(value)) == "null" {
+			continue
+		}
+
+		if err := o.parse(bytes.NewReader(value)); err != nil {
+			errs = append(errs, prefixErrors(fmt.Sprintf("setting %q", o.name), []error{err})...)
+		}
+	}
+
+	values, valueErrs := fileValues(raw, settings)
+	instance.values = values
+
+	return instance, append(errs, valueErrs...)
+}
+
+// fileValues converts the settings of a JSON object to the format of environment variables.
+// Strings are unquoted, lists are separated with commas, and nulls are ignored.
+func fileValues(raw map[string]json.RawMessage, settings []setting) (map[string]string, []error) {
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.name] = true
+	}
+
+	var errs []error
+
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("unknown setting %q", name))
+			continue
+		}
+
+		v, ok, err := fileValue(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("setting %q: %w", name, err))
+		} else if ok {
+			values[name] = v
+		}
+	}
+
+	return values, errs
+}
+
+// fileValue converts a JSON value to the format of environment variables. It returns false for null.
+func fileValue(raw json.RawMessage) (string, bool, error) {
+	raw = bytes.TrimSpace(raw)
+
+	switch {
+	case string(raw) == "null":
+		return "", false, nil
+	case bytes.HasPrefix(raw, []byte(`"`)):
+		var s string
+		err := json.Unmarshal(raw, &s)
+
+		return s, err == nil, err
+	case bytes.HasPrefix(raw, []byte("[")):
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return "", false, err
+		}
+
+		values := make([]string, len(items))
+		for i, item := range items {
+			value, ok, err := fileValue(item)
+			if err != nil || !ok || strings.HasPrefix(string(item), "[") {
+				return "", false, errors.New("lists must contain strings, numbers or booleans")
+			}
+			values[i] = value
+		}
+
+		return strings.Join(values, ","), true, nil
+	case bytes.HasPrefix(raw, []byte("{")):
+		return "", false, errors.New("objects are only supported for the scenario, ledger and calendar")
+	default:
+		return string(raw), true, nil
+	}
+}
+
+// instance returns the settings of the instance in the file, which are empty if the file doesn't define the instance.
+func (f configFile) instance(name string) configFileInstance {
+	for _, i := range f.instances {
+		if i.name == name {
+			return i
+		}
+	}
+
+	return configFileInstance{name: name}
+}
+
+// instanceNames returns the names of the instances in the file.
+func (f configFile) instanceNames() []string {
+	names := make([]string, len(f.instances))
+	for i, instance := range f.instances {
+		names[i] = instance.name
+	}
+
+	return names
+}
+
+// apply sets the objects which are defined on the configuration.
+func (o configObjects) apply(cfg *simulator.Config) {
+	if o.scenario != nil {
+		cfg.Scenario = o.scenario
+	}
+
+	if o.accounts != nil {
+		cfg.Accounts = o.accounts
+	}
+
+	if o.calendar != nil {
+		cfg.Calendar = o.calendar
+	}
+}
+
+// member is a member of a JSON object.
+type member struct {
+	name  string
+	value any
+}
+
+// object is a JSON object which keeps the order of its members.
+type object []member
+
+// MarshalJSON writes the members in order.
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(m.name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// configObject converts the configuration to a JSON object in the format of the configuration file.
+func configObject(cfg simulator.Config, settings []setting) object {
+	v := reflect.ValueOf(cfg)
+
+	o := make(object, len(settings))
+	for i, s := range settings {
+		o[i] = member{name: s.name, value: fileJSONValue(v.FieldByIndex(s.field.Index))}
+	}
+
+	if cfg.Scenario != nil {
+		o = append(o, member{name: scenarioSetting, value: cfg.Scenario})
+	}
+
+	if cfg.Accounts != nil {
+		o = append(o, member{name: ledgerSetting, value: object{{name: "accounts", value: cfg.Accounts}}})
+	}
+
+	if cfg.Calendar != nil {
+		o = append(o, member{name: calendarSetting, value: cfg.Calendar})
+	}
+
+	return o
+}
+
+// fileJSONValue returns the value in the format of the configuration file. Durations and text values are strings.
+func fileJSONValue(v reflect.Value) any {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		return m
+	}
+
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		return time.Duration(v.Int()).String()
+	}
+
+	if v.Kind() == reflect.Slice {
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = fileJSONValue(v.Index(i))
+		}
+
+		return values
+	}
+
+	return v.Interface()
+}
@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_LoadConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		env           map[string]string
+		assertFunc    func(*testing.T, simulator.Config, []Instance)
+		expectedErr   []string
+		unexpectedErr []string
+	}{
+		{
+			name: "Defaults",
+			assertFunc: func(t *testing.T, cfg simulator.Config, instances []Instance) {
+				assert.Equal(t, 11111, cfg.ServerPort)
+				assert.Equal(t, 3*time.Second, cfg.ServerGracefulShutdownTimeout)
+				assert.Zero(t, cfg.ServerMaxConnections)
+				assert.Zero(t, cfg.ServerWorkers)
+				assert.Equal(t, simulator.ConnectionLimitReject, cfg.ServerConnectionLimitPolicy)
+				assert.Equal(t, []simulator.Fault{simulator.FaultClose, simulator.FaultTruncate, simulator.FaultNoNewline, simulator.FaultStall}, cfg.FaultKinds)
+				assert.Equal(t, simulator.LatencyAmount, cfg.Latency.String())
+				assert.Empty(t, instances)
+			},
+		},
+		{
+			name: "File overrides defaults",
+			file: `{
+				"serverPort": 12000,
+				"serverIdleTimeout": "1m",
+				"serverTlsClientAuth": "none",
+				"serverAsyncSettlement": true,
+				"faultProbability": 0.5,
+				"faultKinds": ["close", "stall"],
+				"latency": "fixed:delay=5ms",
+				"latencySeed": 42,
+				"ledgerFile": null
+			}`,
+			assertFunc: func(t *testing.T, cfg simulator.Config, _ []Instance) {
+				assert.Equal(t, 12000, cfg.ServerPort)
+				assert.Equal(t, time.Minute, cfg.ServerIdleTimeout)
+				assert.True(t, cfg.ServerAsyncSettlement)
+				assert.InDelta(t, 0.5, cfg.FaultProbability, 0)
+				assert.Equal(t, []simulator.Fault{simulator.FaultClose, simulator.FaultStall}, cfg.FaultKinds)
+				assert.Equal(t, "fixed:delay=5ms", cfg.Latency.String())
+				assert.Equal(t, uint64(42), cfg.LatencySeed)
+				assert.Equal(t, 11112, cfg.AdminPort)
+			},
+		},
+		{
+			name: "Environment overrides file",
+			file: `{"serverPort": 12000, "serverWorkers": 10}`,
+			env:  map[string]string{"TEST_SERVER_PORT": "13000", "TEST_FAULT_KINDS": "truncate"},
+			assertFunc: func(t *testing.T, cfg simulator.Config, _ []Instance) {
+				assert.Equal(t, 13000, cfg.ServerPort)
+				assert.Equal(t, 10, cfg.ServerWorkers)
+				assert.Equal(t, []simulator.Fault{simulator.FaultTruncate}, cfg.FaultKinds)
+			},
+		},
+		{
+			name: "Instances inherit the configuration",
+			file: `{
+				"serverWorkers": 10,
+				"latency": "fixed:delay=5ms",
+				"instances": [
+					{"name": "gbp", "serverPort": 12000, "adminPort": 12001},
+					{"name": "eur", "serverPort": 12010, "adminPort": 12011, "latency": "fixed:delay=50ms"}
+				]
+			}`,
+			env: map[string]string{"TEST_SERVER_WORKERS": "20", "TEST_EUR_SERVER_WORKERS": "30"},
+			assertFunc: func(t *testing.T, _ simulator.Config, instances []Instance) {
+				require.Len(t, instances, 2)
+
+				assert.Equal(t, "gbp", instances[0].Name)
+				assert.Equal(t, 12000, instances[0].Config.ServerPort)
+				assert.Equal(t, 20, instances[0].Config.ServerWorkers)
+				assert.Equal(t, "fixed:delay=5ms", instances[0].Config.Latency.String())
+
+				assert.Equal(t, "eur", instances[1].Name)
+				assert.Equal(t, 12010, instances[1].Config.ServerPort)
+				assert.Equal(t, 30, instances[1].Config.ServerWorkers)
+				assert.Equal(t, "fixed:delay=50ms", instances[1].Config.Latency.String())
+			},
+		},
+		{
+			name: "Environment lists instances",
+			file: `{"instances": [{"name": "gbp", "serverPort": 12000, "adminPort": 0}]}`,
+			env:  map[string]string{"TEST_INSTANCES": "usd", "TEST_USD_SERVER_PORT": "12020"},
+			assertFunc: func(t *testing.T, _ simulator.Config, instances []Instance) {
+				require.Len(t, instances, 1)
+				assert.Equal(t, "usd", instances[0].Name)
+				assert.Equal(t, 12020, instances[0].Config.ServerPort)
+				assert.Equal(t, 11112, instances[0].Config.AdminPort)
+			},
+		},
+		{
+			name: "Scenario, ledger and calendar",
+			file: `{
+				"scenario": {"rules": [{"name": "slow", "outcome": {"action": "accept", "delay": "150ms"}}], "default": {"action": "accept"}},
+				"ledger": {"accounts": [{"id": "GB29NWBK60161331926819", "balance": 100}]},
+				"instances": [
+					{"name": "gbp", "serverPort": 12000, "adminPort": 12001, "calendar": {"openHours": [{"from": "06:00", "to": "22:00"}]}},
+					{"name": "eur", "serverPort": 12010, "adminPort": 12011, "scenario": null, "ledger": {"accounts": []}}
+				]
+			}`,
+			assertFunc: func(t *testing.T, cfg simulator.Config, instances []Instance) {
+				require.NotNil(t, cfg.Scenario)
+				assert.Equal(t, "slow", cfg.Scenario.Rules[0].Name)
+				assert.Equal(t, []simulator.Account{{ID: "GB29NWBK60161331926819", Balance: 100}}, cfg.Accounts)
+				assert.Nil(t, cfg.Calendar)
+
+				require.Len(t, instances, 2)
+
+				assert.Equal(t, cfg.Scenario, instances[0].Config.Scenario)
+				assert.Equal(t, cfg.Accounts, instances[0].Config.Accounts)
+				require.NotNil(t, instances[0].Config.Calendar)
+				assert.Len(t, instances[0].Config.Calendar.OpenHours, 1)
+
+				assert.Equal(t, cfg.Scenario, instances[1].Config.Scenario)
+				assert.Equal(t, []simulator.Account{}, instances[1].Config.Accounts)
+				assert.Nil(t, instances[1].Config.Calendar)
+			},
+		},
+		{
+			name: "Invalid file",
+			file: `{"serverPort": }`,
+			expectedErr: []string{
+				"can't decode config file",
+			},
+		},
+		{
+			name: "Invalid settings",
+			file: `{
+				"port": 12000,
+				"faultKinds": [["close"]],
+				"serverTlsClientAuth": {},
+				"ledger": {"accounts": [{"balance": 100}]},
+				"instances": [{"serverPort": 12000}, {"name": "gbp", "instances": [], "calendar": {"timezone": "Mars/Olympus"}}]
+			}`,
+			expectedErr: []string{
+				`setting "ledger": account 0: id must be set`,
+				`instance gbp: setting "calendar": `,
+				`unknown setting "port"`,
+				`setting "faultKinds": lists must contain strings, numbers or booleans`,
+				`setting "serverTlsClientAuth": objects are only supported for the scenario, ledger and calendar`,
+				"instance 0: name must be set",
+				`instance gbp: unknown setting "instances"`,
+			},
+		},
+		{
+			name: "Invalid values",
+			file: `{"serverWorkers": "many", "latency": "gamma"}`,
+			env:  map[string]string{"TEST_SERVER_IDLE_TIMEOUT": "soon", "TEST_SERVER_PORT": "x"},
+			expectedErr: []string{
+				`config file setting "serverWorkers": strconv.ParseInt: parsing "many": invalid syntax`,
+				`config file setting "latency": unknown latency "gamma"`,
+				`TEST_SERVER_IDLE_TIMEOUT: time: invalid duration "soon"`,
+				`TEST_SERVER_PORT: strconv.ParseInt: parsing "x": invalid syntax`,
+			},
+			unexpectedErr: []string{"server port 0"},
+		},
+		{
+			name: "Invalid configuration",
+			file: `{"serverPort": 70000, "serverGracefulShutdownTimeout": "-1s", "dummyMinAmountToWait": 200, "dummyMaxAmountToWait": 100}`,
+			expectedErr: []string{
+				"server port 70000 must be between 1 and 65535",
+				"server graceful shutdown timeout -1s must not be negative",
+				"dummy min amount to wait 200 must not be greater than dummy max amount to wait 100",
+			},
+		},
+		{
+			name: "Inline and file scenario",
+			file: `{"scenarioFile": "scenario.json", "scenario": {"default": {"action": "accept"}}}`,
+			expectedErr: []string{
+				"scenario and scenario file must not both be set",
+			},
+		},
+		{
+			name: "Invalid instances",
+			file: `{"instances": [{"name": "gbp", "serverWorkers": -1}, {"name": "eur"}]}`,
+			env:  map[string]string{"TEST_EUR_SERVER_PORT": "x"},
+			expectedErr: []string{
+				"instance gbp: server workers -1 must not be negative",
+				`instance eur: TEST_EUR_SERVER_PORT: strconv.ParseInt: parsing "x": invalid syntax`,
+				"instance eur: port 11111 is used by instance gbp",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			path := ""
+			if test.file != "" {
+				path = writeConfigFile(t, test.file)
+			}
+
+			cfg, instances, err := LoadConfig("test", path)
+			if len(test.expectedErr) > 0 {
+				require.Error(t, err)
+				for _, expected := range test.expectedErr {
+					assert.ErrorContains(t, err, expected)
+				}
+				for _, unexpected := range test.unexpectedErr {
+					assert.NotContains(t, err.Error(), unexpected)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			test.assertFunc(t, cfg, instances)
+		})
+	}
+}
+
+func Test_LoadConfig_LeavesEnvironment(t *testing.T) {
+	path := writeConfigFile(t, `{"serverPort": 12000, "instances": [{"name": "gbp", "adminPort": 0}]}`)
+
+	cfg, instances, err := LoadConfig("test", path)
+	require.NoError(t, err)
+	assert.Equal(t, 12000, cfg.ServerPort)
+	assert.Equal(t, 12000, instances[0].Config.ServerPort)
+
+	for _, key := range []string{"TEST_SERVER_PORT", "TEST_GBP_ADMIN_PORT"} {
+		_, ok := os.LookupEnv(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func Test_LoadConfig_MissingFile(t *testing.T) {
+	_, _, err := LoadConfig("test", filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "can't read config file")
+}
+
+func Test_PrintConfig(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"serverTlsClientAuth": "optional",
+		"serverTlsCertFile": "cert.pem",
+		"serverTlsKeyFile": "key.pem",
+		"latency": "normal:p50=50ms,p99=80ms",
+		"faultKinds": ["stall"],
+		"scenario": {"rules": [{"name": "slow", "match": {"paymentId": "^slow-"}, "outcome": {"action": "accept", "delay": "150ms"}}], "default": {"action": "accept"}},
+		"instances": [
+			{"name": "gbp", "adminPort": 0, "ledger": {"accounts": [{"id": "GB29NWBK60161331926819", "balance": 100, "limit": 50}]}},
+			{"name": "eur", "serverPort": 12000, "adminPort": 12001}
+		]
+	}`)
+
+	cfg, instances, err := LoadConfig("test", path)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintConfig(&buf, cfg, instances))
+
+	assert.Contains(t, buf.String(), `"serverGracefulShutdownTimeout": "3s"`)
+	assert.Contains(t, buf.String(), `"serverTlsClientAuth": "optional"`)
+	assert.Contains(t, buf.String(), `"faultKinds": [
+    "stall"
+  ]`)
+
+	printed, printedInstances, err := LoadConfig("test", writeConfigFile(t, buf.String()))
+	require.NoError(t, err)
+	assert.Equal(t, cfg, printed)
+	assert.Equal(t, instances, printedInstances)
+}
+
+func Test_EncodeConfig(t *testing.T) {
+	cfg, _, err := LoadConfig("test", writeConfigFile(t, `{
+		"latency": "uniform:min=10ms,max=50ms",
+		"scenario": {"default": {"action": "reject", "reason": "Closed"}}
+	}`))
+	require.NoError(t, err)
+
+	encoded, err := EncodeConfig(cfg)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintConfig(&buf, cfg, nil))
+
+	assert.JSONEq(t, buf.String(), string(encoded), "the admin API serves the configuration in the printed format")
+}
+
+func Test_configSettings(t *testing.T) {
+	settings, err := configSettings()
+	require.NoError(t, err)
+
+	names := make(map[string]string, len(settings))
+	for _, s := range settings {
+		names[s.key] = s.name
+	}
+
+	assert.Equal(t, "serverPort", names["SERVER_PORT"])
+	assert.Equal(t, "serverTlsCertFile", names["SERVER_TLS_CERT_FILE"])
+	assert.Equal(t, "serverIpRateLimit", names["SERVER_IP_RATE_LIMIT"])
+	assert.Equal(t, "latency", names["LATENCY"])
+	assert.Equal(t, "dummyMinAmountToWait", names["DUMMY_MIN_AMOUNT_TO_WAIT"])
+}
+
+// writeConfigFile writes the configuration to a temporary file and returns its path.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
@@ -0,0 +1,369 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/journal"
+)
+
+// Transport defines the runtime controls of the transport.
+type Transport interface {
+	SetFaults(probability float64, faults []simulator.Fault)
+	Drain()
+}
+
+// Service defines the runtime controls of the service processing payments.
+type Service interface {
+	SetScenario(scenario *simulator.Scenario)
+	Scenario() *simulator.Scenario
+	SetDelayBounds(minAmount, maxAmount int)
+}
+
+// Journal defines the queries of the request journal.
+type Journal interface {
+	Query(filter journal.Filter) ([]journal.Entry, error)
+}
+
+// Ledger defines the queries of the account ledger.
+type Ledger interface {
+	Accounts() []simulator.Account
+	Account(id string) (simulator.Account, bool)
+}
+
+// ConfigEncoder returns the configuration as JSON, in the format of the configuration file.
+type ConfigEncoder func(cfg simulator.Config) (json.RawMessage, error)
+
+// Server exposes an HTTP API to inspect and change the simulator behaviour at runtime.
+type Server struct {
+	mu        sync.Mutex
+	cfg       simulator.Config
+	transport Transport
+	service   Service
+	metrics   http.Handler
+	journal   Journal
+	ledger    Ledger
+	encode    ConfigEncoder
+}
+
+// NewServer creates a new Server instance. Metrics are served by the metrics handler.
+// The journal is queried for recorded requests and the ledger for account balances, unless they are nil.
+// The configuration is served as encoded by encode.
+func NewServer(cfg simulator.Config, transport Transport, service Service, metrics http.Handler, journal Journal, ledger Ledger, encode ConfigEncoder) *Server {
+	return &Server{
+		cfg:       cfg,
+		transport: transport,
+		service:   service,
+		metrics:   metrics,
+		journal:   journal,
+		ledger:    ledger,
+		encode:    encode,
+	}
+}
+
+// Start starts the HTTP server on the admin port.
+// It will block until context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.cfg.ServerHost, s.cfg.AdminPort))
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	serveErrChan := make(chan error, 1)
+	go func() {
+		serveErrChan <- server.Serve(listener)
+	}()
+
+	slog.Info("Admin server started", "port", s.cfg.AdminPort)
+	defer slog.Info("Admin server stopped")
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErrChan:
+		return err
+	}
+
+	err = server.Shutdown(context.Background())
+
+	if serveErr := <-serveErrChan; !errors.Is(serveErr, http.ErrServerClosed) {
+		return errors.Join(err, serveErr)
+	}
+
+	return err
+}
+
+// handler returns the HTTP handler serving the admin API.
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config", s.getConfig)
+	mux.HandleFunc("GET /scenario", s.getScenario)
+	mux.HandleFunc("PUT /scenario", s.putScenario)
+	mux.HandleFunc("DELETE /scenario", s.deleteScenario)
+	mux.HandleFunc("PUT /faults", s.putFaults)
+	mux.HandleFunc("PUT /delay", s.putDelay)
+	mux.HandleFunc("POST /drain", s.postDrain)
+	mux.Handle("GET /metrics", s.metrics)
+	mux.HandleFunc("GET /journal", s.getJournal)
+	mux.HandleFunc("GET /accounts", s.getAccounts)
+	mux.HandleFunc("GET /accounts/{id}", s.getAccount)
+
+	return mux
+}
+
+// getConfig returns the effective configuration, including the changes made at runtime.
+func (s *Server) getConfig(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	b, err := s.encode(cfg)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (s *Server) getScenario(w http.ResponseWriter, _ *http.Request) {
+	scenario := s.service.Scenario()
+	if scenario == nil {
+		writeError(w, http.StatusNotFound, errors.New("no active scenario"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, scenario)
+}
+
+func (s *Server) putScenario(w http.ResponseWriter, r *http.Request) {
+	scenario, err := simulator.ParseScenario(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.service.SetScenario(&scenario)
+
+	// The scenario replaces the one of the scenario file.
+	s.cfg.Scenario = &scenario
+	s.cfg.ScenarioFile = ""
+
+	slog.Info("Scenario activated", "rules", len(scenario.Rules))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteScenario(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.service.SetScenario(nil)
+
+	s.cfg.Scenario = nil
+	s.cfg.ScenarioFile = ""
+
+	slog.Info("Scenario deactivated")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// faultsRequest is the JSON representation of the fault injection settings.
+type faultsRequest struct {
+	Probability float64           `json:"probability"`
+	Kinds       []simulator.Fault `json:"kinds"`
+}
+
+func (s *Server) putFaults(w http.ResponseWriter, r *http.Request) {
+	var req faultsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Probability < 0 || req.Probability > 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("probability %v is not between 0 and 1", req.Probability))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transport.SetFaults(req.Probability, req.Kinds)
+
+	s.cfg.FaultProbability = req.Probability
+	s.cfg.FaultKinds = req.Kinds
+
+	slog.Info("Fault injection changed", "probability", req.Probability, "kinds", req.Kinds)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delayRequest is the JSON representation of the delay bounds.
+type delayRequest struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (s *Server) putDelay(w http.ResponseWriter, r *http.Request) {
+	var req delayRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Min < 0 || req.Min > req.Max {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("min %d must be between 0 and max %d", req.Min, req.Max))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.service.SetDelayBounds(req.Min, req.Max)
+
+	s.cfg.DummyMinAmountToWait = req.Min
+	s.cfg.DummyMaxAmountToWait = req.Max
+
+	slog.Info("Delay bounds changed", "min", req.Min, "max", req.Max)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) postDrain(w http.ResponseWriter, _ *http.Request) {
+	slog.Info("Drain requested")
+
+	s.transport.Drain()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// defaultJournalLimit is the number of latest entries returned by the journal query if no limit is given.
+const defaultJournalLimit = 100
+
+func (s *Server) getJournal(w http.ResponseWriter, r *http.Request) {
+	if s.journal == nil {
+		writeError(w, http.StatusNotFound, errors.New("journal is disabled"))
+		return
+	}
+
+	filter, err := parseJournalFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, err := s.journal.Query(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) getAccounts(w http.ResponseWriter, _ *http.Request) {
+	if s.ledger == nil {
+		writeError(w, http.StatusNotFound, errors.New("ledger is disabled"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.ledger.Accounts())
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	if s.ledger == nil {
+		writeError(w, http.StatusNotFound, errors.New("ledger is disabled"))
+		return
+	}
+
+	id := r.PathValue("id")
+
+	account, ok := s.ledger.Account(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown account %q", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, account)
+}
+
+// parseJournalFilter parses the journal filter from the query parameters
+// paymentId, connectionId, status, since, until and limit. Times are in RFC 3339 format.
+func parseJournalFilter(query url.Values) (journal.Filter, error) {
+	filter := journal.Filter{
+		PaymentID: query.Get("paymentId"),
+		Status:    query.Get("status"),
+		Limit:     defaultJournalLimit,
+	}
+
+	var err error
+	if v := query.Get("connectionId"); v != "" {
+		if filter.ConnectionID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return journal.Filter{}, fmt.Errorf("invalid connectionId %q", v)
+		}
+	}
+	if v := query.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return journal.Filter{}, fmt.Errorf("invalid since %q", v)
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return journal.Filter{}, fmt.Errorf("invalid until %q", v)
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 {
+			return journal.Filter{}, fmt.Errorf("invalid limit %q", v)
+		}
+	}
+
+	return filter, nil
+}
+
+// decodeJSON decodes the request body into v. It writes a bad request response and returns false if the body is invalid.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	return true
+}
+
+// errorResponse is the JSON representation of an error.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write admin response", "error", err)
+	}
+}
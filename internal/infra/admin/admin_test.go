@@ -0,0 +1,493 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/journal"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
+)
+
+func Test_Handler(t *testing.T) {
+	cfg := simulator.Config{
+		ServerHost:                    "localhost",
+		ServerPort:                    11111,
+		ServerGracefulShutdownTimeout: 3 * time.Second,
+		AdminPort:                     11112,
+		DummyMinAmountToWait:          100,
+		DummyMaxAmountToWait:          10000,
+		IdempotencyWindow:             10 * time.Minute,
+		FaultKinds:                    []simulator.Fault{simulator.FaultClose},
+	}
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		prepareMocks       func(*MockTransport, *MockService)
+		expectedStatus     int
+		expectedBody       string
+		expectedBodyPrefix string
+	}{
+		{
+			name:           "Get config",
+			method:         http.MethodGet,
+			path:           "/config",
+			prepareMocks:   func(*MockTransport, *MockService) {},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"adminPort":11112,"dummyMinAmountToWait":100,"dummyMaxAmountToWait":10000,"scenarioFile":"","scenario":null,` +
+				`"faultProbability":0,"faultKinds":["close"]}`,
+		},
+		{
+			name:   "Get active scenario",
+			method: http.MethodGet,
+			path:   "/scenario",
+			prepareMocks: func(_ *MockTransport, mockService *MockService) {
+				mockService.EXPECT().Scenario().Return(&simulator.Scenario{Default: simulator.Outcome{Action: simulator.ActionDrop}})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"rules":null,"default":{"action":"drop"}}`,
+		},
+		{
+			name:   "Get scenario when no scenario is active",
+			method: http.MethodGet,
+			path:   "/scenario",
+			prepareMocks: func(_ *MockTransport, mockService *MockService) {
+				mockService.EXPECT().Scenario().Return(nil)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no active scenario"}`,
+		},
+		{
+			name:   "Activate scenario",
+			method: http.MethodPut,
+			path:   "/scenario",
+			body:   `{"default": {"action": "reject", "reason": "Closed"}}`,
+			prepareMocks: func(_ *MockTransport, mockService *MockService) {
+				mockService.EXPECT().
+					SetScenario(&simulator.Scenario{Default: simulator.Outcome{Action: simulator.ActionReject, Reason: "Closed"}}).
+					Return()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:               "Activate invalid scenario",
+			method:             http.MethodPut,
+			path:               "/scenario",
+			body:               `{"default": {"action": "explode"}}`,
+			prepareMocks:       func(*MockTransport, *MockService) {},
+			expectedStatus:     http.StatusBadRequest,
+			expectedBodyPrefix: `{"error":"default outcome: unknown action`,
+		},
+		{
+			name:   "Deactivate scenario",
+			method: http.MethodDelete,
+			path:   "/scenario",
+			prepareMocks: func(_ *MockTransport, mockService *MockService) {
+				mockService.EXPECT().SetScenario((*simulator.Scenario)(nil)).Return()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "Change faults",
+			method: http.MethodPut,
+			path:   "/faults",
+			body:   `{"probability": 0.5, "kinds": ["truncate", "stall"]}`,
+			prepareMocks: func(mockTransport *MockTransport, _ *MockService) {
+				mockTransport.EXPECT().
+					SetFaults(0.5, []simulator.Fault{simulator.FaultTruncate, simulator.FaultStall}).
+					Return()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "Change faults with invalid probability",
+			method:         http.MethodPut,
+			path:           "/faults",
+			body:           `{"probability": 2}`,
+			prepareMocks:   func(*MockTransport, *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"probability 2 is not between 0 and 1"}`,
+		},
+		{
+			name:           "Change faults with unknown fault",
+			method:         http.MethodPut,
+			path:           "/faults",
+			body:           `{"probability": 1, "kinds": ["explode"]}`,
+			prepareMocks:   func(*MockTransport, *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unknown fault \"explode\""}`,
+		},
+		{
+			name:   "Change delay bounds",
+			method: http.MethodPut,
+			path:   "/delay",
+			body:   `{"min": 10, "max": 20}`,
+			prepareMocks: func(_ *MockTransport, mockService *MockService) {
+				mockService.EXPECT().SetDelayBounds(10, 20).Return()
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "Change delay bounds with min greater than max",
+			method:         http.MethodPut,
+			path:           "/delay",
+			body:           `{"min": 20, "max": 10}`,
+			prepareMocks:   func(*MockTransport, *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"min 20 must be between 0 and max 10"}`,
+		},
+		{
+			name:   "Drain",
+			method: http.MethodPost,
+			path:   "/drain",
+			prepareMocks: func(mockTransport *MockTransport, _ *MockService) {
+				mockTransport.EXPECT().Drain().Return()
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Unknown method",
+			method:         http.MethodGet,
+			path:           "/drain",
+			prepareMocks:   func(*MockTransport, *MockService) {},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockTransport := NewMockTransport(t)
+			mockService := NewMockService(t)
+			test.prepareMocks(mockTransport, mockService)
+
+			server := NewServer(cfg, mockTransport, mockService, metrics.NewRegistry(), nil, nil, encodeConfig)
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			rec := httptest.NewRecorder()
+
+			server.handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+
+			body := strings.TrimSpace(rec.Body.String())
+			if test.expectedBody != "" {
+				assert.JSONEq(t, test.expectedBody, body)
+			}
+			if test.expectedBodyPrefix != "" {
+				assert.True(t, strings.HasPrefix(body, test.expectedBodyPrefix), body)
+			}
+		})
+	}
+}
+
+func Test_Handler_Journal(t *testing.T) {
+	received := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		path           string
+		disabled       bool
+		prepareMock    func(*MockJournal)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Query with default limit",
+			path: "/journal",
+			prepareMock: func(mockJournal *MockJournal) {
+				mockJournal.EXPECT().
+					Query(journal.Filter{Limit: 100}).
+					Return([]journal.Entry{{ConnectionID: 1, ReceivedAt: received, Request: "PAYMENT|1", Status: "ACCEPTED", Outcome: "delivered"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `[{"connectionId":1,"remote":"","receivedAt":"2024-01-02T03:04:05Z","respondedAt":"0001-01-01T00:00:00Z",` +
+				`"latencyMs":0,"request":"PAYMENT|1","amount":0,"status":"ACCEPTED","response":"","outcome":"delivered"}]`,
+		},
+		{
+			name: "Query with filter",
+			path: "/journal?paymentId=abc&connectionId=2&status=REJECTED&since=2024-01-02T03:04:05Z&until=2024-01-03T00:00:00Z&limit=5",
+			prepareMock: func(mockJournal *MockJournal) {
+				mockJournal.EXPECT().
+					Query(journal.Filter{
+						PaymentID:    "abc",
+						ConnectionID: 2,
+						Status:       "REJECTED",
+						Since:        received,
+						Until:        time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+						Limit:        5,
+					}).
+					Return([]journal.Entry{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "Query with invalid limit",
+			path:           "/journal?limit=0",
+			prepareMock:    func(*MockJournal) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid limit \"0\""}`,
+		},
+		{
+			name:           "Query with invalid time",
+			path:           "/journal?since=yesterday",
+			prepareMock:    func(*MockJournal) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid since \"yesterday\""}`,
+		},
+		{
+			name: "Query fails",
+			path: "/journal",
+			prepareMock: func(mockJournal *MockJournal) {
+				mockJournal.EXPECT().Query(mock.Anything).Return(nil, errors.New("can't read journal"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"can't read journal"}`,
+		},
+		{
+			name:           "Journal is disabled",
+			path:           "/journal",
+			disabled:       true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"journal is disabled"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var j Journal
+			if !test.disabled {
+				mockJournal := NewMockJournal(t)
+				test.prepareMock(mockJournal)
+				j = mockJournal
+			}
+
+			server := NewServer(simulator.Config{}, NewMockTransport(t), NewMockService(t), metrics.NewRegistry(), j, nil, encodeConfig)
+
+			rec := httptest.NewRecorder()
+			server.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.JSONEq(t, test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func Test_Handler_Accounts(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		disabled       bool
+		prepareMock    func(*MockLedger)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "List accounts",
+			path: "/accounts",
+			prepareMock: func(mockLedger *MockLedger) {
+				mockLedger.EXPECT().Accounts().Return([]simulator.Account{{ID: "ACC-1", Balance: 100, Limit: 50}, {ID: "ACC-2", Balance: -5}})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"id":"ACC-1","balance":100,"limit":50},{"id":"ACC-2","balance":-5,"limit":0}]`,
+		},
+		{
+			name: "Get account",
+			path: "/accounts/ACC-1",
+			prepareMock: func(mockLedger *MockLedger) {
+				mockLedger.EXPECT().Account("ACC-1").Return(simulator.Account{ID: "ACC-1", Balance: 100, Limit: 50}, true)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"ACC-1","balance":100,"limit":50}`,
+		},
+		{
+			name: "Get unknown account",
+			path: "/accounts/ACC-3",
+			prepareMock: func(mockLedger *MockLedger) {
+				mockLedger.EXPECT().Account("ACC-3").Return(simulator.Account{}, false)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown account \"ACC-3\""}`,
+		},
+		{
+			name:           "Ledger is disabled",
+			path:           "/accounts",
+			disabled:       true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"ledger is disabled"}`,
+		},
+		{
+			name:           "Ledger is disabled for account",
+			path:           "/accounts/ACC-1",
+			disabled:       true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"ledger is disabled"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var l Ledger
+			if !test.disabled {
+				mockLedger := NewMockLedger(t)
+				test.prepareMock(mockLedger)
+				l = mockLedger
+			}
+
+			server := NewServer(simulator.Config{}, NewMockTransport(t), NewMockService(t), metrics.NewRegistry(), nil, l, encodeConfig)
+
+			rec := httptest.NewRecorder()
+			server.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.JSONEq(t, test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func Test_Handler_ConfigReflectsChanges(t *testing.T) {
+	mockTransport := NewMockTransport(t)
+	mockService := NewMockService(t)
+
+	mockTransport.EXPECT().SetFaults(mock.Anything, mock.Anything).Return()
+	mockService.EXPECT().SetDelayBounds(mock.Anything, mock.Anything).Return()
+	mockService.EXPECT().SetScenario(mock.Anything).Return()
+
+	server := NewServer(simulator.Config{ScenarioFile: "scenario.json"}, mockTransport, mockService, metrics.NewRegistry(), nil, nil, encodeConfig)
+	handler := server.handler()
+
+	for path, body := range map[string]string{
+		"/faults":   `{"probability": 0.25, "kinds": ["no-newline"]}`,
+		"/delay":    `{"min": 1, "max": 2}`,
+		"/scenario": `{"default": {"action": "drop"}}`,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.JSONEq(t, `{"adminPort":0,"dummyMinAmountToWait":1,"dummyMaxAmountToWait":2,"scenarioFile":"",`+
+		`"scenario":{"rules":null,"default":{"action":"drop"}},"faultProbability":0.25,"faultKinds":["no-newline"]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/scenario", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Contains(t, rec.Body.String(), `"scenarioFile":"","scenario":null`)
+}
+
+func Test_Handler_ConfigEncodingFailure(t *testing.T) {
+	encode := func(simulator.Config) (json.RawMessage, error) {
+		return nil, errors.New("encoding failure")
+	}
+
+	server := NewServer(simulator.Config{}, NewMockTransport(t), NewMockService(t), metrics.NewRegistry(), nil, nil, encode)
+
+	rec := httptest.NewRecorder()
+	server.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"error":"encoding failure"}`, rec.Body.String())
+}
+
+func Test_Handler_Metrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewCounter("test_total", "Test counter.").Inc()
+
+	server := NewServer(simulator.Config{}, NewMockTransport(t), NewMockService(t), registry, nil, nil, encodeConfig)
+
+	rec := httptest.NewRecorder()
+	server.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "# HELP test_total Test counter.\n# TYPE test_total counter\ntest_total 1\n", rec.Body.String())
+}
+
+func Test_Start(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port := getFreePort(t)
+
+	server := NewServer(simulator.Config{ServerHost: "localhost", AdminPort: port}, NewMockTransport(t), NewMockService(t), metrics.NewRegistry(), nil, nil, encodeConfig)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Start(ctx)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = client.Get(fmt.Sprintf("http://localhost:%d/config", port)) //nolint:noctx
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), fmt.Sprintf(`"adminPort":%d`, port))
+
+	cncl()
+
+	assert.NoError(t, <-errChan)
+}
+
+// getFreePort returns a free port number.
+func getFreePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close() //nolint:errcheck
+
+	return l.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+}
+
+// encodeConfig encodes the settings changed by the admin API.
+func encodeConfig(cfg simulator.Config) (json.RawMessage, error) {
+	return json.Marshal(struct {
+		AdminPort            int                 `json:"adminPort"`
+		DummyMinAmountToWait int                 `json:"dummyMinAmountToWait"`
+		DummyMaxAmountToWait int                 `json:"dummyMaxAmountToWait"`
+		ScenarioFile         string              `json:"scenarioFile"`
+		Scenario             *simulator.Scenario `json:"scenario"`
+		FaultProbability     float64             `json:"faultProbability"`
+		FaultKinds           []simulator.Fault   `json:"faultKinds"`
+	}{
+		AdminPort:            cfg.AdminPort,
+		DummyMinAmountToWait: cfg.DummyMinAmountToWait,
+		DummyMaxAmountToWait: cfg.DummyMaxAmountToWait,
+		ScenarioFile:         cfg.ScenarioFile,
+		Scenario:             cfg.Scenario,
+		FaultProbability:     cfg.FaultProbability,
+		FaultKinds:           cfg.FaultKinds,
+	})
+}
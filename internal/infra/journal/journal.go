@@ -0,0 +1,274 @@
+// Package journal implements an append-only journal of requests and their responses, stored as JSON lines.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// Outcomes of delivering a response.
+const (
+	// OutcomeDelivered is the outcome of responses written completely.
+	OutcomeDelivered = "delivered"
+	// OutcomeCancelled is the outcome of requests cancelled because the grace period of a graceful shutdown is finished.
+	OutcomeCancelled = "cancelled"
+	// OutcomeFailed is the outcome of responses that couldn't be written.
+	OutcomeFailed = "failed"
+)
+
+// Entry is a request received on a connection and the response it was answered with.
+// Responses corrupted by a fault have the fault as the outcome, and Response holds what was actually written.
+type Entry struct {
+	ConnectionID uint64    `json:"connectionId"`
+	Remote       string    `json:"remote"`
+	ReceivedAt   time.Time `json:"receivedAt"`
+	RespondedAt  time.Time `json:"respondedAt"`
+	LatencyMs    float64   `json:"latencyMs"`
+	Request      string    `json:"request"`
+	ParseError   string    `json:"parseError,omitempty"`
+	PaymentID    string    `json:"paymentId,omitempty"`
+	Amount       int       `json:"amount"`
+	Currency     string    `json:"currency,omitempty"`
+	Reference    string    `json:"reference,omitempty"`
+	Status       string    `json:"status"`
+	Reason       string    `json:"reason,omitempty"`
+	Code         string    `json:"code,omitempty"`
+	Response     string    `json:"response"`
+	Outcome      string    `json:"outcome"`
+}
+
+// Filter selects journal entries. Zero fields match every entry.
+type Filter struct {
+	PaymentID    string
+	ConnectionID uint64
+	Status       string
+	Since        time.Time
+	Until        time.Time
+	// Limit returns only the latest entries if positive.
+	Limit int
+}
+
+// matches returns true if the entry is selected by the filter.
+func (f Filter) matches(e Entry) bool {
+	switch {
+	case f.PaymentID != "" && e.PaymentID != f.PaymentID:
+		return false
+	case f.ConnectionID != 0 && e.ConnectionID != f.ConnectionID:
+		return false
+	case f.Status != "" && e.Status != f.Status:
+		return false
+	case !f.Since.IsZero() && e.ReceivedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.ReceivedAt.Before(f.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+// Journal appends entries to a file. When the file would exceed the maximum size, it is rotated:
+// it is renamed with the suffix `.1`, older files are shifted to the next suffix, and files beyond the maximum are removed.
+// It is safe for concurrent use.
+type Journal struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// Open opens the journal file configured in cfg for appending, creating it if it doesn't exist.
+func Open(cfg simulator.Config) (*Journal, error) {
+	j := &Journal{
+		path:     cfg.JournalFile,
+		maxSize:  cfg.JournalMaxSize,
+		maxFiles: cfg.JournalMaxFiles,
+	}
+
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Record appends the entry to the journal. Errors are logged, as they must not affect the response.
+func (j *Journal) Record(entry Entry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		slog.Error("Can't encode journal entry", "error", err)
+		return
+	}
+	b = append(b, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.maxSize > 0 && j.size > 0 && j.size+int64(len(b)) > j.maxSize {
+		if err := j.rotate(); err != nil {
+			slog.Error("Can't rotate journal", "error", err, "file", j.path)
+			return
+		}
+	}
+
+	n, err := j.file.Write(b)
+	j.size += int64(n)
+	if err != nil {
+		slog.Error("Can't write journal entry", "error", err, "file", j.path)
+	}
+}
+
+// Query returns the entries selected by the filter, oldest first, including entries in rotated files.
+// The files are only opened under the lock, and read afterwards, so queries don't block Record.
+func (j *Journal) Query(filter Filter) ([]Entry, error) {
+	files, err := j.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer closeSnapshot(files)
+
+	entries := []Entry{}
+	for _, f := range files {
+		entries, err = readEntries(f.file.Name(), io.LimitReader(f.file, f.size), filter, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+
+	return entries, nil
+}
+
+// snapshotFile is a journal file opened for a query, with its size when it was opened.
+type snapshotFile struct {
+	file *os.File
+	size int64
+}
+
+// snapshot opens the journal files, oldest first. Opened files can be read after they are rotated,
+// and the entries appended to the journal file afterwards are excluded by its size.
+func (j *Journal) snapshot() ([]snapshotFile, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var files []snapshotFile
+	for i := j.maxFiles; i >= 0; i-- {
+		file, err := os.Open(j.rotatedPath(i))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			closeSnapshot(files)
+			return nil, fmt.Errorf("can't read journal: %w", err)
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			file.Close() //nolint:errcheck
+			closeSnapshot(files)
+			return nil, fmt.Errorf("can't read journal: %w", err)
+		}
+
+		files = append(files, snapshotFile{file: file, size: info.Size()})
+	}
+
+	return files, nil
+}
+
+// closeSnapshot closes the files opened for a query.
+func closeSnapshot(files []snapshotFile) {
+	for _, f := range files {
+		f.file.Close() //nolint:errcheck
+	}
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// open opens the journal file for appending. It must be called with mu held.
+func (j *Journal) open() error {
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("can't open journal: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close() //nolint:errcheck
+		return fmt.Errorf("can't open journal: %w", err)
+	}
+
+	j.file = file
+	j.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts the rotated files, renames the journal file and opens a new one. It must be called with mu held.
+func (j *Journal) rotate() error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(j.rotatedPath(j.maxFiles)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for i := j.maxFiles - 1; i >= 0; i-- {
+		if err := os.Rename(j.rotatedPath(i), j.rotatedPath(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return j.open()
+}
+
+// rotatedPath returns the path of the i-th rotated file. The journal file itself is the 0th.
+func (j *Journal) rotatedPath(i int) string {
+	if i == 0 {
+		return j.path
+	}
+
+	return fmt.Sprintf("%s.%d", j.path, i)
+}
+
+// readEntries appends the entries read from the file at path selected by the filter to entries.
+func readEntries(path string, r io.Reader, filter Filter, entries []Entry) ([]Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("can't read journal %s: %w", path, err)
+		}
+
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read journal: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,176 @@
+package journal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_Journal_Query(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	entries := []Entry{
+		{ConnectionID: 1, ReceivedAt: start, Request: "PAYMENT|1", Amount: 1, Status: "ACCEPTED", Outcome: OutcomeDelivered},
+		{ConnectionID: 1, ReceivedAt: start.Add(time.Second), Request: "PAYMENT|abc-1|2|GBP", PaymentID: "abc-1", Amount: 2,
+			Currency: "GBP", Status: "REJECTED", Reason: "Insufficient funds", Outcome: OutcomeDelivered},
+		{ConnectionID: 2, ReceivedAt: start.Add(2 * time.Second), Request: "PAYMENT|abc-2|3|GBP", PaymentID: "abc-2", Amount: 3,
+			Currency: "GBP", Status: "DROPPED", Outcome: string(simulator.FaultClose)},
+		{ConnectionID: 2, ReceivedAt: start.Add(3 * time.Second), Request: "HELLO", ParseError: "Invalid request",
+			Status: "REJECTED", Reason: "Invalid request", Outcome: OutcomeDelivered},
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []Entry
+	}{
+		{
+			name:     "Every entry",
+			expected: entries,
+		},
+		{
+			name:     "Payment ID",
+			filter:   Filter{PaymentID: "abc-1"},
+			expected: entries[1:2],
+		},
+		{
+			name:     "Connection ID",
+			filter:   Filter{ConnectionID: 2},
+			expected: entries[2:],
+		},
+		{
+			name:     "Status",
+			filter:   Filter{Status: "REJECTED"},
+			expected: []Entry{entries[1], entries[3]},
+		},
+		{
+			name:     "Time range",
+			filter:   Filter{Since: start.Add(time.Second), Until: start.Add(3 * time.Second)},
+			expected: entries[1:3],
+		},
+		{
+			name:     "Limit returns latest entries",
+			filter:   Filter{Limit: 2},
+			expected: entries[2:],
+		},
+		{
+			name:     "No match",
+			filter:   Filter{PaymentID: "xyz"},
+			expected: []Entry{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			j, err := Open(simulator.Config{JournalFile: filepath.Join(t.TempDir(), "journal.jsonl")})
+			require.NoError(t, err)
+			defer j.Close() //nolint:errcheck
+
+			for _, entry := range entries {
+				j.Record(entry)
+			}
+
+			actual, err := j.Query(test.filter)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func Test_Journal_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	entry := func(i int) Entry {
+		return Entry{ConnectionID: 1, Request: fmt.Sprintf("PAYMENT|%d", i), Amount: i, Status: "ACCEPTED", Outcome: OutcomeDelivered}
+	}
+
+	j, err := Open(simulator.Config{JournalFile: path, JournalMaxSize: 300, JournalMaxFiles: 2})
+	require.NoError(t, err)
+	defer j.Close() //nolint:errcheck
+
+	for i := range 10 {
+		j.Record(entry(i))
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(300), name)
+	}
+	assert.NoFileExists(t, path+".3")
+
+	actual, err := j.Query(Filter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, actual)
+	assert.Less(t, len(actual), 10, "oldest entries are removed")
+	assert.Equal(t, entry(9), actual[len(actual)-1])
+
+	for i := 1; i < len(actual); i++ {
+		assert.Equal(t, actual[i-1].Amount+1, actual[i].Amount, "entries are in order")
+	}
+}
+
+func Test_Journal_snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	entry := func(i int) Entry {
+		return Entry{ConnectionID: 1, Request: fmt.Sprintf("PAYMENT|%d", i), Amount: i, Status: "ACCEPTED", Outcome: OutcomeDelivered}
+	}
+
+	j, err := Open(simulator.Config{JournalFile: path, JournalMaxSize: 300, JournalMaxFiles: 2})
+	require.NoError(t, err)
+	defer j.Close() //nolint:errcheck
+
+	for i := range 3 {
+		j.Record(entry(i))
+	}
+
+	files, err := j.snapshot()
+	require.NoError(t, err)
+
+	// Entries recorded while the files are read rotate the files, but don't change the snapshot.
+	for i := 3; i < 6; i++ {
+		j.Record(entry(i))
+	}
+
+	var actual []Entry
+	for _, f := range files {
+		actual, err = readEntries(f.file.Name(), io.LimitReader(f.file, f.size), Filter{}, actual)
+		require.NoError(t, err)
+		require.NoError(t, f.file.Close())
+	}
+
+	assert.Equal(t, []Entry{entry(0), entry(1), entry(2)}, actual)
+}
+
+func Test_Open_AppendsToExistingJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	for i := range 2 {
+		j, err := Open(simulator.Config{JournalFile: path})
+		require.NoError(t, err)
+
+		j.Record(Entry{Amount: i})
+		require.NoError(t, j.Close())
+	}
+
+	j, err := Open(simulator.Config{JournalFile: path})
+	require.NoError(t, err)
+	defer j.Close() //nolint:errcheck
+
+	actual, err := j.Query(Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Amount: 0}, {Amount: 1}}, actual)
+}
+
+func Test_Open_Error(t *testing.T) {
+	_, err := Open(simulator.Config{JournalFile: filepath.Join(t.TempDir(), "missing", "journal.jsonl")})
+	require.ErrorContains(t, err, "can't open journal")
+}
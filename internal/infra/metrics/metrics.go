@@ -0,0 +1,274 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets, in seconds, used for latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the Prometheus text exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+// NewRegistry creates a new Registry instance.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{metric: r.register(name, help, "counter", nil, labels)}
+}
+
+// NewGauge registers a gauge with the given label names.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{metric: r.register(name, help, "gauge", nil, labels)}
+}
+
+// NewHistogram registers a histogram with the given upper bounds of the buckets and label names.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return &Histogram{metric: r.register(name, help, "histogram", buckets, labels)}
+}
+
+// register adds a new metric. It panics if a metric with the same name is already registered,
+// as that is a programming error.
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.metrics {
+		if m.name == name {
+			panic(fmt.Sprintf("metric %q is already registered", name))
+		}
+	}
+
+	m := &metric{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+
+	r.metrics = append(r.metrics, m)
+
+	return m
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format, sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	slices.SortFunc(metrics, func(a, b *metric) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	var sb strings.Builder
+	for _, m := range metrics {
+		m.write(&sb)
+	}
+
+	n, err := io.WriteString(w, sb.String())
+
+	return int64(n), err
+}
+
+// ServeHTTP writes the metrics as the response.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w) //nolint:errcheck
+}
+
+// Counter is a metric that only increases.
+type Counter struct {
+	metric *metric
+}
+
+// Inc increments the counter with the given label values by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter with the given label values by v.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.metric.update(labelValues, func(s *series) {
+		s.value += v
+	})
+}
+
+// Gauge is a metric that can go up and down.
+type Gauge struct {
+	metric *metric
+}
+
+// Inc increments the gauge with the given label values by 1.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge with the given label values by 1.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Add changes the gauge with the given label values by v.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.metric.update(labelValues, func(s *series) {
+		s.value += v
+	})
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	metric *metric
+}
+
+// Observe adds the observation to the histogram with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.metric.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.metric.buckets))
+		}
+
+		for i, upper := range h.metric.buckets {
+			if v <= upper {
+				s.counts[i]++
+			}
+		}
+
+		s.value += v
+		s.count++
+	})
+}
+
+// metric holds every series of a metric, keyed by label values.
+type metric struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+// series is a metric with specific label values.
+// For histograms, value is the sum of observations and counts are the cumulative bucket counts.
+type series struct {
+	labelValues []string
+	value       float64
+	count       uint64
+	counts      []uint64
+}
+
+// update applies f to the series with the label values, creating it if needed.
+// It panics if the number of label values doesn't match the label names, as that is a programming error.
+func (m *metric) update(labelValues []string, f func(*series)) {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %q has %d labels, got %d values", m.name, len(m.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		m.series[key] = s
+	}
+
+	f(s)
+}
+
+// write writes the metric in the Prometheus text exposition format, with series sorted by label values.
+func (m *metric) write(sb *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(sb, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(sb, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+
+		if m.kind != "histogram" {
+			writeSample(sb, m.name, m.labels, s.labelValues, "", "", s.value)
+			continue
+		}
+
+		for i, upper := range m.buckets {
+			writeSample(sb, m.name+"_bucket", m.labels, s.labelValues, "le", formatFloat(upper), float64(s.counts[i]))
+		}
+		writeSample(sb, m.name+"_bucket", m.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(sb, m.name+"_sum", m.labels, s.labelValues, "", "", s.value)
+		writeSample(sb, m.name+"_count", m.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// writeSample writes a single sample line. If extraLabel is set, it is added after the labels of the metric.
+func writeSample(sb *strings.Builder, name string, labels, labelValues []string, extraLabel, extraValue string, v float64) {
+	sb.WriteString(name)
+
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, label+`="`+escapeLabelValue(labelValues[i])+`"`)
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+`="`+extraValue+`"`)
+	}
+
+	if len(pairs) > 0 {
+		sb.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	sb.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+// escapeLabelValue escapes the label value as required by the text exposition format.
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
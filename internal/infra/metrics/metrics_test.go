@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Registry_WriteTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   func(*Registry)
+		expected string
+	}{
+		{
+			name: "Counter without labels",
+			record: func(r *Registry) {
+				c := r.NewCounter("test_total", "Test counter.")
+				c.Inc()
+				c.Add(2)
+			},
+			expected: "# HELP test_total Test counter.\n" +
+				"# TYPE test_total counter\n" +
+				"test_total 3\n",
+		},
+		{
+			name: "Counter without observations",
+			record: func(r *Registry) {
+				r.NewCounter("test_total", "Test counter.", "status")
+			},
+			expected: "# HELP test_total Test counter.\n" +
+				"# TYPE test_total counter\n",
+		},
+		{
+			name: "Counter with labels sorted by label values",
+			record: func(r *Registry) {
+				c := r.NewCounter("test_total", "Test counter.", "status", "reason")
+				c.Inc("REJECTED", "Invalid amount")
+				c.Inc("ACCEPTED", "Transaction processed")
+				c.Inc("REJECTED", "Invalid amount")
+			},
+			expected: "# HELP test_total Test counter.\n" +
+				"# TYPE test_total counter\n" +
+				`test_total{status="ACCEPTED",reason="Transaction processed"} 1` + "\n" +
+				`test_total{status="REJECTED",reason="Invalid amount"} 2` + "\n",
+		},
+		{
+			name: "Escaped help and label values",
+			record: func(r *Registry) {
+				r.NewCounter("test_total", "Test\\counter\nwith lines.", "reason").Inc("say \"hi\"\\\n")
+			},
+			expected: "# HELP test_total Test\\\\counter\\nwith lines.\n" +
+				"# TYPE test_total counter\n" +
+				`test_total{reason="say \"hi\"\\\n"} 1` + "\n",
+		},
+		{
+			name: "Gauge",
+			record: func(r *Registry) {
+				g := r.NewGauge("test_in_flight", "Test gauge.")
+				g.Inc()
+				g.Inc()
+				g.Dec()
+				g.Add(0.5)
+			},
+			expected: "# HELP test_in_flight Test gauge.\n" +
+				"# TYPE test_in_flight gauge\n" +
+				"test_in_flight 1.5\n",
+		},
+		{
+			name: "Histogram",
+			record: func(r *Registry) {
+				h := r.NewHistogram("test_seconds", "Test histogram.", []float64{1, 0.1}, "status")
+				h.Observe(0.05, "ACCEPTED")
+				h.Observe(0.1, "ACCEPTED")
+				h.Observe(0.5, "ACCEPTED")
+				h.Observe(2, "ACCEPTED")
+			},
+			expected: "# HELP test_seconds Test histogram.\n" +
+				"# TYPE test_seconds histogram\n" +
+				`test_seconds_bucket{status="ACCEPTED",le="0.1"} 2` + "\n" +
+				`test_seconds_bucket{status="ACCEPTED",le="1"} 3` + "\n" +
+				`test_seconds_bucket{status="ACCEPTED",le="+Inf"} 4` + "\n" +
+				`test_seconds_sum{status="ACCEPTED"} 2.65` + "\n" +
+				`test_seconds_count{status="ACCEPTED"} 4` + "\n",
+		},
+		{
+			name: "Metrics sorted by name",
+			record: func(r *Registry) {
+				r.NewGauge("b", "B.").Inc()
+				r.NewGauge("a", "A.").Inc()
+			},
+			expected: "# HELP a A.\n# TYPE a gauge\na 1\n" +
+				"# HELP b B.\n# TYPE b gauge\nb 1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := NewRegistry()
+			test.record(registry)
+
+			var sb strings.Builder
+			n, err := registry.WriteTo(&sb)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, sb.String())
+			assert.Equal(t, int64(len(test.expected)), n)
+		})
+	}
+}
+
+func Test_Registry_Panics(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("test_total", "Test counter.", "status")
+
+	assert.PanicsWithValue(t, `metric "test_total" is already registered`, func() {
+		registry.NewGauge("test_total", "Test gauge.")
+	})
+
+	assert.PanicsWithValue(t, `metric "test_total" has 1 labels, got 2 values`, func() {
+		counter.Inc("ACCEPTED", "Transaction processed")
+	})
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// Service defines the interface for processing payments.
+type Service interface {
+	Process(ctx context.Context, payment simulator.Payment) error
+}
+
+// ServiceMetrics records the processing latency of services in the service chain.
+type ServiceMetrics struct {
+	duration *Histogram
+	clock    clock.Clock
+}
+
+// NewServiceMetrics registers the metrics of the service chain to the registry.
+func NewServiceMetrics(registry *Registry, clock clock.Clock) *ServiceMetrics {
+	return &ServiceMetrics{
+		duration: registry.NewHistogram("simulator_service_duration_seconds",
+			"Time spent processing payments by service and result.", DefaultBuckets, "service", "result"),
+		clock: clock,
+	}
+}
+
+// Instrument returns a service recording the latency of the given service under the given name.
+func (m *ServiceMetrics) Instrument(name string, service Service) *InstrumentedService {
+	return &InstrumentedService{
+		name:    name,
+		service: service,
+		metrics: m,
+	}
+}
+
+// InstrumentedService records the latency and result of each payment processed by the underlying service.
+type InstrumentedService struct {
+	name    string
+	service Service
+	metrics *ServiceMetrics
+}
+
+// Process processes the payment with the underlying service and records how long it took.
+func (s *InstrumentedService) Process(ctx context.Context, payment simulator.Payment) error {
+	start := s.metrics.clock.Now()
+
+	err := s.service.Process(ctx, payment)
+
+	s.metrics.duration.Observe(s.metrics.clock.Since(start).Seconds(), s.name, result(err))
+
+	return err
+}
+
+// result returns the result label of the error returned by a service.
+// Faults only corrupt the response, so the result of the error they wrap is returned.
+func result(err error) string {
+	var faultErr simulator.FaultError
+	if errors.As(err, &faultErr) {
+		return result(faultErr.Err)
+	}
+
+	switch {
+	case err == nil:
+		return "accepted"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	case errors.Is(err, simulator.ErrDropConnection):
+		return "dropped"
+	default:
+		return "rejected"
+	}
+}
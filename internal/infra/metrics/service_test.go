@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_InstrumentedService(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedResult string
+	}{
+		{
+			name:           "Accepted",
+			expectedResult: "accepted",
+		},
+		{
+			name:           "Rejected",
+			err:            simulator.RejectionError{Reason: "Insufficient funds"},
+			expectedResult: "rejected",
+		},
+		{
+			name:           "Cancelled",
+			err:            fmt.Errorf("wait: %w", context.Canceled),
+			expectedResult: "cancelled",
+		},
+		{
+			name:           "Dropped",
+			err:            simulator.ErrDropConnection,
+			expectedResult: "dropped",
+		},
+		{
+			name:           "Accepted with fault",
+			err:            simulator.FaultError{Fault: simulator.FaultTruncate},
+			expectedResult: "accepted",
+		},
+		{
+			name:           "Rejected with fault",
+			err:            simulator.FaultError{Fault: simulator.FaultStall, Err: errors.New("busy")},
+			expectedResult: "rejected",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockClock := clock.NewMock()
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().
+				Process(mock.Anything, simulator.Payment{Amount: 1}).
+				RunAndReturn(func(context.Context, simulator.Payment) error {
+					mockClock.Add(300 * time.Millisecond)
+					return test.err
+				})
+
+			registry := NewRegistry()
+			service := NewServiceMetrics(registry, mockClock).Instrument("processing", mockService)
+
+			err := service.Process(context.Background(), simulator.Payment{Amount: 1})
+			assert.Equal(t, test.err, err)
+
+			var sb strings.Builder
+			_, err = registry.WriteTo(&sb)
+			require.NoError(t, err)
+
+			labels := fmt.Sprintf(`service="processing",result=%q`, test.expectedResult)
+			assert.Contains(t, sb.String(), "simulator_service_duration_seconds_bucket{"+labels+`,le="0.25"} 0`)
+			assert.Contains(t, sb.String(), "simulator_service_duration_seconds_bucket{"+labels+`,le="0.5"} 1`)
+			assert.Contains(t, sb.String(), "simulator_service_duration_seconds_sum{"+labels+"} 0.3")
+			assert.Contains(t, sb.String(), "simulator_service_duration_seconds_count{"+labels+"} 1")
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	errIdleTimeout  = errors.New("idle timeout")
+	errReadTimeout  = errors.New("read timeout")
+	errWriteTimeout = errors.New("write timeout")
+)
+
+// maxLineLength is the maximum length of a request line, including the newline.
+const maxLineLength = bufio.MaxScanTokenSize
+
+// setDeadline sets the deadline with the setter to timeout from now. A timeout of 0 clears the deadline.
+// Deadlines are enforced by the network poller, so they are based on the wall clock instead of the transport clock.
+func setDeadline(set func(time.Time) error, timeout time.Duration) error {
+	if timeout <= 0 {
+		return set(time.Time{})
+	}
+
+	return set(time.Now().Add(timeout))
+}
+
+// readLine reads the next request line without the line ending. It waits up to the idle timeout for the line to start,
+// and up to the read timeout for the started line to be completed. A last line without a newline is returned before io.EOF.
+func (t *Transport) readLine(conn net.Conn, reader *bufio.Reader) (string, error) {
+	if err := setDeadline(conn.SetReadDeadline, t.cfg.ServerIdleTimeout); err != nil {
+		return "", err
+	}
+
+	if _, err := reader.Peek(1); err != nil {
+		return "", timeoutError(err, errIdleTimeout)
+	}
+
+	if err := setDeadline(conn.SetReadDeadline, t.cfg.ServerReadTimeout); err != nil {
+		return "", err
+	}
+
+	b, err := reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("request line is longer than %d bytes", maxLineLength)
+	}
+	if err != nil && (!errors.Is(err, io.EOF) || len(b) == 0) {
+		return "", timeoutError(err, errReadTimeout)
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+}
+
+// timeoutError wraps err with the timeout if the deadline of the connection is exceeded.
+func timeoutError(err error, timeout error) error {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: %w", timeout, err)
+	}
+
+	return err
+}
+
+// closeReason returns the timeout that caused the error, or "" if the error isn't caused by a timeout.
+func closeReason(err error) string {
+	for _, timeout := range []error{errIdleTimeout, errReadTimeout, errWriteTimeout} {
+		if errors.Is(err, timeout) {
+			return timeout.Error()
+		}
+	}
+
+	return ""
+}
+
+// logConnectionError logs the error that ended the connection and records it if it is caused by a timeout.
+func (t *Transport) logConnectionError(conn net.Conn, err error) {
+	if reason := closeReason(err); reason != "" {
+		t.metrics.connectionTimeouts.Inc(reason)
+		slog.Info("Closing timed out connection", "reason", reason, "remote", conn.RemoteAddr())
+		return
+	}
+
+	if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		slog.Error("Error reading from connection", "error", err, "remote", conn.RemoteAddr())
+	}
+}
@@ -0,0 +1,120 @@
+package tcp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
+)
+
+func Test_readLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           simulator.Config
+		input         string
+		close         bool
+		expectedLine  string
+		expectedError error
+	}{
+		{
+			name:         "Line",
+			input:        "PAYMENT|1\nPAYMENT|2\n",
+			expectedLine: "PAYMENT|1",
+		},
+		{
+			name:         "Line with carriage return",
+			input:        "PAYMENT|1\r\n",
+			expectedLine: "PAYMENT|1",
+		},
+		{
+			name:         "Last line without newline",
+			input:        "PAYMENT|1",
+			close:        true,
+			expectedLine: "PAYMENT|1",
+		},
+		{
+			name:          "Closed connection",
+			close:         true,
+			expectedError: io.EOF,
+		},
+		{
+			name:          "Idle timeout",
+			cfg:           simulator.Config{ServerIdleTimeout: 10 * time.Millisecond},
+			expectedError: errIdleTimeout,
+		},
+		{
+			name:          "Read timeout",
+			cfg:           simulator.Config{ServerIdleTimeout: time.Second, ServerReadTimeout: 10 * time.Millisecond},
+			input:         "PAYMENT|1",
+			expectedError: errReadTimeout,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			server, client := net.Pipe()
+			defer server.Close() //nolint:errcheck
+
+			go func() {
+				client.Write([]byte(test.input)) //nolint:errcheck
+				if test.close {
+					client.Close() //nolint:errcheck
+				}
+			}()
+			defer client.Close() //nolint:errcheck
+
+			transport := NewTransport(test.cfg, nil, clock.NewMock(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+			line, err := transport.readLine(server, bufio.NewReaderSize(server, maxLineLength))
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedLine, line)
+		})
+	}
+}
+
+func Test_readLine_TooLong(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server, client := net.Pipe()
+	defer server.Close() //nolint:errcheck
+	defer client.Close() //nolint:errcheck
+
+	go client.Write([]byte(strings.Repeat("1", maxLineLength+1))) //nolint:errcheck
+
+	transport := NewTransport(simulator.Config{}, nil, clock.NewMock(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+	_, err := transport.readLine(server, bufio.NewReaderSize(server, maxLineLength))
+	require.EqualError(t, err, "request line is longer than 65536 bytes")
+}
+
+func Test_write_Timeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server, client := net.Pipe()
+	defer server.Close() //nolint:errcheck
+	defer client.Close() //nolint:errcheck
+
+	registry := metrics.NewRegistry()
+	transport := NewTransport(simulator.Config{ServerWriteTimeout: 10 * time.Millisecond}, nil, clock.NewMock(), registry, nil, nil, nil, nil, nil)
+
+	err := transport.write(server, "PAYMENT|1", "RESPONSE|ACCEPTED|Transaction processed\n")
+	require.ErrorIs(t, err, errWriteTimeout)
+
+	waitForMetric(t, registry, `simulator_connection_timeouts_total{reason="write timeout"} 1`)
+}
@@ -0,0 +1,49 @@
+package tcp
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// faultInjector randomly picks faults to corrupt responses with the configured probability.
+type faultInjector struct {
+	mu          sync.RWMutex
+	probability float64
+	faults      []simulator.Fault
+}
+
+// newFaultInjector creates a new faultInjector instance.
+func newFaultInjector(cfg simulator.Config) *faultInjector {
+	f := &faultInjector{}
+	f.set(cfg.FaultProbability, cfg.FaultKinds)
+
+	return f
+}
+
+// set changes the probability and the faults picked from.
+func (f *faultInjector) set(probability float64, faults []simulator.Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.probability = probability
+	f.faults = slices.Clone(faults)
+}
+
+// pick returns a random fault with the configured probability, or an empty fault if the response must not be corrupted.
+func (f *faultInjector) pick() simulator.Fault {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.probability <= 0 || len(f.faults) == 0 {
+		return ""
+	}
+
+	if rand.Float64() >= f.probability { //nolint:gosec // Fault injection doesn't need a secure random number generator.
+		return ""
+	}
+
+	return f.faults[rand.IntN(len(f.faults))] //nolint:gosec // Fault injection doesn't need a secure random number generator.
+}
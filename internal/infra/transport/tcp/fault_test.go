@@ -0,0 +1,54 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_faultInjector_pick(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        simulator.Config
+		assertFunc func(*testing.T, simulator.Fault)
+	}{
+		{
+			name: "Disabled",
+			cfg: simulator.Config{
+				FaultKinds: simulator.Faults(),
+			},
+			assertFunc: func(t *testing.T, fault simulator.Fault) {
+				assert.Empty(t, fault)
+			},
+		},
+		{
+			name: "Without faults",
+			cfg: simulator.Config{
+				FaultProbability: 1,
+			},
+			assertFunc: func(t *testing.T, fault simulator.Fault) {
+				assert.Empty(t, fault)
+			},
+		},
+		{
+			name: "Always",
+			cfg: simulator.Config{
+				FaultProbability: 1,
+				FaultKinds:       []simulator.Fault{simulator.FaultTruncate, simulator.FaultStall},
+			},
+			assertFunc: func(t *testing.T, fault simulator.Fault) {
+				assert.Contains(t, []simulator.Fault{simulator.FaultTruncate, simulator.FaultStall}, fault)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			injector := newFaultInjector(test.cfg)
+			for range 100 {
+				test.assertFunc(t, injector.pick())
+			}
+		})
+	}
+}
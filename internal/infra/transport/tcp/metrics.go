@@ -0,0 +1,106 @@
+package tcp
+
+import (
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
+)
+
+// droppedStatus is the status label of requests answered by closing the connection without a response.
+const droppedStatus = "DROPPED"
+
+// transportMetrics holds the metrics recorded by the transport.
+type transportMetrics struct {
+	connectionsAccepted   *metrics.Counter
+	connectionsClosed     *metrics.Counter
+	requests              *metrics.Counter
+	parseFailures         *metrics.Counter
+	duration              *metrics.Histogram
+	inFlight              *metrics.Gauge
+	shutdownCancellations *metrics.Counter
+	faults                *metrics.Counter
+	handshakeFailures     *metrics.Counter
+	connectionsOpen       *metrics.Gauge
+	connectionsRejected   *metrics.Counter
+	connectionsRefused    *metrics.Counter
+	connectionsQueued     *metrics.Gauge
+	workersBusy           *metrics.Gauge
+	queueLength           *metrics.Gauge
+	queueWait             *metrics.Histogram
+	queueTimeouts         *metrics.Counter
+	connectionTimeouts    *metrics.Counter
+	settlementsPending    *metrics.Gauge
+	throttled             *metrics.Counter
+}
+
+// newTransportMetrics registers the metrics of the transport.
+func newTransportMetrics(registry *metrics.Registry) *transportMetrics {
+	return &transportMetrics{
+		connectionsAccepted: registry.NewCounter("simulator_connections_accepted_total",
+			"Number of accepted connections."),
+		connectionsClosed: registry.NewCounter("simulator_connections_closed_total",
+			"Number of closed connections."),
+		requests: registry.NewCounter("simulator_requests_total",
+			"Number of answered requests by response status and reason.", "status", "reason"),
+		parseFailures: registry.NewCounter("simulator_parse_failures_total",
+			"Number of requests that couldn't be parsed by reason.", "reason"),
+		duration: registry.NewHistogram("simulator_request_duration_seconds",
+			"Time from receiving a request until answering it by response status.", metrics.DefaultBuckets, "status"),
+		inFlight: registry.NewGauge("simulator_requests_in_flight",
+			"Number of requests being processed."),
+		shutdownCancellations: registry.NewCounter("simulator_shutdown_cancellations_total",
+			"Number of requests cancelled because the grace period of a graceful shutdown is finished."),
+		faults: registry.NewCounter("simulator_faults_total",
+			"Number of responses corrupted by a fault.", "fault"),
+		handshakeFailures: registry.NewCounter("simulator_tls_handshake_failures_total",
+			"Number of connections closed because the TLS handshake failed."),
+		connectionsOpen: registry.NewGauge("simulator_connections_open",
+			"Number of connections being served."),
+		connectionsRejected: registry.NewCounter("simulator_connections_rejected_total",
+			"Number of connections closed because the maximum number of connections are open."),
+		connectionsRefused: registry.NewCounter("simulator_connections_refused_total",
+			"Number of connections closed because the scheme is closed."),
+		connectionsQueued: registry.NewGauge("simulator_connections_queued",
+			"Number of connections waiting to be served because the maximum number of connections are open."),
+		workersBusy: registry.NewGauge("simulator_workers_busy",
+			"Number of workers processing a request."),
+		queueLength: registry.NewGauge("simulator_worker_queue_length",
+			"Number of requests waiting for a free worker."),
+		queueWait: registry.NewHistogram("simulator_worker_queue_wait_seconds",
+			"Time requests waited for a free worker.", metrics.DefaultBuckets),
+		queueTimeouts: registry.NewCounter("simulator_worker_queue_timeouts_total",
+			"Number of requests rejected because no worker became free before the queue timeout."),
+		connectionTimeouts: registry.NewCounter("simulator_connection_timeouts_total",
+			"Number of connections closed because a timeout was reached by reason.", "reason"),
+		settlementsPending: registry.NewGauge("simulator_settlements_pending",
+			"Number of payments acknowledged as pending whose settlement is not notified yet."),
+		throttled: registry.NewCounter("simulator_throttled_total",
+			"Number of requests exceeding a rate limit by limit.", "limit"),
+	}
+}
+
+// observe records the response to a request, which took seconds to be answered.
+// Responses corrupted by closing the connection are recorded with the dropped status.
+func (m *transportMetrics) observe(r response, seconds float64) {
+	status, reason := statusAndReason(r)
+
+	if r.fault != "" {
+		m.faults.Inc(string(r.fault))
+	}
+
+	if r.cancelled {
+		m.shutdownCancellations.Inc()
+	}
+
+	m.requests.Inc(status, reason)
+	m.duration.Observe(seconds, status)
+}
+
+// statusAndReason returns the status and reason of the response as they are reported.
+// Responses corrupted by closing the connection have the dropped status and no reason.
+func statusAndReason(r response) (string, string) {
+	if r.fault == simulator.FaultClose {
+		return droppedStatus, ""
+	}
+
+	return r.status.String(), capitalizeFirstLetter(r.reason)
+}
@@ -2,7 +2,13 @@
 
 package tcp
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	simulator "github.com/ormanli/form3-te/internal/app/simulator"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockService is an autogenerated mock type for the Service type
 type MockService struct {
@@ -17,17 +23,17 @@ func (_m *MockService) EXPECT() *MockService_Expecter {
 	return &MockService_Expecter{mock: &_m.Mock}
 }
 
-// Process provides a mock function with given fields: amount
-func (_m *MockService) Process(amount int) error {
-	ret := _m.Called(amount)
+// Process provides a mock function with given fields: ctx, payment
+func (_m *MockService) Process(ctx context.Context, payment simulator.Payment) error {
+	ret := _m.Called(ctx, payment)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Process")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int) error); ok {
-		r0 = rf(amount)
+	if rf, ok := ret.Get(0).(func(context.Context, simulator.Payment) error); ok {
+		r0 = rf(ctx, payment)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -41,14 +47,15 @@ type MockService_Process_Call struct {
 }
 
 // Process is a helper method to define mock.On call
-//   - amount int
-func (_e *MockService_Expecter) Process(amount interface{}) *MockService_Process_Call {
-	return &MockService_Process_Call{Call: _e.mock.On("Process", amount)}
+//   - ctx context.Context
+//   - payment simulator.Payment
+func (_e *MockService_Expecter) Process(ctx interface{}, payment interface{}) *MockService_Process_Call {
+	return &MockService_Process_Call{Call: _e.mock.On("Process", ctx, payment)}
 }
 
-func (_c *MockService_Process_Call) Run(run func(amount int)) *MockService_Process_Call {
+func (_c *MockService_Process_Call) Run(run func(ctx context.Context, payment simulator.Payment)) *MockService_Process_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int))
+		run(args[0].(context.Context), args[1].(simulator.Payment))
 	})
 	return _c
 }
@@ -58,7 +65,7 @@ func (_c *MockService_Process_Call) Return(_a0 error) *MockService_Process_Call
 	return _c
 }
 
-func (_c *MockService_Process_Call) RunAndReturn(run func(int) error) *MockService_Process_Call {
+func (_c *MockService_Process_Call) RunAndReturn(run func(context.Context, simulator.Payment) error) *MockService_Process_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -68,7 +75,8 @@ func (_c *MockService_Process_Call) RunAndReturn(run func(int) error) *MockServi
 func NewMockService(t interface {
 	mock.TestingT
 	Cleanup(func())
-}) *MockService {
+},
+) *MockService {
 	mock := &MockService{}
 	mock.Mock.Test(t)
 
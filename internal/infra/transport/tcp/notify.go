@@ -0,0 +1,164 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/journal"
+)
+
+// syncConn serializes writes to a connection, so notifications don't interleave with responses written by its handler.
+type syncConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+// Write writes b to the connection once no other write is in progress.
+func (c *syncConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.Conn.Write(b)
+}
+
+// registration is a connection registered to receive the notifications of its client.
+type registration struct {
+	conn         net.Conn
+	connectionID uint64
+	client       string
+}
+
+// notifier keeps track of the connections registered to receive settlement notifications.
+type notifier struct {
+	mu            sync.Mutex
+	registrations []registration
+}
+
+// register registers the connection to receive the notifications of the payments of its client.
+func (n *notifier) register(conn net.Conn, connectionID uint64, client string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.registrations = append(n.registrations, registration{conn: conn, connectionID: connectionID, client: client})
+}
+
+// unregister stops sending notifications to the connection, which is closed.
+func (n *notifier) unregister(conn net.Conn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.registrations = slices.DeleteFunc(n.registrations, func(r registration) bool {
+		return r.conn == conn
+	})
+}
+
+// target returns the connection the notification of a payment of the client is sent to, which is the latest connection
+// registered by the client, or the given connection the payment was received on if the client didn't register a connection.
+func (n *notifier) target(conn net.Conn, connectionID uint64, client string) (net.Conn, uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, r := range slices.Backward(n.registrations) {
+		if r.client == client {
+			return r.conn, r.connectionID
+		}
+	}
+
+	return conn, connectionID
+}
+
+// clientKey identifies the client of the connection, by the subject of its certificate if it is authenticated,
+// or by its remote IP otherwise, as the connections of a client have different ports.
+func clientKey(ctx context.Context, conn net.Conn) string {
+	if subject, ok := simulator.ClientSubject(ctx); ok {
+		return "subject:" + subject
+	}
+
+	return "ip:" + remoteIP(conn.RemoteAddr())
+}
+
+// remoteIP returns the IP of the remote address, without its port.
+func remoteIP(remote net.Addr) string {
+	ip := remote.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	return ip
+}
+
+// acknowledge answers an extended payment with the pending status, and settles it in the background.
+// The payment is recorded as pending first, so a status enquiry sent after the acknowledgement finds it.
+// It returns false if the connection must be closed.
+func (t *Transport) acknowledge(ctx context.Context, x exchange) bool {
+	t.connections.addPending()
+	t.metrics.settlementsPending.Inc()
+
+	release := func() {}
+	if t.statuses != nil {
+		release = t.statuses.RecordPending(x.request.payment())
+	}
+
+	t.wg.Add(1)
+	go t.settle(ctx, x, release)
+
+	return t.deliver(x, newResponse(x.request, Pending, pendingReason))
+}
+
+// settle processes a payment acknowledged as pending, and notifies its settlement.
+// If the grace period of a graceful shutdown is finished before the payment is processed, a cancelled notification is sent.
+// release is called once the payment is processed, to remove its pending status if it wasn't recorded by the service.
+func (t *Transport) settle(ctx context.Context, x exchange, release func()) {
+	defer t.wg.Done()
+	defer t.connections.donePending()
+	defer t.metrics.settlementsPending.Dec()
+
+	responseChan := make(chan response, 1)
+
+	err := t.workers.submit(ctx, t.cfg.ServerWorkerQueueTimeout, func() {
+		responseChan <- t.handleRequest(ctx, x.request)
+	})
+
+	var r response
+	switch {
+	case errors.Is(err, errQueueTimeout):
+		r = newErrorResponse(x.request, simulator.ErrServerBusy)
+	case err != nil:
+		r = newCancelledResponse(x.request)
+	default:
+		select {
+		case <-t.handlingCtx.Done():
+			r = newCancelledResponse(x.request)
+		case r = <-responseChan:
+		}
+	}
+
+	release()
+
+	t.notify(ctx, x, r)
+}
+
+// notify sends the response as a notification to the connection returned by the notifier for the client of the payment.
+// Accepted payments are notified as settled. The connection is closed if the notification is corrupted by a fault.
+func (t *Transport) notify(ctx context.Context, x exchange, r response) {
+	x.conn, x.connectionID = t.notifier.target(x.conn, x.connectionID, clientKey(ctx, x.conn))
+
+	r.notification = true
+	if r.status == Accepted {
+		r.status = Settled
+	}
+
+	if r.cancelled {
+		t.metrics.observe(r, t.clock.Since(x.received).Seconds())
+		t.send(x, r, r.format(t.cfg.ServerReasonCodes)+"\n", journal.OutcomeCancelled) //nolint:errcheck // The connection is closed by the graceful shutdown.
+		return
+	}
+
+	if !t.deliver(x, r) {
+		x.conn.Close() //nolint:errcheck // The connection is closed because the notification is corrupted.
+	}
+}
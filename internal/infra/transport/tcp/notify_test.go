@@ -0,0 +1,74 @@
+package tcp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_notifier_target(t *testing.T) {
+	paymentConn, _ := net.Pipe()
+	firstConn, _ := net.Pipe()
+	secondConn, _ := net.Pipe()
+	otherConn, _ := net.Pipe()
+
+	tests := []struct {
+		name               string
+		registrations      []registration
+		client             string
+		expectedConn       net.Conn
+		expectedConnection uint64
+	}{
+		{
+			name:               "No registration",
+			client:             "ip:127.0.0.1",
+			expectedConn:       paymentConn,
+			expectedConnection: 1,
+		},
+		{
+			name: "Latest registration of the client",
+			registrations: []registration{
+				{conn: firstConn, connectionID: 2, client: "ip:127.0.0.1"},
+				{conn: secondConn, connectionID: 3, client: "ip:127.0.0.1"},
+				{conn: otherConn, connectionID: 4, client: "ip:10.0.0.1"},
+			},
+			client:             "ip:127.0.0.1",
+			expectedConn:       secondConn,
+			expectedConnection: 3,
+		},
+		{
+			name: "Registration of another client",
+			registrations: []registration{
+				{conn: otherConn, connectionID: 4, client: "subject:CN=other"},
+			},
+			client:             "subject:CN=client",
+			expectedConn:       paymentConn,
+			expectedConnection: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := &notifier{}
+			for _, r := range test.registrations {
+				n.register(r.conn, r.connectionID, r.client)
+			}
+
+			conn, connectionID := n.target(paymentConn, 1, test.client)
+			assert.Equal(t, test.expectedConn, conn)
+			assert.Equal(t, test.expectedConnection, connectionID)
+		})
+	}
+}
+
+func Test_remoteIP(t *testing.T) {
+	assert.Equal(t, "127.0.0.1", remoteIP(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}))
+	assert.Equal(t, "::1", remoteIP(&net.TCPAddr{IP: net.IPv6loopback, Port: 4000}))
+	assert.Equal(t, "pipe", remoteIP(pipeAddr{}))
+}
+
+// pipeAddr is an address without port.
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -0,0 +1,100 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// errQueueTimeout is returned when no worker becomes free before the queue timeout is reached.
+var errQueueTimeout = errors.New("queue timeout reached")
+
+// workerPool runs jobs with a fixed number of workers. Jobs submitted while every worker is busy wait in the queue.
+// If the pool has no workers, every job runs in its own goroutine.
+type workerPool struct {
+	workers int
+	jobs    chan func()
+	wg      sync.WaitGroup
+	metrics *transportMetrics
+	clock   clock.Clock
+}
+
+// newWorkerPool creates a new workerPool instance. Workers are started by start.
+func newWorkerPool(workers int, metrics *transportMetrics, clock clock.Clock) *workerPool {
+	return &workerPool{
+		workers: workers,
+		jobs:    make(chan func()),
+		metrics: metrics,
+		clock:   clock,
+	}
+}
+
+// start starts the workers, which run jobs until stop is called.
+func (p *workerPool) start() {
+	for range p.workers {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+
+			for job := range p.jobs {
+				p.metrics.workersBusy.Inc()
+				job()
+				p.metrics.workersBusy.Dec()
+			}
+		}()
+	}
+}
+
+// submit hands the job to a free worker. It waits until a worker is free, the timeout is reached or the context is done.
+// A timeout of 0 waits until a worker is free or the context is done.
+func (p *workerPool) submit(ctx context.Context, timeout time.Duration, job func()) error {
+	if p.workers <= 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			job()
+		}()
+
+		return nil
+	}
+
+	queued := p.clock.Now()
+	defer func() {
+		p.metrics.queueWait.Observe(p.clock.Since(queued).Seconds())
+	}()
+
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+	}
+
+	p.metrics.queueLength.Inc()
+	defer p.metrics.queueLength.Dec()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := p.clock.Timer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-expired:
+		p.metrics.queueTimeouts.Inc()
+		return errQueueTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop waits for the submitted jobs to finish and stops the workers. Jobs must not be submitted afterwards.
+func (p *workerPool) stop() {
+	close(p.jobs)
+	p.wg.Wait()
+}
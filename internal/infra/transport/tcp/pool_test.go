@@ -0,0 +1,136 @@
+package tcp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/internal/infra/metrics"
+)
+
+func Test_workerPool(t *testing.T) {
+	tests := []struct {
+		name            string
+		workers         int
+		jobs            int
+		expectedRunning int32
+		expectedQueued  []string
+		expectedMetrics []string
+	}{
+		{
+			name:            "Bounded workers",
+			workers:         2,
+			jobs:            5,
+			expectedRunning: 2,
+			expectedQueued:  []string{"simulator_workers_busy 2", "simulator_worker_queue_length 3"},
+			expectedMetrics: []string{"simulator_workers_busy 0", "simulator_worker_queue_length 0", "simulator_worker_queue_wait_seconds_count 5"},
+		},
+		{
+			name:            "Unlimited workers",
+			workers:         0,
+			jobs:            5,
+			expectedRunning: 5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			registry := metrics.NewRegistry()
+			pool := newWorkerPool(test.workers, newTransportMetrics(registry), clock.NewMock())
+			pool.start()
+
+			release := make(chan struct{})
+
+			var running, maxRunning atomic.Int32
+			job := func() {
+				n := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+
+				<-release
+			}
+
+			var wg sync.WaitGroup
+			for range test.jobs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, pool.submit(context.Background(), 0, job))
+				}()
+			}
+
+			assert.Eventually(t, func() bool {
+				return running.Load() == test.expectedRunning
+			}, time.Second, time.Millisecond)
+			for _, sample := range test.expectedQueued {
+				waitForMetric(t, registry, sample)
+			}
+
+			close(release)
+			wg.Wait()
+			pool.stop()
+
+			assert.Equal(t, test.expectedRunning, maxRunning.Load())
+			for _, sample := range test.expectedMetrics {
+				waitForMetric(t, registry, sample)
+			}
+		})
+	}
+}
+
+func Test_workerPool_QueueTimeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	registry := metrics.NewRegistry()
+	mockClock := clock.NewMock()
+	pool := newWorkerPool(1, newTransportMetrics(registry), mockClock)
+	pool.start()
+	defer pool.stop()
+
+	release := make(chan struct{})
+	require.NoError(t, pool.submit(context.Background(), 0, func() {
+		<-release
+	}))
+	defer close(release)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- pool.submit(context.Background(), 5*time.Second, func() {})
+	}()
+
+	assert.ErrorIs(t, advanceUntil(t, mockClock, errChan), errQueueTimeout)
+	waitForMetric(t, registry, "simulator_worker_queue_timeouts_total 1")
+}
+
+func Test_workerPool_Cancelled(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pool := newWorkerPool(1, newTransportMetrics(metrics.NewRegistry()), clock.NewMock())
+	pool.start()
+	defer pool.stop()
+
+	release := make(chan struct{})
+	require.NoError(t, pool.submit(context.Background(), 0, func() {
+		<-release
+	}))
+	defer close(release)
+
+	ctx, cncl := context.WithCancel(context.Background())
+	cncl()
+
+	assert.ErrorIs(t, pool.submit(ctx, 0, func() {}), context.Canceled)
+}
@@ -1,28 +1,192 @@
 package tcp
 
 import (
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/ormanli/form3-te/internal/app/simulator"
 )
 
-// request represents a payment request with an associated amount.
+const (
+	registerFields                     = 1
+	statusFields                       = 2
+	recallFields                       = 3
+	legacyPaymentFields                = 2
+	extendedPaymentFields              = 4
+	extendedPaymentWithReferenceFields = 5
+	extendedPaymentWithDebtorFields    = 6
+	extendedPaymentWithAccountsFields  = 7
+)
+
+// kind is the kind of request, selected by its first field.
+type kind int
+
+const (
+	paymentRequest kind = iota
+	statusRequest
+	recallRequest
+	registerRequest
+)
+
+// request represents a payment request, an enquiry of the status of a payment, or a recall of a payment.
+// The payment ID, currency, reference and accounts are only set when the request uses the extended format.
+// Status requests only carry the payment ID, and recall requests the payment ID and the reason.
 type request struct {
-	amount int
+	kind            kind
+	paymentID       string
+	amount          int
+	currency        string
+	reference       string
+	debtorAccount   string
+	creditorAccount string
+	reason          string
+}
+
+// payment converts the request to a payment processed by the service.
+func (r request) payment() simulator.Payment {
+	return simulator.Payment{
+		ID:              r.paymentID,
+		Amount:          r.amount,
+		Currency:        r.currency,
+		Reference:       r.reference,
+		DebtorAccount:   r.debtorAccount,
+		CreditorAccount: r.creditorAccount,
+	}
+}
+
+// recall converts the request to a recall resolved by the recaller.
+func (r request) recall() simulator.Recall {
+	return simulator.Recall{
+		PaymentID: r.paymentID,
+		Reason:    r.reason,
+	}
 }
 
 // parseRequest parses a string representation of a payment and returns a request object along with any error encountered during parsing.
+// It supports the legacy format `PAYMENT|<amount>` and the extended format `PAYMENT|<id>|<amount>|<currency>[|<reference>[|<debtor>[|<creditor>]]]`.
+// For extended requests with an invalid amount or currency, the returned request still carries the payment ID, so it can be echoed back.
+// Status enquiries use the format `STATUS|<id>`, recalls `RECALL|<id>|<reason>`, and registrations for notifications `REGISTER`.
 func parseRequest(s string) (request, error) {
 	parts := strings.Split(s, "|")
-	if len(parts) != 2 || parts[0] != "PAYMENT" {
+
+	switch parts[0] {
+	case "PAYMENT":
+		return parsePaymentRequest(parts)
+	case "STATUS":
+		return parseStatusRequest(parts)
+	case "RECALL":
+		return parseRecallRequest(parts)
+	case "REGISTER":
+		if len(parts) != registerFields {
+			return request{}, simulator.ErrInvalidRequest
+		}
+
+		return request{kind: registerRequest}, nil
+	default:
 		return request{}, simulator.ErrInvalidRequest
 	}
+}
 
-	amount, err := strconv.Atoi(parts[1])
+// parsePaymentRequest parses the fields of a payment request.
+func parsePaymentRequest(parts []string) (request, error) {
+	switch len(parts) {
+	case legacyPaymentFields:
+		amount, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return request{}, simulator.ErrInvalidAmount
+		}
+
+		return request{amount: amount}, nil
+	case extendedPaymentFields, extendedPaymentWithReferenceFields, extendedPaymentWithDebtorFields, extendedPaymentWithAccountsFields:
+		return parseExtendedRequest(parts[1:])
+	default:
+		return request{}, simulator.ErrInvalidRequest
+	}
+}
+
+// parseStatusRequest parses the fields of a status request.
+func parseStatusRequest(parts []string) (request, error) {
+	if len(parts) != statusFields {
+		return request{}, simulator.ErrInvalidRequest
+	}
+
+	if !isValidPaymentID(parts[1]) {
+		return request{}, simulator.ErrInvalidPaymentID
+	}
+
+	return request{kind: statusRequest, paymentID: parts[1]}, nil
+}
+
+// parseRecallRequest parses the fields of a recall request. The reason must not be empty.
+func parseRecallRequest(parts []string) (request, error) {
+	if len(parts) != recallFields || parts[2] == "" {
+		return request{}, simulator.ErrInvalidRequest
+	}
+
+	if !isValidPaymentID(parts[1]) {
+		return request{}, simulator.ErrInvalidPaymentID
+	}
+
+	return request{kind: recallRequest, paymentID: parts[1], reason: parts[2]}, nil
+}
+
+// parseExtendedRequest parses the fields of an extended payment request following the `PAYMENT` keyword.
+func parseExtendedRequest(fields []string) (request, error) {
+	paymentID := fields[0]
+	if !isValidPaymentID(paymentID) {
+		return request{}, simulator.ErrInvalidPaymentID
+	}
+
+	amount, err := strconv.Atoi(fields[1])
 	if err != nil {
-		return request{}, simulator.ErrInvalidAmount
+		return request{paymentID: paymentID}, simulator.ErrInvalidAmount
+	}
+
+	currency := fields[2]
+	if !isValidCurrency(currency) {
+		return request{paymentID: paymentID}, simulator.ErrInvalidCurrency
+	}
+
+	r := request{
+		paymentID: paymentID,
+		amount:    amount,
+		currency:  currency,
+	}
+
+	if len(fields) >= extendedPaymentWithReferenceFields-1 {
+		r.reference = fields[3]
+	}
+
+	if len(fields) >= extendedPaymentWithDebtorFields-1 {
+		r.debtorAccount = fields[4]
+	}
+
+	if len(fields) == extendedPaymentWithAccountsFields-1 {
+		r.creditorAccount = fields[5]
+	}
+
+	return r, nil
+}
+
+// isValidPaymentID checks that the payment ID is not empty and doesn't contain whitespace.
+// Status names such as ACCEPTED aren't valid, as responses echoing them couldn't be told apart from responses without an ID.
+func isValidPaymentID(s string) bool {
+	return s != "" && strings.IndexFunc(s, unicode.IsSpace) == -1 && !slices.Contains(statusStrings(), s)
+}
+
+// isValidCurrency checks that the currency is a three letter uppercase code, as defined by ISO 4217.
+func isValidCurrency(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
 	}
 
-	return request{amount: amount}, nil
+	return true
 }
@@ -41,6 +41,139 @@ func Test_parseRequest(t *testing.T) {
 				assert.Empty(t, r)
 			},
 		},
+		{
+			input: "PAYMENT|abc-1|100|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.EqualValues(t, request{paymentID: "abc-1", amount: 100, currency: "GBP"}, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|100|GBP|Invoice 42",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.EqualValues(t, request{paymentID: "abc-1", amount: 100, currency: "GBP", reference: "Invoice 42"}, r)
+			},
+		},
+		{
+			input: "STATUS|abc-1",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.EqualValues(t, request{kind: statusRequest, paymentID: "abc-1"}, r)
+			},
+		},
+		{
+			input: "STATUS|abc 1",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidPaymentID)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "STATUS|abc-1|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidRequest)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "RECALL|abc-1|Fraud",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.EqualValues(t, request{kind: recallRequest, paymentID: "abc-1", reason: "Fraud"}, r)
+			},
+		},
+		{
+			input: "RECALL|abc-1|",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidRequest)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "RECALL||Fraud",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidPaymentID)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "PAYMENT||100|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidPaymentID)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "PAYMENT|ACCEPTED|100|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidPaymentID)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "STATUS|SETTLED",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidPaymentID)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "PAYMENT|accepted|100|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "accepted", r.paymentID)
+			},
+		},
+		{
+			input: "PAYMENT|abc 1|100|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidPaymentID)
+				assert.Empty(t, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|A|GBP",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidAmount)
+				assert.EqualValues(t, request{paymentID: "abc-1"}, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|100|gbp",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidCurrency)
+				assert.EqualValues(t, request{paymentID: "abc-1"}, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|100|GBPX",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidCurrency)
+				assert.EqualValues(t, request{paymentID: "abc-1"}, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|100|GBP|Invoice 42|ACC-1",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.EqualValues(t, request{paymentID: "abc-1", amount: 100, currency: "GBP", reference: "Invoice 42", debtorAccount: "ACC-1"}, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|100|GBP||ACC-1|ACC-2",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.NoError(t, err)
+				assert.EqualValues(t, request{paymentID: "abc-1", amount: 100, currency: "GBP", debtorAccount: "ACC-1", creditorAccount: "ACC-2"}, r)
+			},
+		},
+		{
+			input: "PAYMENT|abc-1|100|GBP|Invoice|ACC-1|ACC-2|42",
+			assertFunc: func(t *testing.T, r request, err error) {
+				assert.ErrorIs(t, err, simulator.ErrInvalidRequest)
+				assert.Empty(t, r)
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
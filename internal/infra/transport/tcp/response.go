@@ -5,17 +5,87 @@ package tcp
 import (
 	"fmt"
 	"strings"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
 )
 
 // response represents a structured response containing status and reason.
+// The payment ID is only set when the response answers an extended request.
+// If fault is set, the response is corrupted by the fault when it is sent.
+// Cancelled is set when the request was cancelled because the grace period of a graceful shutdown is finished.
+// Notifications are sent unsolicited once a payment acknowledged as pending is settled.
+// Code identifies the result independent of the reason and is only written if reason codes are enabled.
 type response struct {
-	status status
-	reason string
+	paymentID    string
+	status       status
+	code         simulator.Code
+	reason       string
+	fault        simulator.Fault
+	cancelled    bool
+	notification bool
+}
+
+// newResponse creates a response to the given request, echoing its payment ID.
+// The code is derived from the status.
+func newResponse(r request, status status, reason string) response {
+	code := simulator.CodeOK
+	switch status {
+	case Rejected:
+		code = simulator.CodeRejected
+	case Pending:
+		code = simulator.CodePending
+	case Unknown:
+		code = simulator.CodePaymentNotFound
+	}
+
+	return response{
+		paymentID: r.paymentID,
+		status:    status,
+		code:      code,
+		reason:    reason,
+	}
+}
+
+// newErrorResponse creates a response rejecting the given request with the error as the reason and its code.
+func newErrorResponse(r request, err error) response {
+	response := newResponse(r, Rejected, err.Error())
+	response.code = simulator.CodeOf(err)
+
+	return response
+}
+
+// newCancelledResponse creates a response to the given request, which was cancelled by a graceful shutdown.
+func newCancelledResponse(r request) response {
+	response := newErrorResponse(r, simulator.ErrCancelled)
+	response.cancelled = true
+
+	return response
 }
 
-// String returns a formatted string representation of the response.
+// String returns a formatted string representation of the response without the code.
 func (r response) String() string {
-	return fmt.Sprintf("RESPONSE|%s|%s", r.status, capitalizeFirstLetter(r.reason))
+	return r.format(false)
+}
+
+// format returns a formatted string representation of the response.
+// Responses to extended requests echo the payment ID as `RESPONSE|<id>|<status>|<reason>`,
+// and notifications are formatted as `NOTIFY|<id>|<status>|<reason>`.
+// If withCode is set, the code follows the status, as in `RESPONSE|<status>|<code>|<reason>`.
+func (r response) format(withCode bool) string {
+	status := r.status.String()
+	if withCode {
+		status += "|" + string(r.code)
+	}
+
+	if r.notification {
+		return fmt.Sprintf("NOTIFY|%s|%s|%s", r.paymentID, status, capitalizeFirstLetter(r.reason))
+	}
+
+	if r.paymentID != "" {
+		return fmt.Sprintf("RESPONSE|%s|%s|%s", r.paymentID, status, capitalizeFirstLetter(r.reason))
+	}
+
+	return fmt.Sprintf("RESPONSE|%s|%s", status, capitalizeFirstLetter(r.reason))
 }
 
 // status is an enumeration type representing different possible states of a response.
@@ -24,6 +94,9 @@ type status int
 const (
 	Accepted status = iota
 	Rejected
+	Pending
+	Unknown
+	Settled
 )
 
 func capitalizeFirstLetter(s string) string {
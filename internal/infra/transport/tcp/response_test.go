@@ -1,9 +1,14 @@
 package tcp
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/client"
+	"github.com/ormanli/form3-te/internal/app/simulator"
 )
 
 func Test_response_String(t *testing.T) {
@@ -28,6 +33,25 @@ func Test_response_String(t *testing.T) {
 			},
 			expected: "RESPONSE|REJECTED|Payment rejected",
 		},
+		{
+			name: "With payment ID",
+			response: response{
+				paymentID: "abc-1",
+				status:    Accepted,
+				reason:    "payment accepted",
+			},
+			expected: "RESPONSE|abc-1|ACCEPTED|Payment accepted",
+		},
+		{
+			name: "Notification",
+			response: response{
+				paymentID:    "abc-1",
+				status:       Settled,
+				reason:       "transaction processed",
+				notification: true,
+			},
+			expected: "NOTIFY|abc-1|SETTLED|Transaction processed",
+		},
 		{
 			name:     "Empty",
 			response: response{},
@@ -40,3 +64,72 @@ func Test_response_String(t *testing.T) {
 		})
 	}
 }
+
+func Test_response_format_WithCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		response response
+		expected string
+	}{
+		{
+			name:     "Basic",
+			response: newResponse(request{}, Accepted, "transaction processed"),
+			expected: "RESPONSE|ACCEPTED|OK|Transaction processed",
+		},
+		{
+			name:     "With payment ID",
+			response: newErrorResponse(request{paymentID: "abc-1"}, simulator.ErrDuplicate),
+			expected: "RESPONSE|abc-1|REJECTED|DUPLICATE|Duplicate",
+		},
+		{
+			name: "Notification",
+			response: response{
+				paymentID:    "abc-1",
+				status:       Settled,
+				code:         simulator.CodeOK,
+				reason:       "transaction processed",
+				notification: true,
+			},
+			expected: "NOTIFY|abc-1|SETTLED|OK|Transaction processed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.response.format(true))
+		})
+	}
+}
+
+// Test_response_Codes_RoundTrip ensures every code written by the transport is parsed back by the client.
+func Test_response_Codes_RoundTrip(t *testing.T) {
+	responses := map[simulator.Code]response{
+		simulator.CodeOK:       newResponse(request{paymentID: "abc-1"}, Accepted, "transaction processed"),
+		simulator.CodePending:  newResponse(request{paymentID: "abc-1"}, Pending, pendingReason),
+		simulator.CodeRejected: newErrorResponse(request{paymentID: "abc-1"}, simulator.RejectionError{Reason: "closed"}),
+		simulator.CodeInternal: newErrorResponse(request{paymentID: "abc-1"}, errors.New("unexpected")),
+	}
+
+	for _, code := range simulator.Codes() {
+		t.Run(string(code), func(t *testing.T) {
+			r, ok := responses[code]
+			if !ok {
+				err := simulator.ErrorOf(code)
+				require.Error(t, err)
+				r = newErrorResponse(request{paymentID: "abc-1"}, err)
+			}
+
+			parsed, err := client.ParseCodedResponse(r.format(true))
+			require.NoError(t, err)
+			assert.Equal(t, string(code), parsed.Code)
+			assert.Equal(t, "abc-1", parsed.PaymentID)
+			assert.Equal(t, capitalizeFirstLetter(r.reason), parsed.Reason)
+
+			r.notification = true
+			if r.status == Rejected {
+				notification, err := client.ParseCodedNotification(r.format(true))
+				require.NoError(t, err)
+				assert.Equal(t, string(code), notification.Code)
+			}
+		})
+	}
+}
@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _statusName = "ACCEPTEDREJECTED"
+const _statusName = "ACCEPTEDREJECTEDPENDINGUNKNOWNSETTLED"
 
-var _statusIndex = [...]uint8{0, 8, 16}
+var _statusIndex = [...]uint8{0, 8, 16, 23, 30, 37}
 
-const _statusLowerName = "acceptedrejected"
+const _statusLowerName = "acceptedrejectedpendingunknownsettled"
 
 func (i status) String() string {
 	if i < 0 || i >= status(len(_statusIndex)-1) {
@@ -26,20 +26,32 @@ func _statusNoOp() {
 	var x [1]struct{}
 	_ = x[Accepted-(0)]
 	_ = x[Rejected-(1)]
+	_ = x[Pending-(2)]
+	_ = x[Unknown-(3)]
+	_ = x[Settled-(4)]
 }
 
-var _statusValues = []status{Accepted, Rejected}
+var _statusValues = []status{Accepted, Rejected, Pending, Unknown, Settled}
 
 var _statusNameToValueMap = map[string]status{
-	_statusName[0:8]:       Accepted,
-	_statusLowerName[0:8]:  Accepted,
-	_statusName[8:16]:      Rejected,
-	_statusLowerName[8:16]: Rejected,
+	_statusName[0:8]:        Accepted,
+	_statusLowerName[0:8]:   Accepted,
+	_statusName[8:16]:       Rejected,
+	_statusLowerName[8:16]:  Rejected,
+	_statusName[16:23]:      Pending,
+	_statusLowerName[16:23]: Pending,
+	_statusName[23:30]:      Unknown,
+	_statusLowerName[23:30]: Unknown,
+	_statusName[30:37]:      Settled,
+	_statusLowerName[30:37]: Settled,
 }
 
 var _statusNames = []string{
 	_statusName[0:8],
 	_statusName[8:16],
+	_statusName[16:23],
+	_statusName[23:30],
+	_statusName[30:37],
 }
 
 // statusString retrieves an enum value from the enum constants string name.
@@ -3,55 +3,155 @@ package tcp
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/benbjohnson/clock"
 
 	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/journal"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
 )
 
 // Service defines the interface for processing requests.
 type Service interface {
-	Process(amount int) error
+	Process(ctx context.Context, payment simulator.Payment) error
+}
+
+// Journal records requests and the responses they are answered with.
+type Journal interface {
+	Record(entry journal.Entry)
+}
+
+// Recorder records the lines received on connections, so they can be replayed.
+type Recorder interface {
+	Record(connectionID uint64, at time.Time, line string)
+}
+
+// Statuses looks up the outcome of processed payments, and records payments acknowledged as pending before they are processed.
+type Statuses interface {
+	Status(paymentID string) (simulator.PaymentStatus, bool)
+	RecordPending(payment simulator.Payment) (release func())
+}
+
+// Recaller resolves recalls of payments.
+type Recaller interface {
+	Recall(ctx context.Context, recall simulator.Recall) error
+}
+
+// Calendar tells when the scheme is closed.
+type Calendar interface {
+	ClosureAt(t time.Time) (simulator.Closure, bool)
 }
 
 // Transport manages TCP connections and handles incoming requests.
 type Transport struct {
-	service          Service
-	cfg              simulator.Config
-	listener         net.Listener
-	stopHandlingChan chan struct{}
-	wg               sync.WaitGroup
-	clock            clock.Clock
+	service      Service
+	statuses     Statuses
+	recaller     Recaller
+	calendar     Calendar
+	journal      Journal
+	recorder     Recorder
+	cfg          simulator.Config
+	listener     net.Listener
+	connections  *connectionTracker
+	faults       *faultInjector
+	throttle     *throttle
+	notifier     *notifier
+	metrics      *transportMetrics
+	workers      *workerPool
+	slots        chan struct{}
+	lastConnID   uint64
+	drainCtx     context.Context
+	requestDrain context.CancelFunc
+	handlingCtx  context.Context
+	stopHandling context.CancelFunc
+	wg           sync.WaitGroup
+	clock        clock.Clock
 }
 
-// NewTransport creates a new Transport instance.
-func NewTransport(cfg simulator.Config, service Service, clock clock.Clock) *Transport {
+// NewTransport creates a new Transport instance. Its metrics are registered to the registry.
+// Requests are recorded in the journal and received lines by the recorder, unless they are nil.
+// Status requests are answered from statuses, or with the unknown status if it is nil.
+// Recall requests are resolved by the recaller, or rejected as not found if it is nil.
+// New connections are refused while the calendar is closed with the refuse action, unless it is nil.
+// If the maximum number of connections or workers is not positive, it is unlimited.
+func NewTransport(
+	cfg simulator.Config,
+	service Service,
+	clock clock.Clock,
+	registry *metrics.Registry,
+	journal Journal,
+	recorder Recorder,
+	statuses Statuses,
+	recaller Recaller,
+	calendar Calendar,
+) *Transport {
+	drainCtx, requestDrain := context.WithCancel(context.Background())
+	handlingCtx, stopHandling := context.WithCancel(context.Background())
+
+	transportMetrics := newTransportMetrics(registry)
+
+	var slots chan struct{}
+	if cfg.ServerMaxConnections > 0 {
+		slots = make(chan struct{}, cfg.ServerMaxConnections)
+	}
+
 	return &Transport{
-		cfg:              cfg,
-		service:          service,
-		stopHandlingChan: make(chan struct{}),
-		wg:               sync.WaitGroup{},
-		clock:            clock,
+		cfg:          cfg,
+		service:      service,
+		statuses:     statuses,
+		recaller:     recaller,
+		calendar:     calendar,
+		journal:      journal,
+		recorder:     recorder,
+		connections:  newConnectionTracker(),
+		faults:       newFaultInjector(cfg),
+		throttle:     newThrottle(cfg, clock),
+		notifier:     &notifier{},
+		metrics:      transportMetrics,
+		workers:      newWorkerPool(cfg.ServerWorkers, transportMetrics, clock),
+		slots:        slots,
+		drainCtx:     drainCtx,
+		requestDrain: requestDrain,
+		handlingCtx:  handlingCtx,
+		stopHandling: stopHandling,
+		wg:           sync.WaitGroup{},
+		clock:        clock,
 	}
 }
 
-// Start initializes the TCP server and starts accepting connections.
-// It will block until context is cancelled and grace period is finished.
+// Start initializes the TCP server and starts accepting connections. If a server certificate is configured, connections use TLS.
+// It will block until context is cancelled or Drain is called, and in-flight requests are completed or grace period is finished.
 func (t *Transport) Start(ctx context.Context) error {
-	var err error
-	t.listener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", t.cfg.ServerHost, t.cfg.ServerPort))
+	tlsConfig, err := newTLSConfig(t.cfg)
+	if err != nil {
+		return err
+	}
+
+	listenConfig := net.ListenConfig{KeepAlive: t.cfg.ServerKeepAlivePeriod}
+
+	t.listener, err = listenConfig.Listen(ctx, "tcp", fmt.Sprintf("%s:%d", t.cfg.ServerHost, t.cfg.ServerPort))
 	if err != nil {
 		return err
 	}
 
+	if tlsConfig != nil {
+		t.listener = tls.NewListener(t.listener, tlsConfig)
+	}
+
 	defer slog.Info("Server stopped")
 
-	slog.Info("Server started", "port", t.cfg.ServerPort)
+	slog.Info("Server started", "port", t.cfg.ServerPort, "tls", tlsConfig != nil,
+		"maxConnections", t.cfg.ServerMaxConnections, "workers", t.cfg.ServerWorkers)
+
+	t.workers.start()
 
 	t.wg.Add(1)
 	go func() {
@@ -66,8 +166,11 @@ func (t *Transport) Start(ctx context.Context) error {
 				continue
 			}
 
+			t.metrics.connectionsAccepted.Inc()
+			t.lastConnID++
+
 			t.wg.Add(1)
-			go t.handleConnection(conn)
+			go t.handleConnection(conn, t.lastConnID)
 		}
 	}()
 
@@ -76,9 +179,25 @@ func (t *Transport) Start(ctx context.Context) error {
 	return nil
 }
 
-// waitForGracefulShutdown waits for a graceful shutdown signal, sleeps until shutdown timeout and then closes the channel to stop handling connections.
+// Drain starts the graceful shutdown as if the context passed to Start is cancelled.
+func (t *Transport) Drain() {
+	t.requestDrain()
+}
+
+// SetFaults changes the probability of injecting faults and the faults picked from, for responses sent afterwards.
+func (t *Transport) SetFaults(probability float64, faults []simulator.Fault) {
+	t.faults.set(probability, faults)
+}
+
+// waitForGracefulShutdown waits for a graceful shutdown signal, closes idle connections and waits until in-flight requests are completed
+// or shutdown timeout is reached. Then it cancels the handling context to stop handling connections and in-flight requests.
 func (t *Transport) waitForGracefulShutdown(ctx context.Context) {
-	<-ctx.Done()
+	select {
+	case <-ctx.Done():
+	case <-t.drainCtx.Done():
+	}
+
+	t.requestDrain()
 
 	slog.Info("Server graceful shutdown started")
 
@@ -87,84 +206,459 @@ func (t *Transport) waitForGracefulShutdown(ctx context.Context) {
 		slog.Error("Error closing listener", "error", err)
 	}
 
-	t.clock.Sleep(t.cfg.ServerGracefulShutdownTimeout)
+	timer := t.clock.Timer(t.cfg.ServerGracefulShutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-t.connections.drain():
+		slog.Info("Server drained in-flight requests")
+	case <-timer.C:
+		slog.Info("Server graceful shutdown timeout reached")
+	}
 
-	close(t.stopHandlingChan)
+	t.stopHandling()
 
 	t.wg.Wait()
+
+	t.workers.stop()
 }
 
-var defaultCancelledResponse = response{
-	status: Rejected,
-	reason: "Cancelled",
+const (
+	pendingReason = "Payment in progress"
+
+	recallAcceptedReason = "Recall accepted"
+	registeredReason     = "Registered for notifications"
+)
+
+// exchange is a request line received on a connection, which is answered by a response.
+// If the line couldn't be parsed, parseErr is set.
+type exchange struct {
+	conn         net.Conn
+	connectionID uint64
+	line         string
+	request      request
+	parseErr     error
+	received     time.Time
 }
 
 // handleConnection manages the lifecycle of a single TCP connection, reading requests and sending responses.
-func (t *Transport) handleConnection(conn net.Conn) {
+// The connection is served once a connection slot is acquired.
+func (t *Transport) handleConnection(conn net.Conn, connectionID uint64) {
 	defer t.wg.Done()
 
+	defer t.metrics.connectionsClosed.Inc()
+
 	defer conn.Close() //nolint:errcheck
 
-	slog.Debug("Handling connection", "remote", conn.RemoteAddr())
+	if t.refused(conn) {
+		return
+	}
+
+	if !t.acquireSlot(conn) {
+		return
+	}
+	defer t.releaseSlot()
+
+	if !t.connections.add(conn) {
+		return
+	}
+	defer t.connections.remove(conn)
 
-	scanner := bufio.NewScanner(conn)
-	for scanner.Scan() {
-		request := scanner.Text()
+	t.metrics.connectionsOpen.Inc()
+	defer t.metrics.connectionsOpen.Dec()
 
-		responseChan := make(chan response, 1)
+	slog.Debug("Handling connection", "remote", conn.RemoteAddr(), "connectionId", connectionID)
 
-		go func() {
-			select {
-			case <-t.stopHandlingChan:
-				return
-			case responseChan <- t.handleRequest(request):
-			}
-		}()
+	ctx := t.handlingCtx
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		setDeadline(conn.SetDeadline, t.cfg.ServerReadTimeout) //nolint:errcheck // The handshake fails if the connection is closed.
 
-		select {
-		case <-t.stopHandlingChan:
-			writeResponse(conn, request, defaultCancelledResponse)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			t.metrics.handshakeFailures.Inc()
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				t.logConnectionError(conn, timeoutError(err, errReadTimeout))
+			} else if !errors.Is(err, net.ErrClosed) {
+				slog.Error("TLS handshake failed", "error", err, "remote", conn.RemoteAddr())
+			}
 			return
-		case response := <-responseChan:
-			writeResponse(conn, request, response)
+		}
+
+		if subject, ok := clientSubject(tlsConn.ConnectionState()); ok {
+			slog.Info("Client authenticated", "subject", subject, "remote", conn.RemoteAddr())
+			ctx = simulator.ContextWithClientSubject(ctx, subject)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		slog.Error("Error reading from connection", "error", err)
+	// Writes are serialized, as notifications can be sent to the connection while its requests are answered.
+	sc := &syncConn{Conn: conn}
+	defer t.notifier.unregister(sc)
+
+	bucket := t.throttle.connectionBucket()
+
+	reader := bufio.NewReaderSize(conn, maxLineLength)
+	for {
+		line, err := t.readLine(conn, reader)
+		if err != nil {
+			t.logConnectionError(conn, err)
+			return
+		}
+
+		if t.recorder != nil {
+			t.recorder.Record(connectionID, t.clock.Now(), line)
+		}
+
+		if !t.connections.setActive(conn) {
+			slog.Debug("Discarding request received during graceful shutdown", "request", line)
+			return
+		}
+
+		if !t.handleLine(ctx, sc, connectionID, bucket, line) {
+			return
+		}
+
+		if !t.connections.setIdle(conn) {
+			return
+		}
 	}
 }
 
-// handleRequest processes an incoming request and returns a corresponding response.
-func (t *Transport) handleRequest(s string) response {
-	r, err := parseRequest(s)
+// handleLine parses and processes a single request line and writes the response to the connection.
+// The request is processed with a context derived from the connection context, which is cancelled when the grace period is finished.
+// If asynchronous settlement is enabled, extended payments are acknowledged as pending and settled in the background,
+// and registrations for notifications are accepted.
+// Requests exceeding a rate limit, which also limits the requests of the connection with the bucket, are throttled first.
+// It returns false if the connection must be closed, because the request was cancelled when the grace period is finished
+// or the response was corrupted by a fault.
+func (t *Transport) handleLine(ctx context.Context, conn net.Conn, connectionID uint64, bucket *tokenBucket, line string) bool {
+	x := exchange{
+		conn:         conn,
+		connectionID: connectionID,
+		line:         line,
+		received:     t.clock.Now(),
+	}
+
+	r, err := parseRequest(line)
+	x.request = r
 	if err != nil {
-		return response{
-			status: Rejected,
-			reason: err.Error(),
+		x.parseErr = err
+		t.metrics.parseFailures.Inc(capitalizeFirstLetter(err.Error()))
+		return t.deliver(x, newErrorResponse(r, err))
+	}
+
+	delay := t.cfg.ServerThrottlePolicy == simulator.ThrottleDelay
+	if wait, limit := t.throttle.admit(bucket, conn.RemoteAddr(), delay); wait > 0 {
+		t.metrics.throttled.Inc(limit)
+
+		switch t.cfg.ServerThrottlePolicy {
+		case simulator.ThrottleDelay:
+			if !t.sleep(ctx, wait) {
+				return t.cancel(x)
+			}
+		case simulator.ThrottleDisconnect:
+			return t.deliver(x, resultResponse(r, simulator.ErrDropConnection))
+		default:
+			return t.deliver(x, newErrorResponse(r, simulator.ErrThrottled))
 		}
 	}
 
-	err = t.service.Process(r.amount)
+	switch {
+	case r.kind == registerRequest && !t.cfg.ServerAsyncSettlement:
+		return t.deliver(x, newErrorResponse(r, simulator.ErrNotificationsDisabled))
+	case r.kind == registerRequest:
+		t.notifier.register(conn, connectionID, clientKey(ctx, conn))
+		return t.deliver(x, newResponse(r, Accepted, registeredReason))
+	case r.kind == paymentRequest && r.paymentID != "" && t.cfg.ServerAsyncSettlement:
+		return t.acknowledge(ctx, x)
+	}
+
+	t.metrics.inFlight.Inc()
+	defer t.metrics.inFlight.Dec()
+
+	requestCtx, requestCncl := context.WithCancel(ctx)
+	defer requestCncl()
+
+	responseChan := make(chan response, 1)
+
+	err = t.workers.submit(requestCtx, t.cfg.ServerWorkerQueueTimeout, func() {
+		responseChan <- t.handleRequest(requestCtx, r)
+	})
+	if errors.Is(err, errQueueTimeout) {
+		return t.deliver(x, newErrorResponse(r, simulator.ErrServerBusy))
+	}
 	if err != nil {
-		return response{
-			status: Rejected,
-			reason: err.Error(),
+		return t.cancel(x)
+	}
+
+	select {
+	case <-t.handlingCtx.Done():
+		return t.cancel(x)
+	case response := <-responseChan:
+		return t.deliver(x, response)
+	}
+}
+
+// sleep waits for d. It returns false if the context is cancelled first.
+func (t *Transport) sleep(ctx context.Context, d time.Duration) bool {
+	timer := t.clock.Timer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// cancel answers the request with a cancelled response, because the grace period is finished.
+// It always returns false, as the connection must be closed.
+func (t *Transport) cancel(x exchange) bool {
+	response := newCancelledResponse(x.request)
+	t.metrics.observe(response, t.clock.Since(x.received).Seconds())
+	t.send(x, response, response.format(t.cfg.ServerReasonCodes)+"\n", journal.OutcomeCancelled) //nolint:errcheck // The connection is closed anyway.
+
+	return false
+}
+
+// refused returns true if the connection must be refused, because the scheme is closed with the refuse action.
+func (t *Transport) refused(conn net.Conn) bool {
+	if t.calendar == nil {
+		return false
+	}
+
+	closure, ok := t.calendar.ClosureAt(t.clock.Now())
+	if !ok || closure.Action != simulator.ClosureRefuse {
+		return false
+	}
+
+	t.metrics.connectionsRefused.Inc()
+	slog.Info("Refusing connection, scheme is closed", "reason", closure.Reason, "remote", conn.RemoteAddr())
+
+	return true
+}
+
+// acquireSlot acquires a connection slot for the connection. If the maximum number of connections are open,
+// the connection is rejected or waits for a slot until draining starts, depending on the connection limit policy.
+// It returns false if the connection must be closed.
+func (t *Transport) acquireSlot(conn net.Conn) bool {
+	if t.slots == nil {
+		return true
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if t.cfg.ServerConnectionLimitPolicy != simulator.ConnectionLimitQueue {
+		t.metrics.connectionsRejected.Inc()
+		slog.Warn("Rejecting connection, maximum number of connections are open", "remote", conn.RemoteAddr())
+		return false
+	}
+
+	t.metrics.connectionsQueued.Inc()
+	defer t.metrics.connectionsQueued.Dec()
+
+	slog.Debug("Queueing connection, maximum number of connections are open", "remote", conn.RemoteAddr())
+
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	case <-t.drainCtx.Done():
+		return false
+	}
+}
+
+// releaseSlot releases the connection slot acquired by acquireSlot.
+func (t *Transport) releaseSlot() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+// handleRequest processes a parsed request and returns a corresponding response.
+// If the context is cancelled while the request is processed, a cancelled response is returned.
+func (t *Transport) handleRequest(ctx context.Context, r request) response {
+	var err error
+	switch r.kind {
+	case statusRequest:
+		return t.statusResponse(r)
+	case recallRequest:
+		return t.recallResponse(ctx, r)
+	default:
+		err = t.service.Process(ctx, r.payment())
+	}
+	if ctx.Err() != nil {
+		return newCancelledResponse(r)
+	}
+
+	var faultErr simulator.FaultError
+	if errors.As(err, &faultErr) {
+		response := resultResponse(r, faultErr.Err)
+		if response.fault == "" {
+			response.fault = faultErr.Fault
 		}
+
+		return response
+	}
+
+	return resultResponse(r, err)
+}
+
+// statusResponse returns the response to a status request, which is the response the payment was answered with,
+// or the pending or unknown status if the payment is still being processed or wasn't processed.
+// Faults corrupting the original response are not applied.
+func (t *Transport) statusResponse(r request) response {
+	if t.statuses == nil {
+		return newResponse(r, Unknown, simulator.ErrPaymentNotFound.Error())
+	}
+
+	s, ok := t.statuses.Status(r.paymentID)
+	switch {
+	case !ok:
+		return newResponse(r, Unknown, simulator.ErrPaymentNotFound.Error())
+	case s.Pending:
+		return newResponse(r, Pending, pendingReason)
+	}
+
+	err := s.Err
+
+	var faultErr simulator.FaultError
+	if errors.As(err, &faultErr) {
+		err = faultErr.Err
+	}
+
+	return resultResponse(r, err)
+}
+
+// recallResponse resolves a recall request and returns the corresponding response.
+// If the context is cancelled while the recall is resolved, a cancelled response is returned.
+func (t *Transport) recallResponse(ctx context.Context, r request) response {
+	if t.recaller == nil {
+		return newErrorResponse(r, simulator.ErrPaymentNotFound)
+	}
+
+	err := t.recaller.Recall(ctx, r.recall())
+	if ctx.Err() != nil {
+		return newCancelledResponse(r)
+	}
+	if err != nil {
+		return newErrorResponse(r, err)
+	}
+
+	return newResponse(r, Accepted, recallAcceptedReason)
+}
+
+// resultResponse returns the response for the result of processing the request.
+func resultResponse(r request, err error) response {
+	if errors.Is(err, simulator.ErrDropConnection) {
+		return response{fault: simulator.FaultClose}
+	}
+	if err != nil {
+		return newErrorResponse(r, err)
+	}
+
+	return newResponse(r, Accepted, "Transaction processed")
+}
+
+// deliver writes the response to the request to the connection, corrupting it if the response has a fault or a fault is injected.
+// It returns false if the connection must be closed.
+func (t *Transport) deliver(x exchange, r response) bool {
+	if r.fault == "" {
+		r.fault = t.faults.pick()
 	}
 
-	return response{
-		status: Accepted,
-		reason: "Transaction processed",
+	t.metrics.observe(r, t.clock.Since(x.received).Seconds())
+
+	switch r.fault {
+	case simulator.FaultClose:
+		slog.Debug("Closing connection without response", "request", x.line, "remote", x.conn.RemoteAddr())
+		t.record(x, r, "", string(r.fault))
+		return false
+	case simulator.FaultTruncate:
+		s := r.format(t.cfg.ServerReasonCodes)
+		t.send(x, r, s[:len(s)/2], string(r.fault)) //nolint:errcheck // The connection is closed anyway.
+		return false
+	case simulator.FaultNoNewline:
+		return t.send(x, r, r.format(t.cfg.ServerReasonCodes), string(r.fault)) == nil
+	case simulator.FaultStall:
+		slog.Debug("Stalling response", "request", x.line, "remote", x.conn.RemoteAddr())
+		t.record(x, r, "", string(r.fault))
+		<-t.handlingCtx.Done()
+		return false
+	default:
+		return t.send(x, r, r.format(t.cfg.ServerReasonCodes)+"\n", journal.OutcomeDelivered) == nil
 	}
 }
 
-// writeResponse sends a response back to the client over the provided connection.
-func writeResponse(conn net.Conn, request string, r response) {
-	_, err := fmt.Fprintf(conn, "%s\n", r)
+// send writes s, which is the response to the request, to the connection and records the exchange with the outcome.
+// The connection must be closed if an error is returned.
+func (t *Transport) send(x exchange, r response, s string, outcome string) error {
+	err := t.write(x.conn, x.line, s)
 	if err != nil {
-		slog.Error("Failed to write response", "error", err, "request", request, "response", r)
+		outcome = journal.OutcomeFailed
+	}
+
+	t.record(x, r, s, outcome)
+
+	return err
+}
+
+// record records the exchange answered by the response in the journal, along with what was written to the connection.
+func (t *Transport) record(x exchange, r response, written string, outcome string) {
+	if t.journal == nil {
 		return
 	}
-	slog.Debug("Handling request", "request", request, "response", r)
+
+	responded := t.clock.Now()
+	status, reason := statusAndReason(r)
+
+	entry := journal.Entry{
+		ConnectionID: x.connectionID,
+		Remote:       x.conn.RemoteAddr().String(),
+		ReceivedAt:   x.received,
+		RespondedAt:  responded,
+		LatencyMs:    float64(responded.Sub(x.received)) / float64(time.Millisecond),
+		Request:      x.line,
+		PaymentID:    x.request.paymentID,
+		Amount:       x.request.amount,
+		Currency:     x.request.currency,
+		Reference:    x.request.reference,
+		Status:       status,
+		Reason:       reason,
+		Response:     written,
+		Outcome:      outcome,
+	}
+	if r.fault != simulator.FaultClose {
+		entry.Code = string(r.code)
+	}
+	if x.parseErr != nil {
+		entry.ParseError = capitalizeFirstLetter(x.parseErr.Error())
+	}
+
+	t.journal.Record(entry)
+}
+
+// write sends the raw string back to the client over the provided connection within the write timeout.
+// The connection must be closed if an error is returned.
+func (t *Transport) write(conn net.Conn, request string, s string) error {
+	err := setDeadline(conn.SetWriteDeadline, t.cfg.ServerWriteTimeout)
+	if err == nil {
+		_, err = conn.Write([]byte(s))
+	}
+	if err != nil {
+		err = timeoutError(err, errWriteTimeout)
+		if closeReason(err) != "" {
+			t.logConnectionError(conn, err)
+		} else {
+			slog.Error("Failed to write response", "error", err, "request", request, "response", s)
+		}
+
+		return err
+	}
+	slog.Debug("Handling request", "request", request, "response", s)
+
+	return nil
 }
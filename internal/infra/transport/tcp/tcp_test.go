@@ -1,90 +1,153 @@
 package tcp
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/kelseyhightower/envconfig"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 
+	"github.com/ormanli/form3-te/client"
 	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/journal"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
 )
 
 func Test_Behaviour(t *testing.T) {
 	tests := []struct {
 		name               string
 		prepareMockService func(*MockService)
-		run                func(*testing.T, net.Conn)
+		run                func(*testing.T, *client.Client)
 	}{
 		{
 			name: "Valid input",
 			prepareMockService: func(mockService *MockService) {
 				mockService.EXPECT().
-					Process(1).
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
 					Return(nil)
 			},
-			run: func(t *testing.T, conn net.Conn) {
-				_, err := conn.Write([]byte("PAYMENT|1\n"))
-				require.NoError(t, err)
-
-				out := make([]byte, 1024)
-
-				_, err = conn.Read(out)
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{Amount: 1})
 				require.NoError(t, err)
-				require.Contains(t, string(out), "RESPONSE|ACCEPTED|Transaction processed")
+				require.Equal(t, client.Response{Status: client.StatusAccepted, Reason: "Transaction processed"}, response)
 			},
 		},
 		{
 			name:               "Invalid amount",
 			prepareMockService: func(mockService *MockService) {},
-			run: func(t *testing.T, conn net.Conn) {
-				_, err := conn.Write([]byte("PAYMENT|A\n"))
-				require.NoError(t, err)
-
-				out := make([]byte, 1024)
-
-				_, err = conn.Read(out)
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Do(context.Background(), "PAYMENT|A")
 				require.NoError(t, err)
-				require.Contains(t, string(out), "RESPONSE|REJECTED|Invalid amount")
+				require.Equal(t, client.Response{Status: client.StatusRejected, Reason: "Invalid amount"}, response)
 			},
 		},
 		{
 			name:               "Invalid request",
 			prepareMockService: func(mockService *MockService) {},
-			run: func(t *testing.T, conn net.Conn) {
-				_, err := conn.Write([]byte("CHECKOUT|1\n"))
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Do(context.Background(), "CHECKOUT|1")
 				require.NoError(t, err)
-
-				out := make([]byte, 1024)
-
-				_, err = conn.Read(out)
+				require.Equal(t, client.Response{Status: client.StatusRejected, Reason: "Invalid request"}, response)
+			},
+		},
+		{
+			name: "Valid extended input",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{ID: "abc-1", Amount: 1, Currency: "GBP", Reference: "Invoice 42"}).
+					Return(nil)
+			},
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{ID: "abc-1", Amount: 1, Currency: "GBP", Reference: "Invoice 42"})
+				require.NoError(t, err)
+				require.Equal(t, client.Response{PaymentID: "abc-1", Status: client.StatusAccepted, Reason: "Transaction processed"}, response)
+			},
+		},
+		{
+			name:               "Invalid currency in extended input",
+			prepareMockService: func(mockService *MockService) {},
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{ID: "abc-1", Amount: 1, Currency: "GB"})
+				require.NoError(t, err)
+				require.Equal(t, client.Response{PaymentID: "abc-1", Status: client.StatusRejected, Reason: "Invalid currency"}, response)
+			},
+		},
+		{
+			name: "Insufficient funds",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{ID: "abc-1", Amount: 1, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-2"}).
+					Return(simulator.ErrInsufficientFunds)
+			},
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{ID: "abc-1", Amount: 1, Currency: "GBP", DebtorAccount: "ACC-1", CreditorAccount: "ACC-2"})
 				require.NoError(t, err)
-				require.Contains(t, string(out), "RESPONSE|REJECTED|Invalid request")
+				require.Equal(t, client.Response{PaymentID: "abc-1", Status: client.StatusRejected, Reason: "Insufficient funds"}, response)
+			},
+		},
+		{
+			name: "Duplicate payment",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}).
+					Return(simulator.ErrDuplicate)
+			},
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{ID: "abc-1", Amount: 1, Currency: "GBP"})
+				require.NoError(t, err)
+				require.Equal(t, client.Response{PaymentID: "abc-1", Status: client.StatusRejected, Reason: "Duplicate"}, response)
+			},
+		},
+		{
+			name: "Downstream service dropped connection",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(simulator.ErrDropConnection)
+			},
+			run: func(t *testing.T, c *client.Client) {
+				_, err := c.Send(context.Background(), client.Payment{Amount: 1})
+				require.ErrorIs(t, err, client.ErrConnectionClosed)
+			},
+		},
+		{
+			name: "Downstream service rejected payment",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(simulator.RejectionError{Reason: "Insufficient funds"})
+			},
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{Amount: 1})
+				require.NoError(t, err)
+				require.Equal(t, client.Response{Status: client.StatusRejected, Reason: "Insufficient funds"}, response)
 			},
 		},
 		{
 			name: "Downstream service failed",
 			prepareMockService: func(mockService *MockService) {
 				mockService.EXPECT().
-					Process(1).
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
 					Return(errors.New("service failure"))
 			},
-			run: func(t *testing.T, conn net.Conn) {
-				_, err := conn.Write([]byte("PAYMENT|1\n"))
-				require.NoError(t, err)
-
-				out := make([]byte, 1024)
-
-				_, err = conn.Read(out)
+			run: func(t *testing.T, c *client.Client) {
+				response, err := c.Send(context.Background(), client.Payment{Amount: 1})
 				require.NoError(t, err)
-				require.Contains(t, string(out), "RESPONSE|REJECTED|Service failure")
+				require.Equal(t, client.Response{Status: client.StatusRejected, Reason: "Service failure"}, response)
 			},
 		},
 	}
@@ -105,16 +168,17 @@ func Test_Behaviour(t *testing.T) {
 				ServerHost: "localhost",
 			}
 
-			transport := NewTransport(cfg, mockService, clock.New())
+			transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
 			go transport.Start(ctx) //nolint:errcheck
 
 			defer cncl()
 
-			conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
-			require.NoError(t, err)
-			defer conn.Close() //nolint:errcheck
+			waitForServer(t, port)
 
-			test.run(t, conn)
+			c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port)})
+			defer c.Close() //nolint:errcheck
+
+			test.run(t, c)
 		})
 	}
 }
@@ -122,16 +186,16 @@ func Test_Behaviour(t *testing.T) {
 func Test_GracefulShutdown(t *testing.T) {
 	tests := []struct {
 		name               string
-		prepareMockService func(*MockService, chan struct{})
-		run                func(*testing.T, int, *contextAndCancel, *clock.Mock)
+		prepareMockService func(*MockService, *serviceControl)
+		run                func(*testing.T, int, *contextAndCancel, *clock.Mock, *serviceControl, <-chan struct{})
 	}{
 		{
 			name:               "Don't Accept New Connection During Grace Period",
-			prepareMockService: func(*MockService, chan struct{}) {},
-			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, mockClock *clock.Mock) {
+			prepareMockService: func(*MockService, *serviceControl) {},
+			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, _ *clock.Mock, _ *serviceControl, done <-chan struct{}) {
 				contextAndCancel.cncl()
 
-				mockClock.Add(time.Second)
+				waitForStop(t, done)
 
 				conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
 				assert.ErrorContains(t, err, "connect: connection refused")
@@ -139,17 +203,13 @@ func Test_GracefulShutdown(t *testing.T) {
 			},
 		},
 		{
-			name: "Accept Request From Existing Connection During Grace Period",
-			prepareMockService: func(mockService *MockService, _ chan struct{}) {
-				mockService.EXPECT().
-					Process(1).
-					Return(nil)
-
+			name: "Close Idle Connection Without Waiting Grace Period",
+			prepareMockService: func(mockService *MockService, _ *serviceControl) {
 				mockService.EXPECT().
-					Process(2).
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
 					Return(nil)
 			},
-			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, mockClock *clock.Mock) {
+			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, _ *clock.Mock, _ *serviceControl, done <-chan struct{}) {
 				conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
 				require.NoError(t, err)
 				defer conn.Close() //nolint:errcheck
@@ -164,28 +224,24 @@ func Test_GracefulShutdown(t *testing.T) {
 
 				contextAndCancel.cncl()
 
-				mockClock.Add(time.Second)
-
-				_, err = conn.Write([]byte("PAYMENT|2\n"))
-				require.NoError(t, err)
+				waitForStop(t, done)
 
-				secondResponse := make([]byte, 1024)
-				_, err = conn.Read(secondResponse)
-				require.NoError(t, err)
-				require.Contains(t, string(secondResponse), "RESPONSE|ACCEPTED|Transaction processed")
+				_, err = conn.Read(make([]byte, 1024))
+				require.ErrorIs(t, err, io.EOF)
 			},
 		},
 		{
-			name: "Request Not Processed During Grace Period",
-			prepareMockService: func(mockService *MockService, c chan struct{}) {
+			name: "Complete In-Flight Request Without Waiting Grace Period",
+			prepareMockService: func(mockService *MockService, control *serviceControl) {
 				mockService.EXPECT().
-					Process(1).
-					RunAndReturn(func(int) error {
-						<-c
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					RunAndReturn(func(context.Context, simulator.Payment) error {
+						close(control.started)
+						<-control.release
 						return nil
 					})
 			},
-			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, mockClock *clock.Mock) {
+			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, _ *clock.Mock, control *serviceControl, done <-chan struct{}) {
 				conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
 				require.NoError(t, err)
 				defer conn.Close() //nolint:errcheck
@@ -193,14 +249,62 @@ func Test_GracefulShutdown(t *testing.T) {
 				_, err = conn.Write([]byte("PAYMENT|1\n"))
 				require.NoError(t, err)
 
+				<-control.started
 				contextAndCancel.cncl()
-				mockClock.Add(time.Second)
-				mockClock.Add(time.Second)
+
+				select {
+				case <-done:
+					require.Fail(t, "transport stopped before in-flight request is completed")
+				case <-time.After(50 * time.Millisecond):
+				}
+
+				close(control.release)
 
 				response := make([]byte, 1024)
 				_, err = conn.Read(response)
 				require.NoError(t, err)
-				require.Contains(t, string(response), "RESPONSE|REJECTED|Cancelled")
+				require.Contains(t, string(response), "RESPONSE|ACCEPTED|Transaction processed")
+
+				waitForStop(t, done)
+
+				_, err = conn.Read(make([]byte, 1024))
+				require.ErrorIs(t, err, io.EOF)
+			},
+		},
+		{
+			name: "Request Not Processed During Grace Period",
+			prepareMockService: func(mockService *MockService, control *serviceControl) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					RunAndReturn(func(ctx context.Context, _ simulator.Payment) error {
+						close(control.started)
+						<-ctx.Done()
+						return ctx.Err()
+					})
+			},
+			run: func(t *testing.T, port int, contextAndCancel *contextAndCancel, mockClock *clock.Mock, control *serviceControl, done <-chan struct{}) {
+				conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+				require.NoError(t, err)
+				defer conn.Close() //nolint:errcheck
+
+				_, err = conn.Write([]byte("PAYMENT|1\n"))
+				require.NoError(t, err)
+
+				<-control.started
+				contextAndCancel.cncl()
+
+				responseChan := readAsync(conn)
+
+				select {
+				case <-done:
+					require.Fail(t, "transport stopped before grace period is finished")
+				case <-time.After(50 * time.Millisecond):
+				}
+
+				response := advanceUntil(t, mockClock, responseChan)
+				require.Contains(t, response, "RESPONSE|REJECTED|Cancelled")
+
+				waitForStop(t, done)
 			},
 		},
 	}
@@ -214,10 +318,13 @@ func Test_GracefulShutdown(t *testing.T) {
 				cncl: startCncl,
 			}
 
-			stop := make(chan struct{})
+			control := &serviceControl{
+				started: make(chan struct{}),
+				release: make(chan struct{}),
+			}
 
 			mockService := NewMockService(t)
-			test.prepareMockService(mockService, stop)
+			test.prepareMockService(mockService, control)
 
 			port, err := getFreePort()
 			require.NoError(t, err)
@@ -230,27 +337,94 @@ func Test_GracefulShutdown(t *testing.T) {
 
 			mockClock := clock.NewMock()
 
-			transport := NewTransport(cfg, mockService, mockClock)
-			go transport.Start(startCtx) //nolint:errcheck
+			transport := NewTransport(cfg, mockService, mockClock, metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(startCtx) //nolint:errcheck
+			}()
 
-			test.run(t, port, c, mockClock)
+			waitForServer(t, port)
 
-			mockClock.WaitForAllTimers()
-			close(stop)
+			test.run(t, port, c, mockClock, control, done)
 		})
 	}
 }
 
+// serviceControl allows tests to observe and control a request while it is processed by the mocked service.
+type serviceControl struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+// readAsync reads a single response from the connection in the background.
+func readAsync(conn net.Conn) <-chan string {
+	responseChan := make(chan string, 1)
+
+	go func() {
+		response := make([]byte, 1024)
+		n, _ := conn.Read(response)
+		responseChan <- string(response[:n])
+	}()
+
+	return responseChan
+}
+
+// advanceUntil advances the mocked clock until a value is received from the channel.
+func advanceUntil[T any](t *testing.T, mockClock *clock.Mock, c <-chan T) T {
+	t.Helper()
+
+	var value T
+	require.Eventually(t, func() bool {
+		mockClock.Add(time.Second)
+		select {
+		case value = <-c:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	return value
+}
+
+// waitForStop waits until the transport is stopped without advancing the clock.
+func waitForStop(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "transport did not stop")
+	}
+}
+
 var (
 	freePortMu     sync.Mutex
 	allocatedPorts = make(map[int]struct{})
 )
 
-// getFreePort returns a free port number.
+// getFreePort returns a free port number that wasn't returned before.
 func getFreePort() (int, error) {
 	freePortMu.Lock()
 	defer freePortMu.Unlock()
 
+	for {
+		port, err := listenFreePort()
+		if err != nil {
+			return 0, err
+		}
+
+		if _, exists := allocatedPorts[port]; !exists {
+			allocatedPorts[port] = struct{}{}
+			return port, nil
+		}
+	}
+}
+
+// listenFreePort returns a port number that is free at the time of the call.
+func listenFreePort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
 	if err != nil {
 		return 0, err
@@ -262,15 +436,32 @@ func getFreePort() (int, error) {
 	}
 	defer l.Close() //nolint:errcheck
 
-	port := l.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+	return l.Addr().(*net.TCPAddr).Port, nil //nolint:forcetypeassert
+}
 
-	if _, exists := allocatedPorts[port]; exists {
-		return getFreePort()
-	}
+// waitForServer blocks until the server accepts connections on the given port.
+func waitForServer(t *testing.T, port int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+		if err != nil {
+			return false
+		}
 
-	allocatedPorts[port] = struct{}{}
+		return conn.Close() == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// waitForMetric blocks until the registry exposes the sample.
+func waitForMetric(t *testing.T, registry *metrics.Registry, sample string) {
+	t.Helper()
 
-	return port, nil
+	require.Eventually(t, func() bool {
+		var sb strings.Builder
+		_, err := registry.WriteTo(&sb)
+		return err == nil && strings.Contains(sb.String(), sample+"\n")
+	}, time.Second, time.Millisecond)
 }
 
 type contextAndCancel struct {
@@ -287,19 +478,19 @@ func Test_TwoSimultaneousConnections(t *testing.T) {
 	mockService := NewMockService(t)
 
 	mockService.EXPECT().
-		Process(1).
+		Process(mock.Anything, simulator.Payment{Amount: 1}).
 		Return(nil)
 
 	mockService.EXPECT().
-		Process(2).
+		Process(mock.Anything, simulator.Payment{Amount: 2}).
 		Return(nil)
 
 	mockService.EXPECT().
-		Process(3).
+		Process(mock.Anything, simulator.Payment{Amount: 3}).
 		Return(nil)
 
 	mockService.EXPECT().
-		Process(4).
+		Process(mock.Anything, simulator.Payment{Amount: 4}).
 		Return(nil)
 
 	port, err := getFreePort()
@@ -311,9 +502,11 @@ func Test_TwoSimultaneousConnections(t *testing.T) {
 	}
 
 	mockClock := clock.NewMock()
-	transport := NewTransport(cfg, mockService, mockClock)
+	transport := NewTransport(cfg, mockService, mockClock, metrics.NewRegistry(), nil, nil, nil, nil, nil)
 	go transport.Start(startCtx) //nolint:errcheck
 
+	waitForServer(t, port)
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
@@ -369,3 +562,1507 @@ func Test_TwoSimultaneousConnections(t *testing.T) {
 	wg.Wait()
 	mockClock.WaitForAllTimers()
 }
+
+func Test_GracefulShutdown_CancelsLongRunningRequest(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	startCtx, startCncl := context.WithCancel(context.Background())
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort:                    port,
+		ServerHost:                    "localhost",
+		ServerGracefulShutdownTimeout: time.Second,
+		DummyMinAmountToWait:          100,
+		DummyMaxAmountToWait:          10000,
+	}
+
+	started := make(chan struct{})
+	dummyService := simulator.NewDummyService(cfg)
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 10000}).
+		RunAndReturn(func(ctx context.Context, payment simulator.Payment) error {
+			close(started)
+			return dummyService.Process(ctx, payment)
+		})
+
+	mockClock := clock.NewMock()
+	transport := NewTransport(cfg, mockService, mockClock, metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(startCtx) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	_, err = conn.Write([]byte("PAYMENT|10000\n"))
+	require.NoError(t, err)
+
+	<-started
+	startCncl()
+
+	response := advanceUntil(t, mockClock, readAsync(conn))
+	require.Contains(t, response, "RESPONSE|REJECTED|Cancelled")
+
+	waitForStop(t, done)
+}
+
+func Test_FaultInjection(t *testing.T) {
+	tests := []struct {
+		name               string
+		faults             []simulator.Fault
+		prepareMockService func(*MockService)
+		run                func(*testing.T, net.Conn)
+	}{
+		{
+			name:   "Close connection",
+			faults: []simulator.Fault{simulator.FaultClose},
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(nil)
+			},
+			run: func(t *testing.T, conn net.Conn) {
+				_, err := conn.Write([]byte("PAYMENT|1\n"))
+				require.NoError(t, err)
+
+				_, err = conn.Read(make([]byte, 1024))
+				require.ErrorIs(t, err, io.EOF)
+			},
+		},
+		{
+			name:   "Truncated response",
+			faults: []simulator.Fault{simulator.FaultTruncate},
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(nil)
+			},
+			run: func(t *testing.T, conn net.Conn) {
+				_, err := conn.Write([]byte("PAYMENT|1\n"))
+				require.NoError(t, err)
+
+				out, err := io.ReadAll(conn)
+				require.NoError(t, err)
+				require.Equal(t, "RESPONSE|ACCEPTED|T", string(out))
+			},
+		},
+		{
+			name:   "Response without newline",
+			faults: []simulator.Fault{simulator.FaultNoNewline},
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(nil).
+					Times(2)
+			},
+			run: func(t *testing.T, conn net.Conn) {
+				_, err := conn.Write([]byte("PAYMENT|1\nPAYMENT|1\n"))
+				require.NoError(t, err)
+
+				expected := "RESPONSE|ACCEPTED|Transaction processedRESPONSE|ACCEPTED|Transaction processed"
+
+				out := make([]byte, len(expected))
+				_, err = io.ReadFull(conn, out)
+				require.NoError(t, err)
+				require.Equal(t, expected, string(out))
+			},
+		},
+		{
+			name:   "Stalled response",
+			faults: []simulator.Fault{simulator.FaultStall},
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(nil)
+			},
+			run: func(t *testing.T, conn net.Conn) {
+				_, err := conn.Write([]byte("PAYMENT|1\n"))
+				require.NoError(t, err)
+
+				require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+				_, err = conn.Read(make([]byte, 1024))
+				require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+			},
+		},
+		{
+			name: "Fault from service",
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(simulator.FaultError{Fault: simulator.FaultTruncate, Err: simulator.RejectionError{Reason: "Insufficient funds"}})
+			},
+			run: func(t *testing.T, conn net.Conn) {
+				_, err := conn.Write([]byte("PAYMENT|1\n"))
+				require.NoError(t, err)
+
+				out, err := io.ReadAll(conn)
+				require.NoError(t, err)
+				require.Equal(t, "RESPONSE|REJECTED|", string(out))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			mockService := NewMockService(t)
+			test.prepareMockService(mockService)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:       port,
+				ServerHost:       "localhost",
+				FaultProbability: 1,
+				FaultKinds:       test.faults,
+			}
+
+			transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+			go transport.Start(ctx) //nolint:errcheck
+
+			defer cncl()
+
+			waitForServer(t, port)
+
+			conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+			require.NoError(t, err)
+			defer conn.Close() //nolint:errcheck
+
+			test.run(t, conn)
+		})
+	}
+}
+
+func Test_Drain(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort:                    port,
+		ServerHost:                    "localhost",
+		ServerGracefulShutdownTimeout: time.Second,
+	}
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 1}).
+		Return(nil)
+
+	transport := NewTransport(cfg, mockService, clock.NewMock(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(context.Background()) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	_, err = conn.Write([]byte("PAYMENT|1\n"))
+	require.NoError(t, err)
+	require.Equal(t, "RESPONSE|ACCEPTED|Transaction processed\n", <-readAsync(conn))
+
+	transport.Drain()
+
+	waitForStop(t, done)
+
+	_, err = conn.Read(make([]byte, 1024))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func Test_Metrics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort:                    port,
+		ServerHost:                    "localhost",
+		ServerGracefulShutdownTimeout: time.Second,
+	}
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 1}).
+		Return(nil)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 2}).
+		Return(simulator.ErrDropConnection)
+
+	registry := metrics.NewRegistry()
+	transport := NewTransport(cfg, mockService, clock.New(), registry, nil, nil, nil, nil, nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(ctx) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port)})
+	defer c.Close() //nolint:errcheck
+
+	response, err := c.Send(context.Background(), client.Payment{Amount: 1})
+	require.NoError(t, err)
+	require.True(t, response.Accepted())
+
+	response, err = c.Do(context.Background(), "PAYMENT|abc")
+	require.NoError(t, err)
+	require.Equal(t, "Invalid amount", response.Reason)
+
+	_, err = c.Send(context.Background(), client.Payment{Amount: 2})
+	require.ErrorIs(t, err, client.ErrConnectionClosed)
+
+	cncl()
+	waitForStop(t, done)
+
+	var sb strings.Builder
+	_, err = registry.WriteTo(&sb)
+	require.NoError(t, err)
+
+	// waitForServer opens a connection as well.
+	out := sb.String()
+	assert.Contains(t, out, "simulator_connections_accepted_total 2\n")
+	assert.Contains(t, out, "simulator_connections_closed_total 2\n")
+	assert.Contains(t, out, `simulator_requests_total{status="ACCEPTED",reason="Transaction processed"} 1`+"\n")
+	assert.Contains(t, out, `simulator_requests_total{status="REJECTED",reason="Invalid amount"} 1`+"\n")
+	assert.Contains(t, out, `simulator_requests_total{status="DROPPED",reason=""} 1`+"\n")
+	assert.Contains(t, out, `simulator_parse_failures_total{reason="Invalid amount"} 1`+"\n")
+	assert.Contains(t, out, `simulator_request_duration_seconds_count{status="ACCEPTED"} 1`+"\n")
+	assert.Contains(t, out, `simulator_faults_total{fault="close"} 1`+"\n")
+	assert.Contains(t, out, "simulator_requests_in_flight 0\n")
+}
+
+func Test_ConnectionLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy simulator.ConnectionLimitPolicy
+		run    func(t *testing.T, registry *metrics.Registry, first net.Conn, second net.Conn)
+	}{
+		{
+			name:   "Reject",
+			policy: simulator.ConnectionLimitReject,
+			run: func(t *testing.T, registry *metrics.Registry, _ net.Conn, second net.Conn) {
+				_, err := second.Read(make([]byte, 1024))
+				require.ErrorIs(t, err, io.EOF)
+
+				waitForMetric(t, registry, "simulator_connections_rejected_total 1")
+			},
+		},
+		{
+			name:   "Queue",
+			policy: simulator.ConnectionLimitQueue,
+			run: func(t *testing.T, registry *metrics.Registry, first net.Conn, second net.Conn) {
+				_, err := second.Write([]byte("PAYMENT|2\n"))
+				require.NoError(t, err)
+
+				waitForMetric(t, registry, "simulator_connections_queued 1")
+
+				responseChan := readAsync(second)
+				select {
+				case response := <-responseChan:
+					require.Failf(t, "queued connection is served", "response: %q", response)
+				case <-time.After(50 * time.Millisecond):
+				}
+
+				require.NoError(t, first.Close())
+
+				require.Equal(t, "RESPONSE|ACCEPTED|Transaction processed\n", <-responseChan)
+				waitForMetric(t, registry, "simulator_connections_queued 0")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:                    port,
+				ServerHost:                    "localhost",
+				ServerGracefulShutdownTimeout: time.Second,
+				ServerMaxConnections:          1,
+				ServerConnectionLimitPolicy:   test.policy,
+			}
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().
+				Process(mock.Anything, mock.Anything).
+				Return(nil)
+
+			registry := metrics.NewRegistry()
+			transport := NewTransport(cfg, mockService, clock.New(), registry, nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+			// waitForServer opens a connection as well.
+			waitForMetric(t, registry, "simulator_connections_closed_total 1")
+
+			first, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+			require.NoError(t, err)
+			defer first.Close() //nolint:errcheck
+
+			_, err = first.Write([]byte("PAYMENT|1\n"))
+			require.NoError(t, err)
+			require.Equal(t, "RESPONSE|ACCEPTED|Transaction processed\n", <-readAsync(first))
+
+			second, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+			require.NoError(t, err)
+			defer second.Close() //nolint:errcheck
+
+			test.run(t, registry, first, second)
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+func Test_Calendar(t *testing.T) {
+	tests := []struct {
+		name    string
+		closure simulator.Closure
+		run     func(t *testing.T, registry *metrics.Registry, conn net.Conn)
+	}{
+		{
+			name:    "Refuse",
+			closure: simulator.Closure{Action: simulator.ClosureRefuse, Reason: "maintenance"},
+			run: func(t *testing.T, registry *metrics.Registry, conn net.Conn) {
+				_, err := conn.Read(make([]byte, 1024))
+				require.ErrorIs(t, err, io.EOF)
+
+				waitForMetric(t, registry, "simulator_connections_refused_total 1")
+			},
+		},
+		{
+			name:    "Reject",
+			closure: simulator.Closure{Action: simulator.ClosureReject, Reason: "maintenance"},
+			run: func(t *testing.T, _ *metrics.Registry, conn net.Conn) {
+				_, err := conn.Write([]byte("PAYMENT|1\n"))
+				require.NoError(t, err)
+				require.Equal(t, "RESPONSE|REJECTED|Maintenance\n", <-readAsync(conn))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:                    port,
+				ServerHost:                    "localhost",
+				ServerGracefulShutdownTimeout: time.Second,
+			}
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().
+				Process(mock.Anything, mock.Anything).
+				Return(simulator.ClosedError{Reason: test.closure.Reason}).
+				Maybe()
+
+			mockCalendar := NewMockCalendar(t)
+			// The connection opened by waitForServer is served while the scheme is open.
+			mockCalendar.EXPECT().ClosureAt(mock.Anything).Return(simulator.Closure{}, false).Once()
+			mockCalendar.EXPECT().ClosureAt(mock.Anything).Return(test.closure, true).Once()
+
+			registry := metrics.NewRegistry()
+			transport := NewTransport(cfg, mockService, clock.New(), registry, nil, nil, nil, nil, mockCalendar)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+			waitForMetric(t, registry, "simulator_connections_closed_total 1")
+
+			conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+			require.NoError(t, err)
+			defer conn.Close() //nolint:errcheck
+
+			test.run(t, registry, conn)
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+func Test_Throttle(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  simulator.Config
+		run  func(t *testing.T, registry *metrics.Registry, port int)
+	}{
+		{
+			name: "Reject",
+			cfg:  simulator.Config{ServerConnectionRateLimit: 1, ServerConnectionRateBurst: 1, ServerThrottlePolicy: simulator.ThrottleReject},
+			run: func(t *testing.T, registry *metrics.Registry, port int) {
+				lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP", "PAYMENT|abc-2|1|GBP")
+				assert.Equal(t, "RESPONSE|abc-1|ACCEPTED|Transaction processed\n", readLine(t, lines))
+				assert.Equal(t, "RESPONSE|abc-2|REJECTED|Throttled\n", readLine(t, lines))
+
+				waitForMetric(t, registry, `simulator_throttled_total{limit="connection"} 1`)
+			},
+		},
+		{
+			name: "Delay",
+			cfg:  simulator.Config{ServerRateLimit: 20, ServerRateBurst: 1, ServerThrottlePolicy: simulator.ThrottleDelay},
+			run: func(t *testing.T, registry *metrics.Registry, port int) {
+				now := time.Now()
+
+				lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP", "PAYMENT|abc-2|1|GBP")
+				assert.Equal(t, "RESPONSE|abc-1|ACCEPTED|Transaction processed\n", readLine(t, lines))
+				assert.Equal(t, "RESPONSE|abc-2|ACCEPTED|Transaction processed\n", readLine(t, lines))
+				assert.GreaterOrEqual(t, time.Since(now), 40*time.Millisecond)
+
+				waitForMetric(t, registry, `simulator_throttled_total{limit="global"} 1`)
+			},
+		},
+		{
+			name: "Disconnect",
+			cfg:  simulator.Config{ServerIPRateLimit: 1, ServerIPRateBurst: 1, ServerThrottlePolicy: simulator.ThrottleDisconnect},
+			run: func(t *testing.T, registry *metrics.Registry, port int) {
+				first := sendLines(t, port, "PAYMENT|abc-1|1|GBP")
+				assert.Equal(t, "RESPONSE|abc-1|ACCEPTED|Transaction processed\n", readLine(t, first))
+
+				second := sendLines(t, port, "PAYMENT|abc-2|1|GBP")
+				_, ok := <-second
+				assert.False(t, ok, "connection must be closed without a response")
+
+				waitForMetric(t, registry, `simulator_throttled_total{limit="ip"} 1`)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := test.cfg
+			cfg.ServerPort = port
+			cfg.ServerHost = "localhost"
+			cfg.ServerGracefulShutdownTimeout = time.Second
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().
+				Process(mock.Anything, mock.Anything).
+				Return(nil)
+
+			registry := metrics.NewRegistry()
+			transport := NewTransport(cfg, mockService, clock.New(), registry, nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+
+			test.run(t, registry, port)
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+func Test_Backpressure(t *testing.T) {
+	tests := []struct {
+		name             string
+		queueTimeout     time.Duration
+		expectedAccepted int
+		expectedBusy     int
+	}{
+		{
+			name:             "Requests wait for a free worker",
+			expectedAccepted: 20,
+		},
+		{
+			name:             "Requests are rejected after the queue timeout",
+			queueTimeout:     50 * time.Millisecond,
+			expectedAccepted: 2,
+			expectedBusy:     18,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:                    port,
+				ServerHost:                    "localhost",
+				ServerGracefulShutdownTimeout: time.Second,
+				ServerWorkers:                 2,
+				ServerWorkerQueueTimeout:      test.queueTimeout,
+			}
+
+			release := make(chan struct{})
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().
+				Process(mock.Anything, mock.Anything).
+				RunAndReturn(func(context.Context, simulator.Payment) error {
+					<-release
+					return nil
+				})
+
+			registry := metrics.NewRegistry()
+			transport := NewTransport(cfg, mockService, clock.New(), registry, nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+
+			c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port), MaxConnections: 20})
+			defer c.Close() //nolint:errcheck
+
+			var (
+				mu        sync.Mutex
+				responses []client.Response
+				wg        sync.WaitGroup
+			)
+			for i := range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					response, err := c.Send(context.Background(), client.Payment{Amount: i + 1})
+					assert.NoError(t, err)
+
+					mu.Lock()
+					responses = append(responses, response)
+					mu.Unlock()
+				}()
+			}
+
+			waitForMetric(t, registry, "simulator_workers_busy 2")
+			if test.queueTimeout == 0 {
+				waitForMetric(t, registry, "simulator_worker_queue_length 18")
+			} else {
+				waitForMetric(t, registry, "simulator_worker_queue_timeouts_total 18")
+			}
+
+			close(release)
+			wg.Wait()
+
+			var accepted, busy int
+			for _, response := range responses {
+				switch {
+				case response.Accepted():
+					accepted++
+				case response.Reason == "Server busy":
+					busy++
+				}
+			}
+			assert.Equal(t, test.expectedAccepted, accepted)
+			assert.Equal(t, test.expectedBusy, busy)
+
+			waitForMetric(t, registry, "simulator_workers_busy 0")
+			waitForMetric(t, registry, "simulator_worker_queue_length 0")
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+func Test_DefaultLimits(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	var cfg simulator.Config
+	require.NoError(t, envconfig.Process("test_default_limits", &cfg))
+	cfg.ServerPort = port
+
+	release := make(chan struct{})
+
+	var processing atomic.Int32
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, mock.Anything).
+		RunAndReturn(func(context.Context, simulator.Payment) error {
+			processing.Add(1)
+			<-release
+			return nil
+		})
+
+	transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(ctx) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	const requests = 150
+
+	c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port), MaxConnections: requests})
+	defer c.Close() //nolint:errcheck
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			response, err := c.Send(context.Background(), client.Payment{Amount: i + 1})
+			assert.NoError(t, err)
+			assert.True(t, response.Accepted())
+		}()
+	}
+
+	// Without limits by default, every request is processed at once instead of waiting for a worker.
+	require.Eventually(t, func() bool {
+		return processing.Load() == requests
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	cncl()
+	waitForStop(t, done)
+}
+
+func Test_Timeouts(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            simulator.Config
+		request        string
+		expectedReason string
+	}{
+		{
+			name:           "Idle timeout",
+			cfg:            simulator.Config{ServerIdleTimeout: 50 * time.Millisecond},
+			expectedReason: "idle timeout",
+		},
+		{
+			name:           "Read timeout",
+			cfg:            simulator.Config{ServerReadTimeout: 50 * time.Millisecond},
+			request:        "PAYMENT|1",
+			expectedReason: "read timeout",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := test.cfg
+			cfg.ServerPort = port
+			cfg.ServerHost = "localhost"
+			cfg.ServerGracefulShutdownTimeout = time.Second
+
+			registry := metrics.NewRegistry()
+			transport := NewTransport(cfg, NewMockService(t), clock.New(), registry, nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+
+			conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+			require.NoError(t, err)
+			defer conn.Close() //nolint:errcheck
+
+			_, err = conn.Write([]byte(test.request))
+			require.NoError(t, err)
+
+			select {
+			case response := <-readAsync(conn):
+				require.Empty(t, response)
+			case <-time.After(time.Second):
+				require.Fail(t, "connection is not closed")
+			}
+
+			waitForMetric(t, registry, fmt.Sprintf(`simulator_connection_timeouts_total{reason=%q} 1`, test.expectedReason))
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+func Test_Journal(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort:                    port,
+		ServerHost:                    "localhost",
+		ServerGracefulShutdownTimeout: time.Second,
+	}
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 1}).
+		Return(nil)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{ID: "abc-1", Amount: 2, Currency: "GBP"}).
+		Return(simulator.ErrInsufficientFunds)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 3}).
+		Return(simulator.ErrDropConnection)
+
+	entries := make(chan journal.Entry, 4)
+	mockJournal := NewMockJournal(t)
+	mockJournal.EXPECT().
+		Record(mock.Anything).
+		Run(func(entry journal.Entry) {
+			entries <- entry
+		})
+
+	mockClock := clock.NewMock()
+	transport := NewTransport(cfg, mockService, mockClock, metrics.NewRegistry(), mockJournal, nil, nil, nil, nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(ctx) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port)})
+	defer c.Close() //nolint:errcheck
+
+	_, err = c.Send(context.Background(), client.Payment{Amount: 1})
+	require.NoError(t, err)
+	_, err = c.Do(context.Background(), "HELLO")
+	require.NoError(t, err)
+	_, err = c.Send(context.Background(), client.Payment{ID: "abc-1", Amount: 2, Currency: "GBP"})
+	require.NoError(t, err)
+	_, err = c.Send(context.Background(), client.Payment{Amount: 3})
+	require.ErrorIs(t, err, client.ErrConnectionClosed)
+
+	cncl()
+	waitForStop(t, done)
+	close(entries)
+
+	var actual []journal.Entry
+	for entry := range entries {
+		require.NotEmpty(t, entry.Remote)
+		entry.Remote = ""
+		actual = append(actual, entry)
+	}
+
+	now := mockClock.Now()
+	assert.Equal(t, []journal.Entry{
+		{ConnectionID: 2, ReceivedAt: now, RespondedAt: now, Request: "PAYMENT|1", Amount: 1, Status: "ACCEPTED",
+			Reason: "Transaction processed", Code: "OK", Response: "RESPONSE|ACCEPTED|Transaction processed\n", Outcome: journal.OutcomeDelivered},
+		{ConnectionID: 2, ReceivedAt: now, RespondedAt: now, Request: "HELLO", ParseError: "Invalid request", Status: "REJECTED",
+			Reason: "Invalid request", Code: "INVALID_REQUEST", Response: "RESPONSE|REJECTED|Invalid request\n", Outcome: journal.OutcomeDelivered},
+		{ConnectionID: 2, ReceivedAt: now, RespondedAt: now, Request: "PAYMENT|abc-1|2|GBP", PaymentID: "abc-1", Amount: 2, Currency: "GBP",
+			Status: "REJECTED", Reason: "Insufficient funds", Code: "INSUFFICIENT_FUNDS", Response: "RESPONSE|abc-1|REJECTED|Insufficient funds\n", Outcome: journal.OutcomeDelivered},
+		{ConnectionID: 2, ReceivedAt: now, RespondedAt: now, Request: "PAYMENT|3", Amount: 3, Status: "DROPPED",
+			Outcome: string(simulator.FaultClose)},
+	}, actual)
+}
+
+func Test_Recorder(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort:                    port,
+		ServerHost:                    "localhost",
+		ServerGracefulShutdownTimeout: time.Second,
+	}
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, mock.Anything).
+		Return(nil)
+
+	mockClock := clock.NewMock()
+
+	type record struct {
+		connectionID uint64
+		at           time.Time
+		line         string
+	}
+	records := make(chan record, 3)
+	mockRecorder := NewMockRecorder(t)
+	mockRecorder.EXPECT().
+		Record(mock.Anything, mock.Anything, mock.Anything).
+		Run(func(connectionID uint64, at time.Time, line string) {
+			records <- record{connectionID: connectionID, at: at, line: line}
+		})
+
+	transport := NewTransport(cfg, mockService, mockClock, metrics.NewRegistry(), nil, mockRecorder, nil, nil, nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(ctx) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	for _, lines := range [][]string{{"PAYMENT|1", "HELLO"}, {"PAYMENT|2"}} {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+		require.NoError(t, err)
+
+		for _, line := range lines {
+			_, err = conn.Write([]byte(line + "\n"))
+			require.NoError(t, err)
+			require.NotEmpty(t, <-readAsync(conn))
+		}
+
+		require.NoError(t, conn.Close())
+	}
+
+	cncl()
+	waitForStop(t, done)
+	close(records)
+
+	var actual []record
+	for r := range records {
+		actual = append(actual, r)
+	}
+
+	// waitForServer opens the first connection.
+	now := mockClock.Now()
+	assert.Equal(t, []record{
+		{connectionID: 2, at: now, line: "PAYMENT|1"},
+		{connectionID: 2, at: now, line: "HELLO"},
+		{connectionID: 3, at: now, line: "PAYMENT|2"},
+	}, actual)
+}
+
+func Test_Status(t *testing.T) {
+	tests := []struct {
+		name             string
+		disabled         bool
+		prepareMock      func(*MockStatuses)
+		expectedResponse client.Response
+	}{
+		{
+			name: "Accepted payment",
+			prepareMock: func(mockStatuses *MockStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(simulator.PaymentStatus{Payment: simulator.Payment{ID: "abc-1"}}, true)
+			},
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusAccepted, Reason: "Transaction processed"},
+		},
+		{
+			name: "Rejected payment",
+			prepareMock: func(mockStatuses *MockStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(simulator.PaymentStatus{Err: simulator.ErrInsufficientFunds}, true)
+			},
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusRejected, Reason: "Insufficient funds"},
+		},
+		{
+			name: "Accepted payment with corrupted response",
+			prepareMock: func(mockStatuses *MockStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(simulator.PaymentStatus{Err: simulator.FaultError{Fault: simulator.FaultTruncate}}, true)
+			},
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusAccepted, Reason: "Transaction processed"},
+		},
+		{
+			name: "Pending payment",
+			prepareMock: func(mockStatuses *MockStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(simulator.PaymentStatus{Pending: true}, true)
+			},
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusPending, Reason: "Payment in progress"},
+		},
+		{
+			name: "Unknown payment",
+			prepareMock: func(mockStatuses *MockStatuses) {
+				mockStatuses.EXPECT().Status("abc-1").Return(simulator.PaymentStatus{}, false)
+			},
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusUnknown, Reason: "Payment not found"},
+		},
+		{
+			name:             "Statuses are disabled",
+			disabled:         true,
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusUnknown, Reason: "Payment not found"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			var statuses Statuses
+			if !test.disabled {
+				mockStatuses := NewMockStatuses(t)
+				test.prepareMock(mockStatuses)
+				statuses = mockStatuses
+			}
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort: port,
+				ServerHost: "localhost",
+			}
+
+			transport := NewTransport(cfg, NewMockService(t), clock.New(), metrics.NewRegistry(), nil, nil, statuses, nil, nil)
+			go transport.Start(ctx) //nolint:errcheck
+
+			defer cncl()
+
+			waitForServer(t, port)
+
+			c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port)})
+			defer c.Close() //nolint:errcheck
+
+			response, err := c.Status(context.Background(), "abc-1")
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func Test_ReasonCodes(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          string
+		err              error
+		expectedResponse client.Response
+	}{
+		{
+			name:             "Accepted",
+			request:          "PAYMENT|abc-1|1|GBP",
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusAccepted, Code: "OK", Reason: "Transaction processed"},
+		},
+		{
+			name:             "Catalogued error",
+			request:          "PAYMENT|abc-1|1|GBP",
+			err:              simulator.ErrInsufficientFunds,
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusRejected, Code: "INSUFFICIENT_FUNDS", Reason: "Insufficient funds"},
+		},
+		{
+			name:             "Rejection",
+			request:          "PAYMENT|abc-1|1|GBP",
+			err:              simulator.RejectionError{Reason: "Closed"},
+			expectedResponse: client.Response{PaymentID: "abc-1", Status: client.StatusRejected, Code: "REJECTED", Reason: "Closed"},
+		},
+		{
+			name:             "Invalid request",
+			request:          "HELLO",
+			expectedResponse: client.Response{Status: client.StatusRejected, Code: "INVALID_REQUEST", Reason: "Invalid request"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			mockService := NewMockService(t)
+			if test.request != "HELLO" {
+				mockService.EXPECT().Process(mock.Anything, mock.Anything).Return(test.err)
+			}
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:        port,
+				ServerHost:        "localhost",
+				ServerReasonCodes: true,
+			}
+
+			transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+			go transport.Start(ctx) //nolint:errcheck
+
+			defer cncl()
+
+			waitForServer(t, port)
+
+			c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port), ReasonCodes: true})
+			defer c.Close() //nolint:errcheck
+
+			response, err := c.Do(context.Background(), test.request)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func Test_Recall(t *testing.T) {
+	recall := simulator.Recall{PaymentID: "abc-1", Reason: "Fraud"}
+
+	tests := []struct {
+		name             string
+		disabled         bool
+		prepareMock      func(*MockRecaller)
+		expectedResponse string
+	}{
+		{
+			name: "Accepted recall",
+			prepareMock: func(mockRecaller *MockRecaller) {
+				mockRecaller.EXPECT().Recall(mock.Anything, recall).Return(nil)
+			},
+			expectedResponse: "RESPONSE|abc-1|ACCEPTED|Recall accepted\n",
+		},
+		{
+			name: "Rejected recall",
+			prepareMock: func(mockRecaller *MockRecaller) {
+				mockRecaller.EXPECT().Recall(mock.Anything, recall).Return(simulator.ErrAlreadySettled)
+			},
+			expectedResponse: "RESPONSE|abc-1|REJECTED|Already settled\n",
+		},
+		{
+			name:             "Recalls are disabled",
+			disabled:         true,
+			expectedResponse: "RESPONSE|abc-1|REJECTED|Payment not found\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			var recaller Recaller
+			if !test.disabled {
+				mockRecaller := NewMockRecaller(t)
+				test.prepareMock(mockRecaller)
+				recaller = mockRecaller
+			}
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort: port,
+				ServerHost: "localhost",
+			}
+
+			transport := NewTransport(cfg, NewMockService(t), clock.New(), metrics.NewRegistry(), nil, nil, nil, recaller, nil)
+			go transport.Start(ctx) //nolint:errcheck
+
+			defer cncl()
+
+			waitForServer(t, port)
+
+			conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+			require.NoError(t, err)
+			defer conn.Close() //nolint:errcheck
+
+			_, err = conn.Write([]byte("RECALL|abc-1|Fraud\n"))
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedResponse, <-readAsync(conn))
+		})
+	}
+}
+
+func Test_Recall_OneRequestAtATime(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort: port,
+		ServerHost: "localhost",
+	}
+
+	mockRecaller := NewMockRecaller(t)
+	mockRecaller.EXPECT().
+		Recall(mock.Anything, simulator.Recall{PaymentID: "abc-1", Reason: "Fraud"}).
+		RunAndReturn(func(context.Context, simulator.Recall) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, simulator.Payment{Amount: 1}).
+		Return(nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, mockRecaller, nil)
+	go transport.Start(ctx) //nolint:errcheck
+
+	defer cncl()
+
+	waitForServer(t, port)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	_, err = conn.Write([]byte("RECALL|abc-1|Fraud\nPAYMENT|1\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{"RESPONSE|abc-1|ACCEPTED|Recall accepted\n", "RESPONSE|ACCEPTED|Transaction processed\n"} {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, expected, line)
+	}
+}
+
+func Test_AsyncSettlement(t *testing.T) {
+	payment := simulator.Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}
+
+	tests := []struct {
+		name               string
+		async              bool
+		prepareMockService func(*MockService)
+		run                func(*testing.T, int)
+	}{
+		{
+			name:  "Accepted payment is notified as settled on the same connection",
+			async: true,
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil)
+			},
+			run: func(t *testing.T, port int) {
+				lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP")
+				assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, lines))
+				assert.Equal(t, "NOTIFY|abc-1|SETTLED|Transaction processed\n", readLine(t, lines))
+			},
+		},
+		{
+			name:  "Rejected payment is notified",
+			async: true,
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(simulator.ErrInsufficientFunds)
+			},
+			run: func(t *testing.T, port int) {
+				lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP")
+				assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, lines))
+				assert.Equal(t, "NOTIFY|abc-1|REJECTED|Insufficient funds\n", readLine(t, lines))
+			},
+		},
+		{
+			name:  "Notification is sent to the registered connection",
+			async: true,
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().Process(mock.Anything, payment).Return(nil)
+			},
+			run: func(t *testing.T, port int) {
+				registered := sendLines(t, port, "REGISTER")
+				assert.Equal(t, "RESPONSE|ACCEPTED|Registered for notifications\n", readLine(t, registered))
+
+				lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP", "PAYMENT|2")
+				assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, lines))
+				assert.Equal(t, "NOTIFY|abc-1|SETTLED|Transaction processed\n", readLine(t, registered))
+				assert.Equal(t, "RESPONSE|ACCEPTED|Transaction processed\n", readLine(t, lines), "legacy payments are answered synchronously")
+			},
+		},
+		{
+			name: "Registration is rejected without asynchronous settlement",
+			prepareMockService: func(*MockService) {
+			},
+			run: func(t *testing.T, port int) {
+				lines := sendLines(t, port, "REGISTER")
+				assert.Equal(t, "RESPONSE|REJECTED|Notifications are disabled\n", readLine(t, lines))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().Process(mock.Anything, simulator.Payment{Amount: 2}).Return(nil).Maybe()
+			test.prepareMockService(mockService)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:            port,
+				ServerHost:            "localhost",
+				ServerAsyncSettlement: test.async,
+			}
+
+			transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+
+			test.run(t, port)
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+func Test_AsyncSettlement_PendingStatus(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	payment := simulator.Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}
+
+	port, err := getFreePort()
+	require.NoError(t, err)
+
+	cfg := simulator.Config{
+		ServerPort:            port,
+		ServerHost:            "localhost",
+		ServerAsyncSettlement: true,
+		StatusRetention:       time.Minute,
+	}
+
+	mockProcessor := NewMockService(t)
+	mockProcessor.EXPECT().Process(mock.Anything, payment).Return(nil)
+
+	statuses := simulator.NewStatusService(cfg, mockProcessor, clock.New())
+
+	// The settlement is held before it reaches the status service, so only the pending status recorded on acknowledgement is known.
+	release := make(chan struct{})
+	mockService := NewMockService(t)
+	mockService.EXPECT().
+		Process(mock.Anything, payment).
+		RunAndReturn(func(ctx context.Context, payment simulator.Payment) error {
+			<-release
+			return statuses.Process(ctx, payment)
+		})
+
+	transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, statuses, nil, nil)
+
+	ctx, cncl := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Start(ctx) //nolint:errcheck
+	}()
+
+	waitForServer(t, port)
+
+	lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP")
+	assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, lines))
+
+	pending := sendLines(t, port, "STATUS|abc-1")
+	assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, pending))
+
+	close(release)
+	assert.Equal(t, "NOTIFY|abc-1|SETTLED|Transaction processed\n", readLine(t, lines))
+
+	settled := sendLines(t, port, "STATUS|abc-1")
+	assert.Equal(t, "RESPONSE|abc-1|ACCEPTED|Transaction processed\n", readLine(t, settled))
+
+	cncl()
+	waitForStop(t, done)
+}
+
+func Test_AsyncSettlement_GracefulShutdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(*testing.T, <-chan string, *clock.Mock, *serviceControl, context.CancelFunc, <-chan struct{})
+		expected []string
+	}{
+		{
+			name: "Settlement completed during grace period is notified",
+			run: func(t *testing.T, lines <-chan string, _ *clock.Mock, control *serviceControl, cncl context.CancelFunc, done <-chan struct{}) {
+				assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, lines))
+
+				<-control.started
+				cncl()
+
+				select {
+				case <-done:
+					require.Fail(t, "transport stopped before pending settlement is notified")
+				case <-time.After(50 * time.Millisecond):
+				}
+
+				close(control.release)
+
+				assert.Equal(t, "NOTIFY|abc-1|SETTLED|Transaction processed\n", readLine(t, lines))
+				waitForStop(t, done)
+			},
+		},
+		{
+			name: "Settlement outlasting grace period is notified as cancelled",
+			run: func(t *testing.T, lines <-chan string, mockClock *clock.Mock, control *serviceControl, cncl context.CancelFunc, done <-chan struct{}) {
+				assert.Equal(t, "RESPONSE|abc-1|PENDING|Payment in progress\n", readLine(t, lines))
+
+				<-control.started
+				cncl()
+
+				assert.Equal(t, "NOTIFY|abc-1|REJECTED|Cancelled\n", advanceUntil(t, mockClock, lines))
+				waitForStop(t, done)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			control := &serviceControl{started: make(chan struct{}), release: make(chan struct{})}
+
+			mockService := NewMockService(t)
+			mockService.EXPECT().
+				Process(mock.Anything, simulator.Payment{ID: "abc-1", Amount: 1, Currency: "GBP"}).
+				RunAndReturn(func(ctx context.Context, _ simulator.Payment) error {
+					close(control.started)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-control.release:
+						return nil
+					}
+				})
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:                    port,
+				ServerHost:                    "localhost",
+				ServerGracefulShutdownTimeout: 5 * time.Second,
+				ServerAsyncSettlement:         true,
+			}
+
+			mockClock := clock.NewMock()
+			transport := NewTransport(cfg, mockService, mockClock, metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+			defer cncl()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+
+			lines := sendLines(t, port, "PAYMENT|abc-1|1|GBP")
+
+			test.run(t, lines, mockClock, control, cncl, done)
+
+			_, ok := <-lines
+			assert.False(t, ok, "connection is closed after the notification")
+		})
+	}
+}
+
+// sendLines opens a connection, writes the lines to it, and returns a channel receiving the lines read from it.
+// The channel is closed once the connection is closed by the server, and the connection is closed when the test ends.
+func sendLines(t *testing.T, port int, lines ...string) <-chan string {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close() //nolint:errcheck
+	})
+
+	for _, line := range lines {
+		_, err = conn.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+
+	received := make(chan string, 10)
+	go func() {
+		defer close(received)
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			received <- line
+		}
+	}()
+
+	return received
+}
+
+// readLine returns the next line received, or fails if no line is received in time.
+func readLine(t *testing.T, lines <-chan string) string {
+	t.Helper()
+
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(time.Second):
+		require.Fail(t, "no line received")
+		return ""
+	}
+}
@@ -0,0 +1,168 @@
+package tcp
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// Names of the rate limits, which are used as metric labels.
+const (
+	limitGlobal     = "global"
+	limitConnection = "connection"
+	limitIP         = "ip"
+)
+
+// tokenBucket allows rate requests per second on average and bursts of up to burst requests.
+// Tokens can go negative when they are reserved for delayed requests.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket. If burst is not positive, it is the rate rounded up, but at least 1.
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(math.Ceil(rate), 1)
+	}
+
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: now}
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns the time until a token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// full returns true if the bucket has all of its tokens, so it behaves as a new bucket.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+
+	return b.tokens >= b.burst
+}
+
+// throttle enforces the global, per connection and per remote IP rate limits.
+type throttle struct {
+	cfg   simulator.Config
+	clock clock.Clock
+
+	mu     sync.Mutex
+	global *tokenBucket
+	ips    map[string]*tokenBucket
+}
+
+// newThrottle creates a new throttle instance. It returns nil if no rate limit is configured.
+func newThrottle(cfg simulator.Config, clock clock.Clock) *throttle {
+	if cfg.ServerRateLimit <= 0 && cfg.ServerConnectionRateLimit <= 0 && cfg.ServerIPRateLimit <= 0 {
+		return nil
+	}
+
+	t := &throttle{cfg: cfg, clock: clock, ips: make(map[string]*tokenBucket)}
+	if cfg.ServerRateLimit > 0 {
+		t.global = newTokenBucket(cfg.ServerRateLimit, cfg.ServerRateBurst, clock.Now())
+	}
+
+	return t
+}
+
+// connectionBucket creates the bucket limiting the requests of a connection, or returns nil if they aren't limited.
+func (t *throttle) connectionBucket() *tokenBucket {
+	if t == nil || t.cfg.ServerConnectionRateLimit <= 0 {
+		return nil
+	}
+
+	return newTokenBucket(t.cfg.ServerConnectionRateLimit, t.cfg.ServerConnectionRateBurst, t.clock.Now())
+}
+
+// admit takes a token from every bucket limiting a request received on a connection with the bucket from the remote address.
+// If a bucket is empty, the time until the request is allowed is returned with the name of the exceeded limit,
+// and the tokens are only taken if reserve is set, so the request can be delayed instead of rejected.
+func (t *throttle) admit(bucket *tokenBucket, remote net.Addr, reserve bool) (time.Duration, string) {
+	if t == nil {
+		return 0, ""
+	}
+
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buckets := [...]struct {
+		bucket *tokenBucket
+		limit  string
+	}{
+		{bucket: t.global, limit: limitGlobal},
+		{bucket: bucket, limit: limitConnection},
+		{bucket: t.ipBucket(remote, now), limit: limitIP},
+	}
+
+	var (
+		wait  time.Duration
+		limit string
+	)
+	for _, b := range buckets {
+		if b.bucket == nil {
+			continue
+		}
+
+		if w := b.bucket.wait(now); w > wait {
+			wait, limit = w, b.limit
+		}
+	}
+
+	if wait == 0 || reserve {
+		for _, b := range buckets {
+			if b.bucket != nil {
+				b.bucket.tokens--
+			}
+		}
+	}
+
+	return wait, limit
+}
+
+// ipBucket returns the bucket limiting the requests from the IP of the remote address, or nil if they aren't limited.
+// Full buckets of other IPs are removed when a bucket is created, as they behave as new buckets.
+func (t *throttle) ipBucket(remote net.Addr, now time.Time) *tokenBucket {
+	if t.cfg.ServerIPRateLimit <= 0 {
+		return nil
+	}
+
+	ip := remoteIP(remote)
+
+	if b, ok := t.ips[ip]; ok {
+		return b
+	}
+
+	for other, b := range t.ips {
+		if b.full(now) {
+			delete(t.ips, other)
+		}
+	}
+
+	b := newTokenBucket(t.cfg.ServerIPRateLimit, t.cfg.ServerIPRateBurst, now)
+	t.ips[ip] = b
+
+	return b
+}
@@ -0,0 +1,202 @@
+package tcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_tokenBucket(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		run   func(*testing.T, *tokenBucket)
+	}{
+		{
+			name:  "Burst is available immediately",
+			rate:  1,
+			burst: 3,
+			run: func(t *testing.T, b *tokenBucket) {
+				for range 3 {
+					require.Zero(t, b.wait(now))
+					b.tokens--
+				}
+
+				assert.Equal(t, time.Second, b.wait(now))
+			},
+		},
+		{
+			name: "Burst defaults to the rate",
+			rate: 2.5,
+			run: func(t *testing.T, b *tokenBucket) {
+				assert.InDelta(t, 3, b.burst, 0)
+			},
+		},
+		{
+			name: "Burst is at least one",
+			rate: 0.5,
+			run: func(t *testing.T, b *tokenBucket) {
+				assert.InDelta(t, 1, b.burst, 0)
+			},
+		},
+		{
+			name:  "Tokens are refilled at the rate",
+			rate:  10,
+			burst: 1,
+			run: func(t *testing.T, b *tokenBucket) {
+				b.tokens--
+				assert.Equal(t, 100*time.Millisecond, b.wait(now))
+				assert.Equal(t, 40*time.Millisecond, b.wait(now.Add(60*time.Millisecond)))
+				assert.Zero(t, b.wait(now.Add(100*time.Millisecond)))
+			},
+		},
+		{
+			name:  "Refill is capped at the burst",
+			rate:  10,
+			burst: 2,
+			run: func(t *testing.T, b *tokenBucket) {
+				b.tokens--
+				assert.True(t, b.full(now.Add(time.Hour)))
+				assert.InDelta(t, 2, b.tokens, 0)
+			},
+		},
+		{
+			name:  "Reserved tokens delay later requests",
+			rate:  10,
+			burst: 1,
+			run: func(t *testing.T, b *tokenBucket) {
+				b.tokens -= 3
+				assert.Equal(t, 300*time.Millisecond, b.wait(now))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newTokenBucket(test.rate, test.burst, now))
+		})
+	}
+}
+
+func Test_throttle(t *testing.T) {
+	first := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+	sameIP := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 2000}
+	second := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1000}
+
+	tests := []struct {
+		name string
+		cfg  simulator.Config
+		run  func(*testing.T, *throttle, *clock.Mock)
+	}{
+		{
+			name: "Global limit",
+			cfg:  simulator.Config{ServerRateLimit: 1, ServerRateBurst: 1},
+			run: func(t *testing.T, th *throttle, mockClock *clock.Mock) {
+				assertAdmitted(t, th, th.connectionBucket(), first)
+
+				wait, limit := th.admit(th.connectionBucket(), second, false)
+				assert.Equal(t, time.Second, wait)
+				assert.Equal(t, limitGlobal, limit)
+
+				mockClock.Add(time.Second)
+				assertAdmitted(t, th, th.connectionBucket(), second)
+			},
+		},
+		{
+			name: "Connection limit",
+			cfg:  simulator.Config{ServerConnectionRateLimit: 1, ServerConnectionRateBurst: 1},
+			run: func(t *testing.T, th *throttle, _ *clock.Mock) {
+				bucket := th.connectionBucket()
+				assertAdmitted(t, th, bucket, first)
+
+				wait, limit := th.admit(bucket, first, false)
+				assert.Equal(t, time.Second, wait)
+				assert.Equal(t, limitConnection, limit)
+
+				assertAdmitted(t, th, th.connectionBucket(), first)
+			},
+		},
+		{
+			name: "IP limit",
+			cfg:  simulator.Config{ServerIPRateLimit: 1, ServerIPRateBurst: 1},
+			run: func(t *testing.T, th *throttle, _ *clock.Mock) {
+				assertAdmitted(t, th, th.connectionBucket(), first)
+
+				wait, limit := th.admit(th.connectionBucket(), sameIP, false)
+				assert.Equal(t, time.Second, wait)
+				assert.Equal(t, limitIP, limit)
+
+				assertAdmitted(t, th, th.connectionBucket(), second)
+			},
+		},
+		{
+			name: "Rejected requests don't take tokens",
+			cfg:  simulator.Config{ServerRateLimit: 1, ServerRateBurst: 2, ServerIPRateLimit: 1, ServerIPRateBurst: 1},
+			run: func(t *testing.T, th *throttle, _ *clock.Mock) {
+				assertAdmitted(t, th, nil, first)
+
+				wait, limit := th.admit(nil, first, false)
+				assert.Equal(t, time.Second, wait)
+				assert.Equal(t, limitIP, limit)
+
+				assertAdmitted(t, th, nil, second)
+			},
+		},
+		{
+			name: "Reserved requests take tokens",
+			cfg:  simulator.Config{ServerRateLimit: 10, ServerRateBurst: 1},
+			run: func(t *testing.T, th *throttle, _ *clock.Mock) {
+				assertAdmitted(t, th, nil, first)
+
+				wait, _ := th.admit(nil, first, true)
+				assert.Equal(t, 100*time.Millisecond, wait)
+
+				wait, _ = th.admit(nil, first, true)
+				assert.Equal(t, 200*time.Millisecond, wait)
+			},
+		},
+		{
+			name: "Full IP buckets are removed",
+			cfg:  simulator.Config{ServerIPRateLimit: 1, ServerIPRateBurst: 1},
+			run: func(t *testing.T, th *throttle, mockClock *clock.Mock) {
+				assertAdmitted(t, th, nil, first)
+				require.Len(t, th.ips, 1)
+
+				mockClock.Add(time.Second)
+				assertAdmitted(t, th, nil, second)
+				assert.Len(t, th.ips, 1)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockClock := clock.NewMock()
+			test.run(t, newThrottle(test.cfg, mockClock), mockClock)
+		})
+	}
+}
+
+func Test_throttle_Unlimited(t *testing.T) {
+	th := newThrottle(simulator.Config{}, clock.NewMock())
+	assert.Nil(t, th)
+	assert.Nil(t, th.connectionBucket())
+
+	assertAdmitted(t, th, nil, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000})
+}
+
+// assertAdmitted asserts that the request from the remote address isn't throttled.
+func assertAdmitted(t *testing.T, th *throttle, bucket *tokenBucket, remote net.Addr) {
+	t.Helper()
+
+	wait, limit := th.admit(bucket, remote, false)
+	assert.Zero(t, wait)
+	assert.Empty(t, limit)
+}
@@ -0,0 +1,78 @@
+package tcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+// newTLSConfig creates the TLS configuration of the listener from the configuration.
+// It returns nil if no server certificate is configured, in which case TLS is disabled.
+func newTLSConfig(cfg simulator.Config) (*tls.Config, error) {
+	if cfg.ServerTLSCertFile == "" && cfg.ServerTLSKeyFile == "" {
+		if cfg.ServerTLSClientCAFile != "" || (cfg.ServerTLSClientAuth != "" && cfg.ServerTLSClientAuth != simulator.ClientAuthNone) {
+			return nil, errors.New("client authentication requires a server certificate and key")
+		}
+
+		return nil, nil //nolint:nilnil // TLS is disabled.
+	}
+
+	if cfg.ServerTLSCertFile == "" || cfg.ServerTLSKeyFile == "" {
+		return nil, errors.New("TLS requires both a server certificate and key")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.ServerTLSCertFile, cfg.ServerTLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch cfg.ServerTLSClientAuth {
+	case "", simulator.ClientAuthNone:
+		tlsConfig.ClientAuth = tls.NoClientCert
+	case simulator.ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case simulator.ClientAuthRequired:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth %q", cfg.ServerTLSClientAuth)
+	}
+
+	if tlsConfig.ClientAuth == tls.NoClientCert {
+		return tlsConfig, nil
+	}
+
+	if cfg.ServerTLSClientCAFile == "" {
+		return nil, fmt.Errorf("client auth %q requires a client CA file", cfg.ServerTLSClientAuth)
+	}
+
+	pem, err := os.ReadFile(cfg.ServerTLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read client CA file: %w", err)
+	}
+
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("client CA file doesn't contain a PEM certificate")
+	}
+
+	return tlsConfig, nil
+}
+
+// clientSubject returns the subject of the certificate presented by the client of the TLS connection.
+// It returns false if the client didn't present a certificate.
+func clientSubject(state tls.ConnectionState) (string, bool) {
+	if len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+
+	return state.PeerCertificates[0].Subject.String(), true
+}
@@ -0,0 +1,338 @@
+package tcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/client"
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
+)
+
+func Test_newTLSConfig(t *testing.T) {
+	pki := newTestPKI(t)
+
+	tests := []struct {
+		name       string
+		cfg        simulator.Config
+		assertFunc func(*testing.T, *tls.Config, error)
+	}{
+		{
+			name: "TLS disabled",
+			cfg:  simulator.Config{ServerTLSClientAuth: simulator.ClientAuthNone},
+			assertFunc: func(t *testing.T, tlsConfig *tls.Config, err error) {
+				require.NoError(t, err)
+				assert.Nil(t, tlsConfig)
+			},
+		},
+		{
+			name: "TLS without client authentication",
+			cfg:  simulator.Config{ServerTLSCertFile: pki.serverCertFile, ServerTLSKeyFile: pki.serverKeyFile},
+			assertFunc: func(t *testing.T, tlsConfig *tls.Config, err error) {
+				require.NoError(t, err)
+				assert.Len(t, tlsConfig.Certificates, 1)
+				assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+				assert.Nil(t, tlsConfig.ClientCAs)
+			},
+		},
+		{
+			name: "Optional client authentication",
+			cfg: simulator.Config{
+				ServerTLSCertFile:     pki.serverCertFile,
+				ServerTLSKeyFile:      pki.serverKeyFile,
+				ServerTLSClientCAFile: pki.caFile,
+				ServerTLSClientAuth:   simulator.ClientAuthOptional,
+			},
+			assertFunc: func(t *testing.T, tlsConfig *tls.Config, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+				assert.NotNil(t, tlsConfig.ClientCAs)
+			},
+		},
+		{
+			name: "Required client authentication",
+			cfg: simulator.Config{
+				ServerTLSCertFile:     pki.serverCertFile,
+				ServerTLSKeyFile:      pki.serverKeyFile,
+				ServerTLSClientCAFile: pki.caFile,
+				ServerTLSClientAuth:   simulator.ClientAuthRequired,
+			},
+			assertFunc: func(t *testing.T, tlsConfig *tls.Config, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+			},
+		},
+		{
+			name: "Client authentication without server certificate",
+			cfg:  simulator.Config{ServerTLSClientCAFile: pki.caFile, ServerTLSClientAuth: simulator.ClientAuthRequired},
+			assertFunc: func(t *testing.T, _ *tls.Config, err error) {
+				assert.EqualError(t, err, "client authentication requires a server certificate and key")
+			},
+		},
+		{
+			name: "Certificate without key",
+			cfg:  simulator.Config{ServerTLSCertFile: pki.serverCertFile},
+			assertFunc: func(t *testing.T, _ *tls.Config, err error) {
+				assert.EqualError(t, err, "TLS requires both a server certificate and key")
+			},
+		},
+		{
+			name: "Missing certificate file",
+			cfg:  simulator.Config{ServerTLSCertFile: "missing.pem", ServerTLSKeyFile: pki.serverKeyFile},
+			assertFunc: func(t *testing.T, _ *tls.Config, err error) {
+				assert.ErrorContains(t, err, "can't load server certificate")
+			},
+		},
+		{
+			name: "Client authentication without client CA",
+			cfg: simulator.Config{
+				ServerTLSCertFile:   pki.serverCertFile,
+				ServerTLSKeyFile:    pki.serverKeyFile,
+				ServerTLSClientAuth: simulator.ClientAuthRequired,
+			},
+			assertFunc: func(t *testing.T, _ *tls.Config, err error) {
+				assert.EqualError(t, err, `client auth "required" requires a client CA file`)
+			},
+		},
+		{
+			name: "Client CA file without certificate",
+			cfg: simulator.Config{
+				ServerTLSCertFile:     pki.serverCertFile,
+				ServerTLSKeyFile:      pki.serverKeyFile,
+				ServerTLSClientCAFile: pki.serverKeyFile,
+				ServerTLSClientAuth:   simulator.ClientAuthRequired,
+			},
+			assertFunc: func(t *testing.T, _ *tls.Config, err error) {
+				assert.EqualError(t, err, "client CA file doesn't contain a PEM certificate")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(test.cfg)
+			test.assertFunc(t, tlsConfig, err)
+		})
+	}
+}
+
+func Test_TLS(t *testing.T) {
+	pki := newTestPKI(t)
+
+	tests := []struct {
+		name               string
+		clientAuth         simulator.ClientAuth
+		clientCertificate  bool
+		prepareMockService func(*MockService)
+		assertFunc         func(*testing.T, client.Response, error)
+	}{
+		{
+			name:       "TLS without client authentication",
+			clientAuth: simulator.ClientAuthNone,
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					RunAndReturn(func(ctx context.Context, _ simulator.Payment) error {
+						_, ok := simulator.ClientSubject(ctx)
+						if ok {
+							return simulator.RejectionError{Reason: "Unexpected client"}
+						}
+						return nil
+					})
+			},
+			assertFunc: func(t *testing.T, response client.Response, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, client.Response{Status: client.StatusAccepted, Reason: "Transaction processed"}, response)
+			},
+		},
+		{
+			name:              "Client certificate subject is passed to service",
+			clientAuth:        simulator.ClientAuthRequired,
+			clientCertificate: true,
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					RunAndReturn(func(ctx context.Context, _ simulator.Payment) error {
+						subject, _ := simulator.ClientSubject(ctx)
+						return simulator.RejectionError{Reason: subject}
+					})
+			},
+			assertFunc: func(t *testing.T, response client.Response, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, client.Response{Status: client.StatusRejected, Reason: "CN=client,O=Form3"}, response)
+			},
+		},
+		{
+			name:               "Client without certificate is rejected",
+			clientAuth:         simulator.ClientAuthRequired,
+			prepareMockService: func(*MockService) {},
+			assertFunc: func(t *testing.T, _ client.Response, err error) {
+				assert.ErrorContains(t, err, "certificate required")
+			},
+		},
+		{
+			name:       "Client without certificate is accepted when client authentication is optional",
+			clientAuth: simulator.ClientAuthOptional,
+			prepareMockService: func(mockService *MockService) {
+				mockService.EXPECT().
+					Process(mock.Anything, simulator.Payment{Amount: 1}).
+					Return(nil)
+			},
+			assertFunc: func(t *testing.T, response client.Response, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, client.Response{Status: client.StatusAccepted, Reason: "Transaction processed"}, response)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			port, err := getFreePort()
+			require.NoError(t, err)
+
+			cfg := simulator.Config{
+				ServerPort:                    port,
+				ServerHost:                    "localhost",
+				ServerGracefulShutdownTimeout: time.Second,
+				ServerTLSCertFile:             pki.serverCertFile,
+				ServerTLSKeyFile:              pki.serverKeyFile,
+				ServerTLSClientCAFile:         pki.caFile,
+				ServerTLSClientAuth:           test.clientAuth,
+			}
+
+			mockService := NewMockService(t)
+			test.prepareMockService(mockService)
+
+			transport := NewTransport(cfg, mockService, clock.New(), metrics.NewRegistry(), nil, nil, nil, nil, nil)
+
+			ctx, cncl := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				transport.Start(ctx) //nolint:errcheck
+			}()
+
+			waitForServer(t, port)
+
+			clientConfig := &tls.Config{
+				RootCAs:    pki.caPool,
+				MinVersion: tls.VersionTLS12,
+			}
+			if test.clientCertificate {
+				clientConfig.Certificates = []tls.Certificate{pki.clientCert}
+			}
+
+			c := client.New(client.Config{Address: fmt.Sprintf("localhost:%d", port), TLSConfig: clientConfig})
+
+			response, err := c.Send(context.Background(), client.Payment{Amount: 1})
+			test.assertFunc(t, response, err)
+
+			require.NoError(t, c.Close())
+
+			cncl()
+			waitForStop(t, done)
+		})
+	}
+}
+
+// testPKI holds a certificate authority with a server and a client certificate issued by it.
+type testPKI struct {
+	caFile         string
+	caPool         *x509.CertPool
+	serverCertFile string
+	serverKeyFile  string
+	clientCert     tls.Certificate
+}
+
+// newTestPKI generates the certificates in memory and writes the files used by the server to a temporary directory.
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca", Organization: []string{"Form3"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, commonName string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Form3"}},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+
+		return der, key
+	}
+
+	serverDER, serverKey := issue(2, "localhost", x509.ExtKeyUsageServerAuth)
+	clientDER, clientKey := issue(3, "client", x509.ExtKeyUsageClientAuth)
+
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, b []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: b}), 0o600))
+
+		return path
+	}
+
+	serverKeyDER, err := x509.MarshalECPrivateKey(serverKey)
+	require.NoError(t, err)
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+
+	return testPKI{
+		caFile:         writePEM("ca.pem", "CERTIFICATE", caDER),
+		caPool:         caPool,
+		serverCertFile: writePEM("server.pem", "CERTIFICATE", serverDER),
+		serverKeyFile:  writePEM("server-key.pem", "EC PRIVATE KEY", serverKeyDER),
+		clientCert: tls.Certificate{
+			Certificate: [][]byte{clientDER},
+			PrivateKey:  clientKey,
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package tcp
+
+import (
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// connectionTracker keeps track of open connections and whether they have a request in progress,
+// and of payments whose settlement is pending.
+// Once draining starts, idle connections are closed when no settlement is pending, as notifications may still be sent to them,
+// and drained is closed when no request is in progress and no settlement is pending.
+type connectionTracker struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	inFlight int
+	pending  int
+	draining bool
+	drained  chan struct{}
+}
+
+// newConnectionTracker creates a new connectionTracker instance.
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{
+		conns:   make(map[net.Conn]bool),
+		drained: make(chan struct{}),
+	}
+}
+
+// add registers a new idle connection. It returns false if the tracker is draining and the connection must not be served.
+func (c *connectionTracker) add(conn net.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return false
+	}
+
+	c.conns[conn] = false
+
+	return true
+}
+
+// remove unregisters the connection, releasing its in-flight request if it had one.
+func (c *connectionTracker) remove(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active, ok := c.conns[conn]
+	if !ok {
+		return
+	}
+
+	delete(c.conns, conn)
+
+	if active {
+		c.inFlight--
+		c.signalDrainedLocked()
+	}
+}
+
+// setActive marks the connection as having a request in progress.
+// It returns false if the tracker is draining, in which case the request must be discarded.
+func (c *connectionTracker) setActive(conn net.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return false
+	}
+
+	c.conns[conn] = true
+	c.inFlight++
+
+	return true
+}
+
+// setIdle marks the connection as idle after its request is answered.
+// It returns false if the tracker is draining and no settlement is pending, in which case the connection must be closed.
+func (c *connectionTracker) setIdle(conn net.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conns[conn] = false
+	c.inFlight--
+	c.signalDrainedLocked()
+
+	return !c.draining || c.pending > 0
+}
+
+// addPending registers a payment whose settlement is pending.
+func (c *connectionTracker) addPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending++
+}
+
+// donePending unregisters a payment once its settlement is notified. If draining, idle connections are closed
+// when no settlement is pending anymore.
+func (c *connectionTracker) donePending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending--
+
+	if c.draining && c.pending == 0 {
+		c.closeIdleLocked()
+	}
+
+	c.signalDrainedLocked()
+}
+
+// drain starts draining, closes all idle connections unless a settlement is pending,
+// and returns a channel that is closed once no request is in progress and no settlement is pending.
+func (c *connectionTracker) drain() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return c.drained
+	}
+
+	c.draining = true
+
+	if c.pending == 0 {
+		c.closeIdleLocked()
+	}
+
+	c.signalDrainedLocked()
+
+	return c.drained
+}
+
+// closeIdleLocked closes all idle connections. It must be called with mu held.
+func (c *connectionTracker) closeIdleLocked() {
+	for conn, active := range c.conns {
+		if active {
+			continue
+		}
+
+		err := conn.Close()
+		if err != nil {
+			slog.Error("Error closing idle connection", "error", err, "remote", conn.RemoteAddr())
+		}
+	}
+}
+
+// signalDrainedLocked closes the drained channel if draining, no request is in progress and no settlement is pending.
+// It must be called with mu held.
+func (c *connectionTracker) signalDrainedLocked() {
+	if c.draining && c.inFlight == 0 && c.pending == 0 {
+		select {
+		case <-c.drained:
+		default:
+			close(c.drained)
+		}
+	}
+}
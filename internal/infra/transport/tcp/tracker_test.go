@@ -0,0 +1,133 @@
+package tcp
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_connectionTracker(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(*testing.T, *connectionTracker, net.Conn, net.Conn)
+	}{
+		{
+			name: "Drained immediately without connections",
+			run: func(t *testing.T, tracker *connectionTracker, _, _ net.Conn) {
+				assertClosed(t, tracker.drain())
+			},
+		},
+		{
+			name: "Idle connection is closed on drain",
+			run: func(t *testing.T, tracker *connectionTracker, server, client net.Conn) {
+				require.True(t, tracker.add(server))
+
+				assertClosed(t, tracker.drain())
+
+				_, err := client.Read(make([]byte, 1))
+				assert.ErrorIs(t, err, io.EOF)
+			},
+		},
+		{
+			name: "Drained after active connection becomes idle",
+			run: func(t *testing.T, tracker *connectionTracker, server, _ net.Conn) {
+				require.True(t, tracker.add(server))
+				require.True(t, tracker.setActive(server))
+
+				drained := tracker.drain()
+				assertOpen(t, drained)
+
+				assert.False(t, tracker.setIdle(server))
+				assertClosed(t, drained)
+			},
+		},
+		{
+			name: "Drained after active connection is removed",
+			run: func(t *testing.T, tracker *connectionTracker, server, _ net.Conn) {
+				require.True(t, tracker.add(server))
+				require.True(t, tracker.setActive(server))
+
+				drained := tracker.drain()
+				assertOpen(t, drained)
+
+				tracker.remove(server)
+				assertClosed(t, drained)
+			},
+		},
+		{
+			name: "Idle connection is kept open while a settlement is pending",
+			run: func(t *testing.T, tracker *connectionTracker, server, client net.Conn) {
+				require.True(t, tracker.add(server))
+				tracker.addPending()
+
+				drained := tracker.drain()
+				assertOpen(t, drained)
+
+				tracker.donePending()
+				assertClosed(t, drained)
+
+				_, err := client.Read(make([]byte, 1))
+				assert.ErrorIs(t, err, io.EOF)
+			},
+		},
+		{
+			name: "Active connection is kept open while a settlement is pending",
+			run: func(t *testing.T, tracker *connectionTracker, server, _ net.Conn) {
+				require.True(t, tracker.add(server))
+				require.True(t, tracker.setActive(server))
+				tracker.addPending()
+
+				drained := tracker.drain()
+
+				assert.True(t, tracker.setIdle(server))
+				assertOpen(t, drained)
+
+				tracker.donePending()
+				assertClosed(t, drained)
+			},
+		},
+		{
+			name: "Reject new connections and requests while draining",
+			run: func(t *testing.T, tracker *connectionTracker, server, _ net.Conn) {
+				require.True(t, tracker.add(server))
+
+				tracker.drain()
+
+				assert.False(t, tracker.setActive(server))
+				assert.False(t, tracker.add(server))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close() //nolint:errcheck
+			defer client.Close() //nolint:errcheck
+
+			test.run(t, newConnectionTracker(), server, client)
+		})
+	}
+}
+
+func assertClosed(t *testing.T, c <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-c:
+	default:
+		assert.Fail(t, "channel is not closed")
+	}
+}
+
+func assertOpen(t *testing.T, c <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-c:
+		assert.Fail(t, "channel is closed")
+	default:
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/logging"
+)
+
+// instanceName restricts instance names, so they can be used in environment variable names.
+var instanceName = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// Instance is a named simulator with its own configuration.
+type Instance struct {
+	Name   string
+	Config simulator.Config
+}
+
+// RunInstances starts the instances in one process, each with its own listener, services and admin server.
+// The logging is configured from cfg. All instances are stopped when ctx is cancelled or any of them fails,
+// and RunInstances returns once every instance has stopped.
+func RunInstances(ctx context.Context, cfg simulator.Config, instances []Instance) error {
+	if err := validateInstances(instances); err != nil {
+		return err
+	}
+
+	logging.Setup(cfg)
+
+	ctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, len(instances))
+	for i, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slog.Info("Instance starting", "instance", instance.Name, "port", instance.Config.ServerPort)
+
+			err := run(ctx, instance.Config)
+			// An instance stopped while it is starting, because the process is stopping, hasn't failed.
+			if err != nil && !(errors.Is(err, context.Canceled) && ctx.Err() != nil) {
+				// A failed instance stops the others, so the process doesn't run with part of its instances.
+				cncl()
+				errs[i] = fmt.Errorf("instance %s: %w", instance.Name, err)
+			}
+
+			slog.Info("Instance stopped", "instance", instance.Name)
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// validateInstances checks that the instances have valid unique names,
+// and don't share ports or files which can't be used by more than one instance.
+func validateInstances(instances []Instance) error {
+	if len(instances) == 0 {
+		return errors.New("no instances")
+	}
+
+	var errs []error
+
+	names := make(map[string]bool)
+	ports := make(map[int]string)
+	files := make(map[string]string)
+
+	for _, instance := range instances {
+		if !instanceName.MatchString(instance.Name) {
+			errs = append(errs, fmt.Errorf("invalid instance name %q", instance.Name))
+		}
+
+		name := strings.ToLower(instance.Name)
+		if names[name] {
+			errs = append(errs, fmt.Errorf("duplicate instance %q", instance.Name))
+		}
+		names[name] = true
+
+		for _, port := range []int{instance.Config.ServerPort, instance.Config.AdminPort} {
+			if port == 0 {
+				continue
+			}
+
+			if other, ok := ports[port]; ok {
+				errs = append(errs, fmt.Errorf("instance %s: port %d is used by instance %s", instance.Name, port, other))
+			}
+			ports[port] = instance.Name
+		}
+
+		for _, file := range []string{instance.Config.JournalFile, instance.Config.RecordFile} {
+			if file == "" {
+				continue
+			}
+
+			if other, ok := files[file]; ok {
+				errs = append(errs, fmt.Errorf("instance %s: file %s is used by instance %s", instance.Name, file, other))
+			}
+			files[file] = instance.Name
+		}
+	}
+
+	return errors.Join(errs...)
+}
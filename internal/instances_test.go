@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ormanli/form3-te/internal/app/simulator"
+)
+
+func Test_validateInstances(t *testing.T) {
+	tests := []struct {
+		name        string
+		instances   []Instance
+		expectedErr []string
+	}{
+		{
+			name: "Valid instances",
+			instances: []Instance{
+				{Name: "gbp", Config: simulator.Config{ServerPort: 1, AdminPort: 2, JournalFile: "gbp.log"}},
+				{Name: "eur", Config: simulator.Config{ServerPort: 3, JournalFile: "eur.log"}},
+			},
+		},
+		{
+			name:        "No instances",
+			expectedErr: []string{"no instances"},
+		},
+		{
+			name: "Invalid names",
+			instances: []Instance{
+				{Name: "", Config: simulator.Config{ServerPort: 1}},
+				{Name: "gbp_1", Config: simulator.Config{ServerPort: 2}},
+			},
+			expectedErr: []string{`invalid instance name ""`, `invalid instance name "gbp_1"`},
+		},
+		{
+			name: "Duplicate names",
+			instances: []Instance{
+				{Name: "gbp", Config: simulator.Config{ServerPort: 1}},
+				{Name: "GBP", Config: simulator.Config{ServerPort: 2}},
+			},
+			expectedErr: []string{`duplicate instance "GBP"`},
+		},
+		{
+			name: "Shared ports",
+			instances: []Instance{
+				{Name: "gbp", Config: simulator.Config{ServerPort: 1, AdminPort: 2}},
+				{Name: "eur", Config: simulator.Config{ServerPort: 2, AdminPort: 1}},
+			},
+			expectedErr: []string{"instance eur: port 2 is used by instance gbp", "instance eur: port 1 is used by instance gbp"},
+		},
+		{
+			name: "Shared files",
+			instances: []Instance{
+				{Name: "gbp", Config: simulator.Config{ServerPort: 1, JournalFile: "journal.log"}},
+				{Name: "eur", Config: simulator.Config{ServerPort: 2, RecordFile: "journal.log"}},
+			},
+			expectedErr: []string{"instance eur: file journal.log is used by instance gbp"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateInstances(test.instances)
+			if len(test.expectedErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, expected := range test.expectedErr {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
+
+func Test_RunInstances(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gbp := newInstance(t, "gbp")
+	eur := newInstance(t, "eur")
+	eur.Config.ServerReasonCodes = true
+
+	ctx, cncl := context.WithCancel(context.Background())
+	defer cncl()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunInstances(ctx, simulator.Config{}, []Instance{gbp, eur})
+	}()
+
+	assert.Equal(t, "RESPONSE|ACCEPTED|Transaction processed", sendPayment(t, gbp.Config.ServerPort))
+	assert.Equal(t, "RESPONSE|ACCEPTED|OK|Transaction processed", sendPayment(t, eur.Config.ServerPort))
+
+	cncl()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "instances did not stop")
+	}
+}
+
+func Test_RunInstances_Failure(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gbp := newInstance(t, "gbp")
+	eur := newInstance(t, "eur")
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", eur.Config.ServerPort))
+	require.NoError(t, err)
+	defer listener.Close() //nolint:errcheck
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunInstances(context.Background(), simulator.Config{}, []Instance{gbp, eur})
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "instance eur: ")
+		assert.NotContains(t, err.Error(), "instance gbp")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "instances did not stop")
+	}
+}
+
+// newInstance creates an instance with the default configuration, listening on free ports without an admin server.
+func newInstance(t *testing.T, name string) Instance {
+	t.Helper()
+
+	var cfg simulator.Config
+	require.NoError(t, envconfig.Process("test_"+name, &cfg))
+
+	cfg.ServerPort = getFreePort(t)
+	cfg.AdminPort = 0
+
+	return Instance{Name: name, Config: cfg}
+}
+
+// sendPayment sends a payment which is processed without waiting to the port, and returns the response.
+func sendPayment(t *testing.T, port int) string {
+	t.Helper()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close() //nolint:errcheck
+
+	_, err = fmt.Fprintln(conn, "PAYMENT|50")
+	require.NoError(t, err)
+
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+
+	return response[:len(response)-1]
+}
+
+// getFreePort returns a port number that is free at the time of the call.
+func getFreePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close() //nolint:errcheck
+
+	return l.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+}
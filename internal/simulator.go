@@ -2,11 +2,19 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
 
 	"github.com/benbjohnson/clock"
 
+	"github.com/ormanli/form3-te/internal/app/replay"
 	"github.com/ormanli/form3-te/internal/app/simulator"
+	"github.com/ormanli/form3-te/internal/infra/admin"
+	"github.com/ormanli/form3-te/internal/infra/journal"
 	"github.com/ormanli/form3-te/internal/infra/logging"
+	"github.com/ormanli/form3-te/internal/infra/metrics"
 	"github.com/ormanli/form3-te/internal/infra/transport/tcp"
 )
 
@@ -14,8 +22,195 @@ import (
 func Run(ctx context.Context, cfg simulator.Config) error {
 	logging.Setup(cfg)
 
-	service := simulator.NewValidationService(simulator.NewDummyService(cfg))
-	tcpTransport := tcp.NewTransport(cfg, service, clock.New())
+	return run(ctx, cfg)
+}
+
+// run starts a simulator with its own services, transport and admin server, and blocks until it is stopped.
+func run(ctx context.Context, cfg simulator.Config) error {
+	clk := clock.New()
+
+	processingService, err := newProcessingService(cfg)
+	if err != nil {
+		return err
+	}
+
+	registry := metrics.NewRegistry()
+	serviceMetrics := metrics.NewServiceMetrics(registry, clk)
+
+	var (
+		service     simulator.Service = serviceMetrics.Instrument("processing", processingService)
+		adminLedger admin.Ledger
+	)
+	if cfg.LedgerFile != "" || cfg.Accounts != nil {
+		ledger, err := newLedgerService(cfg, service)
+		if err != nil {
+			return err
+		}
+
+		service, adminLedger = ledger, ledger
+	}
+
+	service = simulator.NewValidationService(service)
+
+	var (
+		statuses tcp.Statuses
+		recaller tcp.Recaller
+	)
+	if cfg.StatusRetention > 0 {
+		statusService := simulator.NewStatusService(cfg, service, clk)
+		service, statuses = statusService, statusService
+		recaller = simulator.NewRecallService(cfg, statusService, clk)
+	}
+
+	if cfg.IdempotencyWindow > 0 {
+		service = simulator.NewIdempotencyService(cfg, service, clk)
+	}
+
+	var calendar tcp.Calendar
+	if cfg.CalendarFile != "" || cfg.Calendar != nil {
+		c, err := loadCalendar(cfg)
+		if err != nil {
+			return err
+		}
+
+		service, calendar = simulator.NewCalendarService(c, service, clk), c
+	}
+	service = serviceMetrics.Instrument("chain", service)
+
+	var (
+		transportJournal tcp.Journal
+		adminJournal     admin.Journal
+	)
+	if cfg.JournalFile != "" {
+		j, err := journal.Open(cfg)
+		if err != nil {
+			return err
+		}
+		defer j.Close() //nolint:errcheck
+
+		slog.Info("Journal opened", "file", cfg.JournalFile)
+
+		transportJournal, adminJournal = j, j
+	}
+
+	var recorder tcp.Recorder
+	if cfg.RecordFile != "" {
+		r, err := replay.NewRecorder(cfg)
+		if err != nil {
+			return err
+		}
+		defer r.Close() //nolint:errcheck
+
+		slog.Info("Recording received lines", "file", cfg.RecordFile)
+
+		recorder = r
+	}
+
+	tcpTransport := tcp.NewTransport(cfg, service, clk, registry, transportJournal, recorder, statuses, recaller, calendar)
+
+	if cfg.AdminPort == 0 {
+		return tcpTransport.Start(ctx)
+	}
+
+	adminServer := admin.NewServer(cfg, tcpTransport, processingService, registry, adminJournal, adminLedger, EncodeConfig)
+
+	ctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+
+	adminErrChan := make(chan error, 1)
+	go func() {
+		err := adminServer.Start(ctx)
+		if err != nil {
+			// The simulator can't be controlled without the admin server, so it is stopped as well.
+			cncl()
+		}
+		adminErrChan <- err
+	}()
+
+	err = tcpTransport.Start(ctx)
+
+	// The transport also stops when a drain is requested through the admin server, so the admin server is stopped afterwards.
+	cncl()
+
+	return errors.Join(err, <-adminErrChan)
+}
+
+// newProcessingService creates the service simulating the scheme behaviour.
+// If a scenario or scenario file is configured, the scenario is activated, otherwise payments are processed by DummyService.
+func newProcessingService(cfg simulator.Config) (*simulator.ConfigurableService, error) {
+	if cfg.Scenario != nil {
+		slog.Info("Scenario loaded", "rules", len(cfg.Scenario.Rules))
+
+		return simulator.NewConfigurableService(cfg, cfg.Scenario), nil
+	}
+
+	if cfg.ScenarioFile == "" {
+		return simulator.NewConfigurableService(cfg, nil), nil
+	}
+
+	f, err := os.Open(cfg.ScenarioFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't open scenario file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	scenario, err := simulator.ParseScenario(f)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Scenario loaded", "file", cfg.ScenarioFile, "rules", len(scenario.Rules))
+
+	return simulator.NewConfigurableService(cfg, &scenario), nil
+}
+
+// newLedgerService creates the ledger checking funds before payments are processed by service,
+// with the configured accounts or the accounts seeded from the ledger file.
+func newLedgerService(cfg simulator.Config, service simulator.Service) (*simulator.LedgerService, error) {
+	if cfg.Accounts != nil {
+		slog.Info("Ledger loaded", "accounts", len(cfg.Accounts))
+
+		return simulator.NewLedgerService(cfg.Accounts, service), nil
+	}
+
+	f, err := os.Open(cfg.LedgerFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't open ledger file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	accounts, err := simulator.ParseAccounts(f)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Ledger loaded", "file", cfg.LedgerFile, "accounts", len(accounts))
+
+	return simulator.NewLedgerService(accounts, service), nil
+}
+
+// loadCalendar returns the configured calendar defining when the scheme is closed, or reads it from the calendar file.
+func loadCalendar(cfg simulator.Config) (simulator.Calendar, error) {
+	if cfg.Calendar != nil {
+		slog.Info("Calendar loaded", "openHours", len(cfg.Calendar.OpenHours),
+			"maintenance", len(cfg.Calendar.Maintenance), "outages", len(cfg.Calendar.Outages))
+
+		return *cfg.Calendar, nil
+	}
+
+	f, err := os.Open(cfg.CalendarFile)
+	if err != nil {
+		return simulator.Calendar{}, fmt.Errorf("can't open calendar file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	calendar, err := simulator.ParseCalendar(f)
+	if err != nil {
+		return simulator.Calendar{}, err
+	}
+
+	slog.Info("Calendar loaded", "file", cfg.CalendarFile, "openHours", len(calendar.OpenHours),
+		"maintenance", len(calendar.Maintenance), "outages", len(calendar.Outages))
 
-	return tcpTransport.Start(ctx)
+	return calendar, nil
 }